@@ -1,15 +1,26 @@
 import { useState } from 'react';
 
+// InvalidParam is one rejected field in an RFC 7807 problem response from the BFF.
+interface InvalidParam {
+  name: string;
+  reason: string;
+  code: string;
+}
+
 export default function RegisterForm() {
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
   const [message, setMessage] = useState('');
   const [error, setError] = useState('');
+  const [fieldErrors, setFieldErrors] = useState<InvalidParam[]>([]);
+
+  const errorsFor = (field: string) => fieldErrors.filter((p) => p.name === field);
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setMessage('');
     setError('');
+    setFieldErrors([]);
 
     try {
       const res = await fetch('http://localhost:8080/api/auth/register', {
@@ -21,6 +32,11 @@ export default function RegisterForm() {
       });
 
       if (!res.ok) {
+        if (res.headers.get('Content-Type')?.includes('application/problem+json')) {
+          const problem = await res.json();
+          setFieldErrors(problem.invalid_params ?? []);
+          throw new Error(problem.detail ?? problem.title);
+        }
         const data = await res.text();
         throw new Error(data);
       }
@@ -57,6 +73,9 @@ export default function RegisterForm() {
                 className="block w-full rounded-md border-0 py-1.5 text-gray-900 shadow-sm ring-1 ring-inset ring-gray-300 placeholder:text-gray-400 focus:ring-2 focus:ring-inset focus:ring-indigo-600 sm:text-sm sm:leading-6 p-2"
               />
             </div>
+            {errorsFor('email').map((p) => (
+              <p key={p.code} className="mt-1 text-sm text-red-600">{p.reason}</p>
+            ))}
           </div>
 
           <div>
@@ -68,13 +87,16 @@ export default function RegisterForm() {
                 id="password"
                 name="password"
                 type="password"
-                autoComplete="current-password"
+                autoComplete="new-password"
                 required
                 value={password}
                 onChange={(e) => setPassword(e.target.value)}
                 className="block w-full rounded-md border-0 py-1.5 text-gray-900 shadow-sm ring-1 ring-inset ring-gray-300 placeholder:text-gray-400 focus:ring-2 focus:ring-inset focus:ring-indigo-600 sm:text-sm sm:leading-6 p-2"
               />
             </div>
+            {errorsFor('password').map((p) => (
+              <p key={p.code} className="mt-1 text-sm text-red-600">{p.reason}</p>
+            ))}
           </div>
 
           <div>
@@ -7,8 +7,10 @@
 package shipping
 
 import (
+	common "github.com/my-store/pkg/api/common"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,17 +23,355 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-type CreateShipmentRequest struct {
+// QuotePolicy picks a quote when the caller doesn't name a carrier service.
+type QuotePolicy int32
+
+const (
+	// The service's configured default (SHIPPING_QUOTE_POLICY).
+	QuotePolicy_QUOTE_POLICY_UNSPECIFIED QuotePolicy = 0
+	// Lowest price, then fewest days.
+	QuotePolicy_QUOTE_POLICY_CHEAPEST QuotePolicy = 1
+	// Fewest days, then lowest price.
+	QuotePolicy_QUOTE_POLICY_FASTEST QuotePolicy = 2
+)
+
+// Enum value maps for QuotePolicy.
+var (
+	QuotePolicy_name = map[int32]string{
+		0: "QUOTE_POLICY_UNSPECIFIED",
+		1: "QUOTE_POLICY_CHEAPEST",
+		2: "QUOTE_POLICY_FASTEST",
+	}
+	QuotePolicy_value = map[string]int32{
+		"QUOTE_POLICY_UNSPECIFIED": 0,
+		"QUOTE_POLICY_CHEAPEST":    1,
+		"QUOTE_POLICY_FASTEST":     2,
+	}
+)
+
+func (x QuotePolicy) Enum() *QuotePolicy {
+	p := new(QuotePolicy)
+	*p = x
+	return p
+}
+
+func (x QuotePolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (QuotePolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_shipping_proto_enumTypes[0].Descriptor()
+}
+
+func (QuotePolicy) Type() protoreflect.EnumType {
+	return &file_shipping_proto_enumTypes[0]
+}
+
+func (x QuotePolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use QuotePolicy.Descriptor instead.
+func (QuotePolicy) EnumDescriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{0}
+}
+
+// ShipmentStatus is where a shipment is in its lifecycle. DELIVERED, DELIVERY_FAILED
+// and LOST are final.
+type ShipmentStatus int32
+
+const (
+	ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED      ShipmentStatus = 0
+	ShipmentStatus_SHIPMENT_STATUS_CREATED          ShipmentStatus = 1
+	ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT       ShipmentStatus = 2
+	ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY ShipmentStatus = 3
+	ShipmentStatus_SHIPMENT_STATUS_DELIVERED        ShipmentStatus = 4
+	ShipmentStatus_SHIPMENT_STATUS_DELIVERY_FAILED  ShipmentStatus = 5
+	ShipmentStatus_SHIPMENT_STATUS_LOST             ShipmentStatus = 6
+)
+
+// Enum value maps for ShipmentStatus.
+var (
+	ShipmentStatus_name = map[int32]string{
+		0: "SHIPMENT_STATUS_UNSPECIFIED",
+		1: "SHIPMENT_STATUS_CREATED",
+		2: "SHIPMENT_STATUS_IN_TRANSIT",
+		3: "SHIPMENT_STATUS_OUT_FOR_DELIVERY",
+		4: "SHIPMENT_STATUS_DELIVERED",
+		5: "SHIPMENT_STATUS_DELIVERY_FAILED",
+		6: "SHIPMENT_STATUS_LOST",
+	}
+	ShipmentStatus_value = map[string]int32{
+		"SHIPMENT_STATUS_UNSPECIFIED":      0,
+		"SHIPMENT_STATUS_CREATED":          1,
+		"SHIPMENT_STATUS_IN_TRANSIT":       2,
+		"SHIPMENT_STATUS_OUT_FOR_DELIVERY": 3,
+		"SHIPMENT_STATUS_DELIVERED":        4,
+		"SHIPMENT_STATUS_DELIVERY_FAILED":  5,
+		"SHIPMENT_STATUS_LOST":             6,
+	}
+)
+
+func (x ShipmentStatus) Enum() *ShipmentStatus {
+	p := new(ShipmentStatus)
+	*p = x
+	return p
+}
+
+func (x ShipmentStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ShipmentStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_shipping_proto_enumTypes[1].Descriptor()
+}
+
+func (ShipmentStatus) Type() protoreflect.EnumType {
+	return &file_shipping_proto_enumTypes[1]
+}
+
+func (x ShipmentStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ShipmentStatus.Descriptor instead.
+func (ShipmentStatus) EnumDescriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{1}
+}
+
+// ShippingQuote is a carrier service's price for a parcel.
+type ShippingQuote struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Carrier string                 `protobuf:"bytes,1,opt,name=carrier,proto3" json:"carrier,omitempty"`
+	// The carrier's code for the service, e.g. "ground".
+	Service string `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	// Human-readable service name, e.g. "Ground".
+	ServiceName string `protobuf:"bytes,3,opt,name=service_name,json=serviceName,proto3" json:"service_name,omitempty"`
+	PriceCents  int64  `protobuf:"varint,4,opt,name=price_cents,json=priceCents,proto3" json:"price_cents,omitempty"`
+	// ISO 4217 code, e.g. "USD".
+	Currency      string `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	EstimatedDays int32  `protobuf:"varint,6,opt,name=estimated_days,json=estimatedDays,proto3" json:"estimated_days,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShippingQuote) Reset() {
+	*x = ShippingQuote{}
+	mi := &file_shipping_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShippingQuote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShippingQuote) ProtoMessage() {}
+
+func (x *ShippingQuote) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShippingQuote.ProtoReflect.Descriptor instead.
+func (*ShippingQuote) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ShippingQuote) GetCarrier() string {
+	if x != nil {
+		return x.Carrier
+	}
+	return ""
+}
+
+func (x *ShippingQuote) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *ShippingQuote) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+func (x *ShippingQuote) GetPriceCents() int64 {
+	if x != nil {
+		return x.PriceCents
+	}
+	return 0
+}
+
+func (x *ShippingQuote) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *ShippingQuote) GetEstimatedDays() int32 {
+	if x != nil {
+		return x.EstimatedDays
+	}
+	return 0
+}
+
+// ShipmentItem is a quantity of one product of the order in a shipment.
+type ShipmentItem struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	OrderId       int64                  `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
-	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	ProductId     int64                  `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity      int32                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShipmentItem) Reset() {
+	*x = ShipmentItem{}
+	mi := &file_shipping_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShipmentItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShipmentItem) ProtoMessage() {}
+
+func (x *ShipmentItem) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShipmentItem.ProtoReflect.Descriptor instead.
+func (*ShipmentItem) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ShipmentItem) GetProductId() int64 {
+	if x != nil {
+		return x.ProductId
+	}
+	return 0
+}
+
+func (x *ShipmentItem) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+// ShipmentEvent is one entry of a shipment's tracking history.
+type ShipmentEvent struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Status      ShipmentStatus         `protobuf:"varint,1,opt,name=status,proto3,enum=shipping.ShipmentStatus" json:"status,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	OccurredAt  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	// Where the carrier scanned the parcel, e.g. "Memphis, TN". Empty if unknown.
+	Location      string `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShipmentEvent) Reset() {
+	*x = ShipmentEvent{}
+	mi := &file_shipping_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShipmentEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShipmentEvent) ProtoMessage() {}
+
+func (x *ShipmentEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShipmentEvent.ProtoReflect.Descriptor instead.
+func (*ShipmentEvent) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ShipmentEvent) GetStatus() ShipmentStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED
+}
+
+func (x *ShipmentEvent) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ShipmentEvent) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *ShipmentEvent) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+type CreateShipmentRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	OrderId int64                  `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Address *common.Address        `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	// Parcel weight; a default is assumed if 0.
+	WeightGrams int32 `protobuf:"varint,4,opt,name=weight_grams,json=weightGrams,proto3" json:"weight_grams,omitempty"`
+	// Carrier service to book, as returned by GetShippingQuotes. If empty, one is
+	// picked by policy.
+	Carrier string      `protobuf:"bytes,5,opt,name=carrier,proto3" json:"carrier,omitempty"`
+	Service string      `protobuf:"bytes,6,opt,name=service,proto3" json:"service,omitempty"`
+	Policy  QuotePolicy `protobuf:"varint,7,opt,name=policy,proto3,enum=shipping.QuotePolicy" json:"policy,omitempty"`
+	// What goes in this parcel. If empty, everything of the order not yet shipped.
+	Items []*ShipmentItem `protobuf:"bytes,8,rep,name=items,proto3" json:"items,omitempty"`
+	// The order's items, which the order's shipments together may not exceed.
+	Ordered       []*ShipmentItem `protobuf:"bytes,9,rep,name=ordered,proto3" json:"ordered,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CreateShipmentRequest) Reset() {
 	*x = CreateShipmentRequest{}
-	mi := &file_shipping_proto_msgTypes[0]
+	mi := &file_shipping_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -43,7 +383,7 @@ func (x *CreateShipmentRequest) String() string {
 func (*CreateShipmentRequest) ProtoMessage() {}
 
 func (x *CreateShipmentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_shipping_proto_msgTypes[0]
+	mi := &file_shipping_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -56,7 +396,7 @@ func (x *CreateShipmentRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateShipmentRequest.ProtoReflect.Descriptor instead.
 func (*CreateShipmentRequest) Descriptor() ([]byte, []int) {
-	return file_shipping_proto_rawDescGZIP(), []int{0}
+	return file_shipping_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *CreateShipmentRequest) GetOrderId() int64 {
@@ -66,25 +406,68 @@ func (x *CreateShipmentRequest) GetOrderId() int64 {
 	return 0
 }
 
-func (x *CreateShipmentRequest) GetAddress() string {
+func (x *CreateShipmentRequest) GetAddress() *common.Address {
 	if x != nil {
 		return x.Address
 	}
+	return nil
+}
+
+func (x *CreateShipmentRequest) GetWeightGrams() int32 {
+	if x != nil {
+		return x.WeightGrams
+	}
+	return 0
+}
+
+func (x *CreateShipmentRequest) GetCarrier() string {
+	if x != nil {
+		return x.Carrier
+	}
+	return ""
+}
+
+func (x *CreateShipmentRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
 	return ""
 }
 
+func (x *CreateShipmentRequest) GetPolicy() QuotePolicy {
+	if x != nil {
+		return x.Policy
+	}
+	return QuotePolicy_QUOTE_POLICY_UNSPECIFIED
+}
+
+func (x *CreateShipmentRequest) GetItems() []*ShipmentItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *CreateShipmentRequest) GetOrdered() []*ShipmentItem {
+	if x != nil {
+		return x.Ordered
+	}
+	return nil
+}
+
 type CreateShipmentResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        int32                  `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	TrackingId    string                 `protobuf:"bytes,3,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	TrackingId string                 `protobuf:"bytes,3,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
+	// The quote that was booked.
+	Quote         *ShippingQuote  `protobuf:"bytes,4,opt,name=quote,proto3" json:"quote,omitempty"`
+	Items         []*ShipmentItem `protobuf:"bytes,5,rep,name=items,proto3" json:"items,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CreateShipmentResponse) Reset() {
 	*x = CreateShipmentResponse{}
-	mi := &file_shipping_proto_msgTypes[1]
+	mi := &file_shipping_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -96,7 +479,7 @@ func (x *CreateShipmentResponse) String() string {
 func (*CreateShipmentResponse) ProtoMessage() {}
 
 func (x *CreateShipmentResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_shipping_proto_msgTypes[1]
+	mi := &file_shipping_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -109,28 +492,28 @@ func (x *CreateShipmentResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateShipmentResponse.ProtoReflect.Descriptor instead.
 func (*CreateShipmentResponse) Descriptor() ([]byte, []int) {
-	return file_shipping_proto_rawDescGZIP(), []int{1}
+	return file_shipping_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *CreateShipmentResponse) GetStatus() int32 {
+func (x *CreateShipmentResponse) GetTrackingId() string {
 	if x != nil {
-		return x.Status
+		return x.TrackingId
 	}
-	return 0
+	return ""
 }
 
-func (x *CreateShipmentResponse) GetError() string {
+func (x *CreateShipmentResponse) GetQuote() *ShippingQuote {
 	if x != nil {
-		return x.Error
+		return x.Quote
 	}
-	return ""
+	return nil
 }
 
-func (x *CreateShipmentResponse) GetTrackingId() string {
+func (x *CreateShipmentResponse) GetItems() []*ShipmentItem {
 	if x != nil {
-		return x.TrackingId
+		return x.Items
 	}
-	return ""
+	return nil
 }
 
 type GetShipmentStatusRequest struct {
@@ -142,7 +525,7 @@ type GetShipmentStatusRequest struct {
 
 func (x *GetShipmentStatusRequest) Reset() {
 	*x = GetShipmentStatusRequest{}
-	mi := &file_shipping_proto_msgTypes[2]
+	mi := &file_shipping_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -154,7 +537,7 @@ func (x *GetShipmentStatusRequest) String() string {
 func (*GetShipmentStatusRequest) ProtoMessage() {}
 
 func (x *GetShipmentStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_shipping_proto_msgTypes[2]
+	mi := &file_shipping_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -167,7 +550,7 @@ func (x *GetShipmentStatusRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetShipmentStatusRequest.ProtoReflect.Descriptor instead.
 func (*GetShipmentStatusRequest) Descriptor() ([]byte, []int) {
-	return file_shipping_proto_rawDescGZIP(), []int{2}
+	return file_shipping_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *GetShipmentStatusRequest) GetTrackingId() string {
@@ -178,17 +561,20 @@ func (x *GetShipmentStatusRequest) GetTrackingId() string {
 }
 
 type GetShipmentStatusResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        int32                  `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	StatusText    string                 `protobuf:"bytes,3,opt,name=status_text,json=statusText,proto3" json:"status_text,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Human-readable description of the current status.
+	StatusText string         `protobuf:"bytes,3,opt,name=status_text,json=statusText,proto3" json:"status_text,omitempty"`
+	Status     ShipmentStatus `protobuf:"varint,4,opt,name=status,proto3,enum=shipping.ShipmentStatus" json:"status,omitempty"`
+	OrderId    int64          `protobuf:"varint,5,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	// Oldest first; the last event is the current status.
+	History       []*ShipmentEvent `protobuf:"bytes,6,rep,name=history,proto3" json:"history,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetShipmentStatusResponse) Reset() {
 	*x = GetShipmentStatusResponse{}
-	mi := &file_shipping_proto_msgTypes[3]
+	mi := &file_shipping_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -200,7 +586,7 @@ func (x *GetShipmentStatusResponse) String() string {
 func (*GetShipmentStatusResponse) ProtoMessage() {}
 
 func (x *GetShipmentStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_shipping_proto_msgTypes[3]
+	mi := &file_shipping_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -213,54 +599,700 @@ func (x *GetShipmentStatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetShipmentStatusResponse.ProtoReflect.Descriptor instead.
 func (*GetShipmentStatusResponse) Descriptor() ([]byte, []int) {
-	return file_shipping_proto_rawDescGZIP(), []int{3}
+	return file_shipping_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *GetShipmentStatusResponse) GetStatus() int32 {
+func (x *GetShipmentStatusResponse) GetStatusText() string {
 	if x != nil {
-		return x.Status
+		return x.StatusText
 	}
-	return 0
+	return ""
 }
 
-func (x *GetShipmentStatusResponse) GetError() string {
+func (x *GetShipmentStatusResponse) GetStatus() ShipmentStatus {
 	if x != nil {
-		return x.Error
+		return x.Status
 	}
-	return ""
+	return ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED
 }
 
-func (x *GetShipmentStatusResponse) GetStatusText() string {
+func (x *GetShipmentStatusResponse) GetOrderId() int64 {
 	if x != nil {
-		return x.StatusText
+		return x.OrderId
 	}
-	return ""
+	return 0
 }
 
-var File_shipping_proto protoreflect.FileDescriptor
+func (x *GetShipmentStatusResponse) GetHistory() []*ShipmentEvent {
+	if x != nil {
+		return x.History
+	}
+	return nil
+}
 
-const file_shipping_proto_rawDesc = "" +
-	"\n" +
-	"\x0eshipping.proto\x12\bshipping\"L\n" +
+type GetShipmentTrackingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TrackingId    string                 `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShipmentTrackingRequest) Reset() {
+	*x = GetShipmentTrackingRequest{}
+	mi := &file_shipping_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShipmentTrackingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShipmentTrackingRequest) ProtoMessage() {}
+
+func (x *GetShipmentTrackingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShipmentTrackingRequest.ProtoReflect.Descriptor instead.
+func (*GetShipmentTrackingRequest) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetShipmentTrackingRequest) GetTrackingId() string {
+	if x != nil {
+		return x.TrackingId
+	}
+	return ""
+}
+
+type GetShipmentTrackingResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	TrackingId string                 `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
+	OrderId    int64                  `protobuf:"varint,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status     ShipmentStatus         `protobuf:"varint,3,opt,name=status,proto3,enum=shipping.ShipmentStatus" json:"status,omitempty"`
+	StatusText string                 `protobuf:"bytes,4,opt,name=status_text,json=statusText,proto3" json:"status_text,omitempty"`
+	// Oldest first, ordered by when the carrier recorded them.
+	Events  []*ShipmentEvent `protobuf:"bytes,5,rep,name=events,proto3" json:"events,omitempty"`
+	Carrier string           `protobuf:"bytes,6,opt,name=carrier,proto3" json:"carrier,omitempty"`
+	Service string           `protobuf:"bytes,7,opt,name=service,proto3" json:"service,omitempty"`
+	// The carrier's own tracking number.
+	CarrierTrackingNumber string          `protobuf:"bytes,8,opt,name=carrier_tracking_number,json=carrierTrackingNumber,proto3" json:"carrier_tracking_number,omitempty"`
+	Items                 []*ShipmentItem `protobuf:"bytes,9,rep,name=items,proto3" json:"items,omitempty"`
+	// Set for the parcel of a return, which travels from the customer to us.
+	ReturnId      int64 `protobuf:"varint,10,opt,name=return_id,json=returnId,proto3" json:"return_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShipmentTrackingResponse) Reset() {
+	*x = GetShipmentTrackingResponse{}
+	mi := &file_shipping_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShipmentTrackingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShipmentTrackingResponse) ProtoMessage() {}
+
+func (x *GetShipmentTrackingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShipmentTrackingResponse.ProtoReflect.Descriptor instead.
+func (*GetShipmentTrackingResponse) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetShipmentTrackingResponse) GetTrackingId() string {
+	if x != nil {
+		return x.TrackingId
+	}
+	return ""
+}
+
+func (x *GetShipmentTrackingResponse) GetOrderId() int64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *GetShipmentTrackingResponse) GetStatus() ShipmentStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED
+}
+
+func (x *GetShipmentTrackingResponse) GetStatusText() string {
+	if x != nil {
+		return x.StatusText
+	}
+	return ""
+}
+
+func (x *GetShipmentTrackingResponse) GetEvents() []*ShipmentEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *GetShipmentTrackingResponse) GetCarrier() string {
+	if x != nil {
+		return x.Carrier
+	}
+	return ""
+}
+
+func (x *GetShipmentTrackingResponse) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *GetShipmentTrackingResponse) GetCarrierTrackingNumber() string {
+	if x != nil {
+		return x.CarrierTrackingNumber
+	}
+	return ""
+}
+
+func (x *GetShipmentTrackingResponse) GetItems() []*ShipmentItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *GetShipmentTrackingResponse) GetReturnId() int64 {
+	if x != nil {
+		return x.ReturnId
+	}
+	return 0
+}
+
+type GetShippingQuotesRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Address *common.Address        `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// Parcel weight; a default is assumed if 0.
+	WeightGrams int32 `protobuf:"varint,2,opt,name=weight_grams,json=weightGrams,proto3" json:"weight_grams,omitempty"`
+	// Orders the quotes, best first.
+	Policy        QuotePolicy `protobuf:"varint,3,opt,name=policy,proto3,enum=shipping.QuotePolicy" json:"policy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShippingQuotesRequest) Reset() {
+	*x = GetShippingQuotesRequest{}
+	mi := &file_shipping_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShippingQuotesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShippingQuotesRequest) ProtoMessage() {}
+
+func (x *GetShippingQuotesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShippingQuotesRequest.ProtoReflect.Descriptor instead.
+func (*GetShippingQuotesRequest) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetShippingQuotesRequest) GetAddress() *common.Address {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *GetShippingQuotesRequest) GetWeightGrams() int32 {
+	if x != nil {
+		return x.WeightGrams
+	}
+	return 0
+}
+
+func (x *GetShippingQuotesRequest) GetPolicy() QuotePolicy {
+	if x != nil {
+		return x.Policy
+	}
+	return QuotePolicy_QUOTE_POLICY_UNSPECIFIED
+}
+
+type GetShippingQuotesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Quotes        []*ShippingQuote       `protobuf:"bytes,1,rep,name=quotes,proto3" json:"quotes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShippingQuotesResponse) Reset() {
+	*x = GetShippingQuotesResponse{}
+	mi := &file_shipping_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShippingQuotesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShippingQuotesResponse) ProtoMessage() {}
+
+func (x *GetShippingQuotesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShippingQuotesResponse.ProtoReflect.Descriptor instead.
+func (*GetShippingQuotesResponse) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetShippingQuotesResponse) GetQuotes() []*ShippingQuote {
+	if x != nil {
+		return x.Quotes
+	}
+	return nil
+}
+
+type CreateReturnShipmentRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	OrderId int64                  `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	// The order service's ID for the return.
+	ReturnId int64 `protobuf:"varint,2,opt,name=return_id,json=returnId,proto3" json:"return_id,omitempty"`
+	// The customer's address, where the carrier picks the parcel up.
+	Address *common.Address `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	// Parcel weight; a default is assumed if 0.
+	WeightGrams int32 `protobuf:"varint,4,opt,name=weight_grams,json=weightGrams,proto3" json:"weight_grams,omitempty"`
+	// What is being returned.
+	Items         []*ShipmentItem `protobuf:"bytes,5,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateReturnShipmentRequest) Reset() {
+	*x = CreateReturnShipmentRequest{}
+	mi := &file_shipping_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateReturnShipmentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateReturnShipmentRequest) ProtoMessage() {}
+
+func (x *CreateReturnShipmentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateReturnShipmentRequest.ProtoReflect.Descriptor instead.
+func (*CreateReturnShipmentRequest) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CreateReturnShipmentRequest) GetOrderId() int64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *CreateReturnShipmentRequest) GetReturnId() int64 {
+	if x != nil {
+		return x.ReturnId
+	}
+	return 0
+}
+
+func (x *CreateReturnShipmentRequest) GetAddress() *common.Address {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *CreateReturnShipmentRequest) GetWeightGrams() int32 {
+	if x != nil {
+		return x.WeightGrams
+	}
+	return 0
+}
+
+func (x *CreateReturnShipmentRequest) GetItems() []*ShipmentItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type GetShipmentLabelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TrackingId    string                 `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShipmentLabelRequest) Reset() {
+	*x = GetShipmentLabelRequest{}
+	mi := &file_shipping_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShipmentLabelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShipmentLabelRequest) ProtoMessage() {}
+
+func (x *GetShipmentLabelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShipmentLabelRequest.ProtoReflect.Descriptor instead.
+func (*GetShipmentLabelRequest) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetShipmentLabelRequest) GetTrackingId() string {
+	if x != nil {
+		return x.TrackingId
+	}
+	return ""
+}
+
+type GetShipmentLabelResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "application/pdf" or "application/zpl".
+	ContentType   string `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Content       []byte `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetShipmentLabelResponse) Reset() {
+	*x = GetShipmentLabelResponse{}
+	mi := &file_shipping_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetShipmentLabelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetShipmentLabelResponse) ProtoMessage() {}
+
+func (x *GetShipmentLabelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetShipmentLabelResponse.ProtoReflect.Descriptor instead.
+func (*GetShipmentLabelResponse) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetShipmentLabelResponse) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *GetShipmentLabelResponse) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+// ShipmentStatusChanged is published to the shipment-status-changed topic, keyed
+// by order ID, when a shipment is created (with previous_status UNSPECIFIED) and
+// whenever its status changes.
+type ShipmentStatusChanged struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TrackingId     string                 `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
+	OrderId        int64                  `protobuf:"varint,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status         ShipmentStatus         `protobuf:"varint,3,opt,name=status,proto3,enum=shipping.ShipmentStatus" json:"status,omitempty"`
+	PreviousStatus ShipmentStatus         `protobuf:"varint,4,opt,name=previous_status,json=previousStatus,proto3,enum=shipping.ShipmentStatus" json:"previous_status,omitempty"`
+	Location       string                 `protobuf:"bytes,5,opt,name=location,proto3" json:"location,omitempty"`
+	OccurredAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	// What the parcel contains. Empty for shipments booked before orders could be
+	// split, which contain the whole order.
+	Items []*ShipmentItem `protobuf:"bytes,7,rep,name=items,proto3" json:"items,omitempty"`
+	// Set for the parcel of a return; its delivery means the return has arrived.
+	ReturnId      int64 `protobuf:"varint,8,opt,name=return_id,json=returnId,proto3" json:"return_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShipmentStatusChanged) Reset() {
+	*x = ShipmentStatusChanged{}
+	mi := &file_shipping_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShipmentStatusChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShipmentStatusChanged) ProtoMessage() {}
+
+func (x *ShipmentStatusChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_shipping_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShipmentStatusChanged.ProtoReflect.Descriptor instead.
+func (*ShipmentStatusChanged) Descriptor() ([]byte, []int) {
+	return file_shipping_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ShipmentStatusChanged) GetTrackingId() string {
+	if x != nil {
+		return x.TrackingId
+	}
+	return ""
+}
+
+func (x *ShipmentStatusChanged) GetOrderId() int64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *ShipmentStatusChanged) GetStatus() ShipmentStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED
+}
+
+func (x *ShipmentStatusChanged) GetPreviousStatus() ShipmentStatus {
+	if x != nil {
+		return x.PreviousStatus
+	}
+	return ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED
+}
+
+func (x *ShipmentStatusChanged) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *ShipmentStatusChanged) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *ShipmentStatusChanged) GetItems() []*ShipmentItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *ShipmentStatusChanged) GetReturnId() int64 {
+	if x != nil {
+		return x.ReturnId
+	}
+	return 0
+}
+
+var File_shipping_proto protoreflect.FileDescriptor
+
+const file_shipping_proto_rawDesc = "" +
+	"\n" +
+	"\x0eshipping.proto\x12\bshipping\x1a\fcommon.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xca\x01\n" +
+	"\rShippingQuote\x12\x18\n" +
+	"\acarrier\x18\x01 \x01(\tR\acarrier\x12\x18\n" +
+	"\aservice\x18\x02 \x01(\tR\aservice\x12!\n" +
+	"\fservice_name\x18\x03 \x01(\tR\vserviceName\x12\x1f\n" +
+	"\vprice_cents\x18\x04 \x01(\x03R\n" +
+	"priceCents\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\x12%\n" +
+	"\x0eestimated_days\x18\x06 \x01(\x05R\restimatedDays\"I\n" +
+	"\fShipmentItem\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x01 \x01(\x03R\tproductId\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x05R\bquantity\"\xbc\x01\n" +
+	"\rShipmentEvent\x120\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x18.shipping.ShipmentStatusR\x06status\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12;\n" +
+	"\voccurred_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12\x1a\n" +
+	"\blocation\x18\x04 \x01(\tR\blocation\"\xc9\x02\n" +
 	"\x15CreateShipmentRequest\x12\x19\n" +
-	"\border_id\x18\x01 \x01(\x03R\aorderId\x12\x18\n" +
-	"\aaddress\x18\x02 \x01(\tR\aaddress\"g\n" +
-	"\x16CreateShipmentResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\x05R\x06status\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1f\n" +
+	"\border_id\x18\x01 \x01(\x03R\aorderId\x12)\n" +
+	"\aaddress\x18\x03 \x01(\v2\x0f.common.AddressR\aaddress\x12!\n" +
+	"\fweight_grams\x18\x04 \x01(\x05R\vweightGrams\x12\x18\n" +
+	"\acarrier\x18\x05 \x01(\tR\acarrier\x12\x18\n" +
+	"\aservice\x18\x06 \x01(\tR\aservice\x12-\n" +
+	"\x06policy\x18\a \x01(\x0e2\x15.shipping.QuotePolicyR\x06policy\x12,\n" +
+	"\x05items\x18\b \x03(\v2\x16.shipping.ShipmentItemR\x05items\x120\n" +
+	"\aordered\x18\t \x03(\v2\x16.shipping.ShipmentItemR\aorderedJ\x04\b\x02\x10\x03\"\xa2\x01\n" +
+	"\x16CreateShipmentResponse\x12\x1f\n" +
 	"\vtracking_id\x18\x03 \x01(\tR\n" +
-	"trackingId\";\n" +
+	"trackingId\x12-\n" +
+	"\x05quote\x18\x04 \x01(\v2\x17.shipping.ShippingQuoteR\x05quote\x12,\n" +
+	"\x05items\x18\x05 \x03(\v2\x16.shipping.ShipmentItemR\x05itemsJ\x04\b\x01\x10\x02J\x04\b\x02\x10\x03\";\n" +
 	"\x18GetShipmentStatusRequest\x12\x1f\n" +
 	"\vtracking_id\x18\x01 \x01(\tR\n" +
-	"trackingId\"j\n" +
-	"\x19GetShipmentStatusResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\x05R\x06status\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1f\n" +
+	"trackingId\"\xc8\x01\n" +
+	"\x19GetShipmentStatusResponse\x12\x1f\n" +
 	"\vstatus_text\x18\x03 \x01(\tR\n" +
-	"statusText2\xc8\x01\n" +
+	"statusText\x120\n" +
+	"\x06status\x18\x04 \x01(\x0e2\x18.shipping.ShipmentStatusR\x06status\x12\x19\n" +
+	"\border_id\x18\x05 \x01(\x03R\aorderId\x121\n" +
+	"\ahistory\x18\x06 \x03(\v2\x17.shipping.ShipmentEventR\ahistoryJ\x04\b\x01\x10\x02J\x04\b\x02\x10\x03\"=\n" +
+	"\x1aGetShipmentTrackingRequest\x12\x1f\n" +
+	"\vtracking_id\x18\x01 \x01(\tR\n" +
+	"trackingId\"\x94\x03\n" +
+	"\x1bGetShipmentTrackingResponse\x12\x1f\n" +
+	"\vtracking_id\x18\x01 \x01(\tR\n" +
+	"trackingId\x12\x19\n" +
+	"\border_id\x18\x02 \x01(\x03R\aorderId\x120\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x18.shipping.ShipmentStatusR\x06status\x12\x1f\n" +
+	"\vstatus_text\x18\x04 \x01(\tR\n" +
+	"statusText\x12/\n" +
+	"\x06events\x18\x05 \x03(\v2\x17.shipping.ShipmentEventR\x06events\x12\x18\n" +
+	"\acarrier\x18\x06 \x01(\tR\acarrier\x12\x18\n" +
+	"\aservice\x18\a \x01(\tR\aservice\x126\n" +
+	"\x17carrier_tracking_number\x18\b \x01(\tR\x15carrierTrackingNumber\x12,\n" +
+	"\x05items\x18\t \x03(\v2\x16.shipping.ShipmentItemR\x05items\x12\x1b\n" +
+	"\treturn_id\x18\n" +
+	" \x01(\x03R\breturnId\"\x97\x01\n" +
+	"\x18GetShippingQuotesRequest\x12)\n" +
+	"\aaddress\x18\x01 \x01(\v2\x0f.common.AddressR\aaddress\x12!\n" +
+	"\fweight_grams\x18\x02 \x01(\x05R\vweightGrams\x12-\n" +
+	"\x06policy\x18\x03 \x01(\x0e2\x15.shipping.QuotePolicyR\x06policy\"L\n" +
+	"\x19GetShippingQuotesResponse\x12/\n" +
+	"\x06quotes\x18\x01 \x03(\v2\x17.shipping.ShippingQuoteR\x06quotes\"\xd1\x01\n" +
+	"\x1bCreateReturnShipmentRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\x03R\aorderId\x12\x1b\n" +
+	"\treturn_id\x18\x02 \x01(\x03R\breturnId\x12)\n" +
+	"\aaddress\x18\x03 \x01(\v2\x0f.common.AddressR\aaddress\x12!\n" +
+	"\fweight_grams\x18\x04 \x01(\x05R\vweightGrams\x12,\n" +
+	"\x05items\x18\x05 \x03(\v2\x16.shipping.ShipmentItemR\x05items\":\n" +
+	"\x17GetShipmentLabelRequest\x12\x1f\n" +
+	"\vtracking_id\x18\x01 \x01(\tR\n" +
+	"trackingId\"W\n" +
+	"\x18GetShipmentLabelResponse\x12!\n" +
+	"\fcontent_type\x18\x01 \x01(\tR\vcontentType\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\"\xec\x02\n" +
+	"\x15ShipmentStatusChanged\x12\x1f\n" +
+	"\vtracking_id\x18\x01 \x01(\tR\n" +
+	"trackingId\x12\x19\n" +
+	"\border_id\x18\x02 \x01(\x03R\aorderId\x120\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x18.shipping.ShipmentStatusR\x06status\x12A\n" +
+	"\x0fprevious_status\x18\x04 \x01(\x0e2\x18.shipping.ShipmentStatusR\x0epreviousStatus\x12\x1a\n" +
+	"\blocation\x18\x05 \x01(\tR\blocation\x12;\n" +
+	"\voccurred_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12,\n" +
+	"\x05items\x18\a \x03(\v2\x16.shipping.ShipmentItemR\x05items\x12\x1b\n" +
+	"\treturn_id\x18\b \x01(\x03R\breturnId*`\n" +
+	"\vQuotePolicy\x12\x1c\n" +
+	"\x18QUOTE_POLICY_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15QUOTE_POLICY_CHEAPEST\x10\x01\x12\x18\n" +
+	"\x14QUOTE_POLICY_FASTEST\x10\x02*\xf2\x01\n" +
+	"\x0eShipmentStatus\x12\x1f\n" +
+	"\x1bSHIPMENT_STATUS_UNSPECIFIED\x10\x00\x12\x1b\n" +
+	"\x17SHIPMENT_STATUS_CREATED\x10\x01\x12\x1e\n" +
+	"\x1aSHIPMENT_STATUS_IN_TRANSIT\x10\x02\x12$\n" +
+	" SHIPMENT_STATUS_OUT_FOR_DELIVERY\x10\x03\x12\x1d\n" +
+	"\x19SHIPMENT_STATUS_DELIVERED\x10\x04\x12#\n" +
+	"\x1fSHIPMENT_STATUS_DELIVERY_FAILED\x10\x05\x12\x18\n" +
+	"\x14SHIPMENT_STATUS_LOST\x10\x062\xce\x04\n" +
 	"\x0fShippingService\x12U\n" +
 	"\x0eCreateShipment\x12\x1f.shipping.CreateShipmentRequest\x1a .shipping.CreateShipmentResponse\"\x00\x12^\n" +
-	"\x11GetShipmentStatus\x12\".shipping.GetShipmentStatusRequest\x1a#.shipping.GetShipmentStatusResponse\"\x00B&Z$github.com/my-store/pkg/api/shippingb\x06proto3"
+	"\x11GetShipmentStatus\x12\".shipping.GetShipmentStatusRequest\x1a#.shipping.GetShipmentStatusResponse\"\x00\x12d\n" +
+	"\x13GetShipmentTracking\x12$.shipping.GetShipmentTrackingRequest\x1a%.shipping.GetShipmentTrackingResponse\"\x00\x12^\n" +
+	"\x11GetShippingQuotes\x12\".shipping.GetShippingQuotesRequest\x1a#.shipping.GetShippingQuotesResponse\"\x00\x12[\n" +
+	"\x10GetShipmentLabel\x12!.shipping.GetShipmentLabelRequest\x1a\".shipping.GetShipmentLabelResponse\"\x00\x12a\n" +
+	"\x14CreateReturnShipment\x12%.shipping.CreateReturnShipmentRequest\x1a .shipping.CreateShipmentResponse\"\x00B&Z$github.com/my-store/pkg/api/shippingb\x06proto3"
 
 var (
 	file_shipping_proto_rawDescOnce sync.Once
@@ -274,23 +1306,69 @@ func file_shipping_proto_rawDescGZIP() []byte {
 	return file_shipping_proto_rawDescData
 }
 
-var file_shipping_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_shipping_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_shipping_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_shipping_proto_goTypes = []any{
-	(*CreateShipmentRequest)(nil),     // 0: shipping.CreateShipmentRequest
-	(*CreateShipmentResponse)(nil),    // 1: shipping.CreateShipmentResponse
-	(*GetShipmentStatusRequest)(nil),  // 2: shipping.GetShipmentStatusRequest
-	(*GetShipmentStatusResponse)(nil), // 3: shipping.GetShipmentStatusResponse
+	(QuotePolicy)(0),                    // 0: shipping.QuotePolicy
+	(ShipmentStatus)(0),                 // 1: shipping.ShipmentStatus
+	(*ShippingQuote)(nil),               // 2: shipping.ShippingQuote
+	(*ShipmentItem)(nil),                // 3: shipping.ShipmentItem
+	(*ShipmentEvent)(nil),               // 4: shipping.ShipmentEvent
+	(*CreateShipmentRequest)(nil),       // 5: shipping.CreateShipmentRequest
+	(*CreateShipmentResponse)(nil),      // 6: shipping.CreateShipmentResponse
+	(*GetShipmentStatusRequest)(nil),    // 7: shipping.GetShipmentStatusRequest
+	(*GetShipmentStatusResponse)(nil),   // 8: shipping.GetShipmentStatusResponse
+	(*GetShipmentTrackingRequest)(nil),  // 9: shipping.GetShipmentTrackingRequest
+	(*GetShipmentTrackingResponse)(nil), // 10: shipping.GetShipmentTrackingResponse
+	(*GetShippingQuotesRequest)(nil),    // 11: shipping.GetShippingQuotesRequest
+	(*GetShippingQuotesResponse)(nil),   // 12: shipping.GetShippingQuotesResponse
+	(*CreateReturnShipmentRequest)(nil), // 13: shipping.CreateReturnShipmentRequest
+	(*GetShipmentLabelRequest)(nil),     // 14: shipping.GetShipmentLabelRequest
+	(*GetShipmentLabelResponse)(nil),    // 15: shipping.GetShipmentLabelResponse
+	(*ShipmentStatusChanged)(nil),       // 16: shipping.ShipmentStatusChanged
+	(*timestamppb.Timestamp)(nil),       // 17: google.protobuf.Timestamp
+	(*common.Address)(nil),              // 18: common.Address
 }
 var file_shipping_proto_depIdxs = []int32{
-	0, // 0: shipping.ShippingService.CreateShipment:input_type -> shipping.CreateShipmentRequest
-	2, // 1: shipping.ShippingService.GetShipmentStatus:input_type -> shipping.GetShipmentStatusRequest
-	1, // 2: shipping.ShippingService.CreateShipment:output_type -> shipping.CreateShipmentResponse
-	3, // 3: shipping.ShippingService.GetShipmentStatus:output_type -> shipping.GetShipmentStatusResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1,  // 0: shipping.ShipmentEvent.status:type_name -> shipping.ShipmentStatus
+	17, // 1: shipping.ShipmentEvent.occurred_at:type_name -> google.protobuf.Timestamp
+	18, // 2: shipping.CreateShipmentRequest.address:type_name -> common.Address
+	0,  // 3: shipping.CreateShipmentRequest.policy:type_name -> shipping.QuotePolicy
+	3,  // 4: shipping.CreateShipmentRequest.items:type_name -> shipping.ShipmentItem
+	3,  // 5: shipping.CreateShipmentRequest.ordered:type_name -> shipping.ShipmentItem
+	2,  // 6: shipping.CreateShipmentResponse.quote:type_name -> shipping.ShippingQuote
+	3,  // 7: shipping.CreateShipmentResponse.items:type_name -> shipping.ShipmentItem
+	1,  // 8: shipping.GetShipmentStatusResponse.status:type_name -> shipping.ShipmentStatus
+	4,  // 9: shipping.GetShipmentStatusResponse.history:type_name -> shipping.ShipmentEvent
+	1,  // 10: shipping.GetShipmentTrackingResponse.status:type_name -> shipping.ShipmentStatus
+	4,  // 11: shipping.GetShipmentTrackingResponse.events:type_name -> shipping.ShipmentEvent
+	3,  // 12: shipping.GetShipmentTrackingResponse.items:type_name -> shipping.ShipmentItem
+	18, // 13: shipping.GetShippingQuotesRequest.address:type_name -> common.Address
+	0,  // 14: shipping.GetShippingQuotesRequest.policy:type_name -> shipping.QuotePolicy
+	2,  // 15: shipping.GetShippingQuotesResponse.quotes:type_name -> shipping.ShippingQuote
+	18, // 16: shipping.CreateReturnShipmentRequest.address:type_name -> common.Address
+	3,  // 17: shipping.CreateReturnShipmentRequest.items:type_name -> shipping.ShipmentItem
+	1,  // 18: shipping.ShipmentStatusChanged.status:type_name -> shipping.ShipmentStatus
+	1,  // 19: shipping.ShipmentStatusChanged.previous_status:type_name -> shipping.ShipmentStatus
+	17, // 20: shipping.ShipmentStatusChanged.occurred_at:type_name -> google.protobuf.Timestamp
+	3,  // 21: shipping.ShipmentStatusChanged.items:type_name -> shipping.ShipmentItem
+	5,  // 22: shipping.ShippingService.CreateShipment:input_type -> shipping.CreateShipmentRequest
+	7,  // 23: shipping.ShippingService.GetShipmentStatus:input_type -> shipping.GetShipmentStatusRequest
+	9,  // 24: shipping.ShippingService.GetShipmentTracking:input_type -> shipping.GetShipmentTrackingRequest
+	11, // 25: shipping.ShippingService.GetShippingQuotes:input_type -> shipping.GetShippingQuotesRequest
+	14, // 26: shipping.ShippingService.GetShipmentLabel:input_type -> shipping.GetShipmentLabelRequest
+	13, // 27: shipping.ShippingService.CreateReturnShipment:input_type -> shipping.CreateReturnShipmentRequest
+	6,  // 28: shipping.ShippingService.CreateShipment:output_type -> shipping.CreateShipmentResponse
+	8,  // 29: shipping.ShippingService.GetShipmentStatus:output_type -> shipping.GetShipmentStatusResponse
+	10, // 30: shipping.ShippingService.GetShipmentTracking:output_type -> shipping.GetShipmentTrackingResponse
+	12, // 31: shipping.ShippingService.GetShippingQuotes:output_type -> shipping.GetShippingQuotesResponse
+	15, // 32: shipping.ShippingService.GetShipmentLabel:output_type -> shipping.GetShipmentLabelResponse
+	6,  // 33: shipping.ShippingService.CreateReturnShipment:output_type -> shipping.CreateShipmentResponse
+	28, // [28:34] is the sub-list for method output_type
+	22, // [22:28] is the sub-list for method input_type
+	22, // [22:22] is the sub-list for extension type_name
+	22, // [22:22] is the sub-list for extension extendee
+	0,  // [0:22] is the sub-list for field type_name
 }
 
 func init() { file_shipping_proto_init() }
@@ -303,13 +1381,14 @@ func file_shipping_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shipping_proto_rawDesc), len(file_shipping_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      2,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_shipping_proto_goTypes,
 		DependencyIndexes: file_shipping_proto_depIdxs,
+		EnumInfos:         file_shipping_proto_enumTypes,
 		MessageInfos:      file_shipping_proto_msgTypes,
 	}.Build()
 	File_shipping_proto = out.File
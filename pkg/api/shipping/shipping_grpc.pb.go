@@ -19,16 +19,35 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ShippingService_CreateShipment_FullMethodName    = "/shipping.ShippingService/CreateShipment"
-	ShippingService_GetShipmentStatus_FullMethodName = "/shipping.ShippingService/GetShipmentStatus"
+	ShippingService_CreateShipment_FullMethodName       = "/shipping.ShippingService/CreateShipment"
+	ShippingService_GetShipmentStatus_FullMethodName    = "/shipping.ShippingService/GetShipmentStatus"
+	ShippingService_GetShipmentTracking_FullMethodName  = "/shipping.ShippingService/GetShipmentTracking"
+	ShippingService_GetShippingQuotes_FullMethodName    = "/shipping.ShippingService/GetShippingQuotes"
+	ShippingService_GetShipmentLabel_FullMethodName     = "/shipping.ShippingService/GetShipmentLabel"
+	ShippingService_CreateReturnShipment_FullMethodName = "/shipping.ShippingService/CreateReturnShipment"
 )
 
 // ShippingServiceClient is the client API for ShippingService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Errors are returned as gRPC status codes carrying google.rpc.ErrorInfo and,
+// for invalid input, google.rpc.BadRequest details. Response fields 1 and 2 are
+// reserved from the earlier in-message status and error fields.
 type ShippingServiceClient interface {
 	CreateShipment(ctx context.Context, in *CreateShipmentRequest, opts ...grpc.CallOption) (*CreateShipmentResponse, error)
 	GetShipmentStatus(ctx context.Context, in *GetShipmentStatusRequest, opts ...grpc.CallOption) (*GetShipmentStatusResponse, error)
+	// GetShipmentTracking returns the full timeline of carrier scans.
+	GetShipmentTracking(ctx context.Context, in *GetShipmentTrackingRequest, opts ...grpc.CallOption) (*GetShipmentTrackingResponse, error)
+	// GetShippingQuotes prices a parcel to an address with every carrier, e.g. for
+	// checkout to show the options. It books nothing.
+	GetShippingQuotes(ctx context.Context, in *GetShippingQuotesRequest, opts ...grpc.CallOption) (*GetShippingQuotesResponse, error)
+	// GetShipmentLabel returns the shipping label to print and stick on the parcel.
+	GetShipmentLabel(ctx context.Context, in *GetShipmentLabelRequest, opts ...grpc.CallOption) (*GetShipmentLabelResponse, error)
+	// CreateReturnShipment books the parcel a customer sends back for an approved
+	// return, from their address to the returns center. Booking a return again
+	// returns its existing shipment.
+	CreateReturnShipment(ctx context.Context, in *CreateReturnShipmentRequest, opts ...grpc.CallOption) (*CreateShipmentResponse, error)
 }
 
 type shippingServiceClient struct {
@@ -59,12 +78,67 @@ func (c *shippingServiceClient) GetShipmentStatus(ctx context.Context, in *GetSh
 	return out, nil
 }
 
+func (c *shippingServiceClient) GetShipmentTracking(ctx context.Context, in *GetShipmentTrackingRequest, opts ...grpc.CallOption) (*GetShipmentTrackingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetShipmentTrackingResponse)
+	err := c.cc.Invoke(ctx, ShippingService_GetShipmentTracking_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shippingServiceClient) GetShippingQuotes(ctx context.Context, in *GetShippingQuotesRequest, opts ...grpc.CallOption) (*GetShippingQuotesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetShippingQuotesResponse)
+	err := c.cc.Invoke(ctx, ShippingService_GetShippingQuotes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shippingServiceClient) GetShipmentLabel(ctx context.Context, in *GetShipmentLabelRequest, opts ...grpc.CallOption) (*GetShipmentLabelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetShipmentLabelResponse)
+	err := c.cc.Invoke(ctx, ShippingService_GetShipmentLabel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shippingServiceClient) CreateReturnShipment(ctx context.Context, in *CreateReturnShipmentRequest, opts ...grpc.CallOption) (*CreateShipmentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateShipmentResponse)
+	err := c.cc.Invoke(ctx, ShippingService_CreateReturnShipment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShippingServiceServer is the server API for ShippingService service.
 // All implementations must embed UnimplementedShippingServiceServer
 // for forward compatibility.
+//
+// Errors are returned as gRPC status codes carrying google.rpc.ErrorInfo and,
+// for invalid input, google.rpc.BadRequest details. Response fields 1 and 2 are
+// reserved from the earlier in-message status and error fields.
 type ShippingServiceServer interface {
 	CreateShipment(context.Context, *CreateShipmentRequest) (*CreateShipmentResponse, error)
 	GetShipmentStatus(context.Context, *GetShipmentStatusRequest) (*GetShipmentStatusResponse, error)
+	// GetShipmentTracking returns the full timeline of carrier scans.
+	GetShipmentTracking(context.Context, *GetShipmentTrackingRequest) (*GetShipmentTrackingResponse, error)
+	// GetShippingQuotes prices a parcel to an address with every carrier, e.g. for
+	// checkout to show the options. It books nothing.
+	GetShippingQuotes(context.Context, *GetShippingQuotesRequest) (*GetShippingQuotesResponse, error)
+	// GetShipmentLabel returns the shipping label to print and stick on the parcel.
+	GetShipmentLabel(context.Context, *GetShipmentLabelRequest) (*GetShipmentLabelResponse, error)
+	// CreateReturnShipment books the parcel a customer sends back for an approved
+	// return, from their address to the returns center. Booking a return again
+	// returns its existing shipment.
+	CreateReturnShipment(context.Context, *CreateReturnShipmentRequest) (*CreateShipmentResponse, error)
 	mustEmbedUnimplementedShippingServiceServer()
 }
 
@@ -81,6 +155,18 @@ func (UnimplementedShippingServiceServer) CreateShipment(context.Context, *Creat
 func (UnimplementedShippingServiceServer) GetShipmentStatus(context.Context, *GetShipmentStatusRequest) (*GetShipmentStatusResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetShipmentStatus not implemented")
 }
+func (UnimplementedShippingServiceServer) GetShipmentTracking(context.Context, *GetShipmentTrackingRequest) (*GetShipmentTrackingResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetShipmentTracking not implemented")
+}
+func (UnimplementedShippingServiceServer) GetShippingQuotes(context.Context, *GetShippingQuotesRequest) (*GetShippingQuotesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetShippingQuotes not implemented")
+}
+func (UnimplementedShippingServiceServer) GetShipmentLabel(context.Context, *GetShipmentLabelRequest) (*GetShipmentLabelResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetShipmentLabel not implemented")
+}
+func (UnimplementedShippingServiceServer) CreateReturnShipment(context.Context, *CreateReturnShipmentRequest) (*CreateShipmentResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateReturnShipment not implemented")
+}
 func (UnimplementedShippingServiceServer) mustEmbedUnimplementedShippingServiceServer() {}
 func (UnimplementedShippingServiceServer) testEmbeddedByValue()                         {}
 
@@ -138,6 +224,78 @@ func _ShippingService_GetShipmentStatus_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShippingService_GetShipmentTracking_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetShipmentTrackingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShippingServiceServer).GetShipmentTracking(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShippingService_GetShipmentTracking_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShippingServiceServer).GetShipmentTracking(ctx, req.(*GetShipmentTrackingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShippingService_GetShippingQuotes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetShippingQuotesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShippingServiceServer).GetShippingQuotes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShippingService_GetShippingQuotes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShippingServiceServer).GetShippingQuotes(ctx, req.(*GetShippingQuotesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShippingService_GetShipmentLabel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetShipmentLabelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShippingServiceServer).GetShipmentLabel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShippingService_GetShipmentLabel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShippingServiceServer).GetShipmentLabel(ctx, req.(*GetShipmentLabelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShippingService_CreateReturnShipment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateReturnShipmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShippingServiceServer).CreateReturnShipment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShippingService_CreateReturnShipment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShippingServiceServer).CreateReturnShipment(ctx, req.(*CreateReturnShipmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ShippingService_ServiceDesc is the grpc.ServiceDesc for ShippingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +311,22 @@ var ShippingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetShipmentStatus",
 			Handler:    _ShippingService_GetShipmentStatus_Handler,
 		},
+		{
+			MethodName: "GetShipmentTracking",
+			Handler:    _ShippingService_GetShipmentTracking_Handler,
+		},
+		{
+			MethodName: "GetShippingQuotes",
+			Handler:    _ShippingService_GetShippingQuotes_Handler,
+		},
+		{
+			MethodName: "GetShipmentLabel",
+			Handler:    _ShippingService_GetShipmentLabel_Handler,
+		},
+		{
+			MethodName: "CreateReturnShipment",
+			Handler:    _ShippingService_CreateReturnShipment_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "shipping.proto",
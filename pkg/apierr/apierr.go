@@ -0,0 +1,131 @@
+// Package apierr builds and inspects gRPC errors that carry google.rpc error details.
+//
+// Every service returns failures as real gRPC status errors. Expected failures carry an
+// ErrorInfo detail with a stable machine-readable reason (e.g. "USER_NOT_FOUND") and,
+// for invalid input, a BadRequest detail listing each offending field. Callers should
+// branch on the status code and reason, never on the message text.
+package apierr
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain identifies the service that produced an error, e.g. "auth.my-store".
+type Domain string
+
+// Error returns a status error with an ErrorInfo detail.
+func (d Domain) Error(code codes.Code, reason, msg string) error {
+	return d.WithMetadata(code, reason, msg, nil)
+}
+
+// WithMetadata is like Error but attaches extra key/value context to the ErrorInfo.
+func (d Domain) WithMetadata(code codes.Code, reason, msg string, metadata map[string]string) error {
+	return attach(status.New(code, msg), &errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   string(d),
+		Metadata: metadata,
+	})
+}
+
+// InvalidArgument returns an InvalidArgument error listing the rejected fields.
+func (d Domain) InvalidArgument(reason, msg string, violations []*errdetails.BadRequest_FieldViolation) error {
+	return attach(status.New(codes.InvalidArgument, msg),
+		&errdetails.ErrorInfo{Reason: reason, Domain: string(d)},
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+}
+
+// Unavailable returns an Unavailable error with a RetryInfo detail telling the
+// caller how long to wait before trying again.
+func (d Domain) Unavailable(reason, msg string, retryAfter time.Duration) error {
+	return attach(status.New(codes.Unavailable, msg),
+		&errdetails.ErrorInfo{Reason: reason, Domain: string(d)},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)},
+	)
+}
+
+// ResourceExhausted returns a ResourceExhausted error with a RetryInfo detail
+// telling the caller how long to wait before trying again.
+func (d Domain) ResourceExhausted(reason, msg string, retryAfter time.Duration) error {
+	return attach(status.New(codes.ResourceExhausted, msg),
+		&errdetails.ErrorInfo{Reason: reason, Domain: string(d)},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)},
+	)
+}
+
+// FieldViolation describes why a single request field was rejected. Reason is a
+// stable code such as "required" that clients can use to localise the description.
+func FieldViolation(field, reason, description string) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Reason:      reason,
+		Description: description,
+	}
+}
+
+func attach(st *status.Status, details ...protoadapt.MessageV1) error {
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		// Details only fail to attach if they can't be marshalled; the bare status is still useful.
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// Info returns the ErrorInfo detail of err, or nil if it has none.
+func Info(err error) *errdetails.ErrorInfo {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
+
+// Reason returns the ErrorInfo reason of err, or "" if it has none.
+func Reason(err error) string {
+	return Info(err).GetReason()
+}
+
+// FieldViolations returns the BadRequest field violations carried by err.
+func FieldViolations(err error) []*errdetails.BadRequest_FieldViolation {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			return br.GetFieldViolations()
+		}
+	}
+	return nil
+}
+
+// RetryDelay returns the delay suggested by the RetryInfo detail of err, if any.
+func RetryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return ri.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// Is reports whether err is a status error with the given code and reason.
+func Is(err error, code codes.Code, reason string) bool {
+	return err != nil && status.Code(err) == code && Reason(err) == reason
+}
@@ -0,0 +1,20 @@
+package events
+
+import (
+	"context"
+
+	"github.com/my-store/pkg/kafka"
+)
+
+// Publisher publishes messages, returning once they are stored. *kafka.Producer
+// publishes to Kafka, and the broker in package memory publishes in process.
+type Publisher interface {
+	Publish(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Consumer handles the records of a consumer group's topics until ctx is
+// cancelled. *kafka.Consumer, the consumer in package consumer and the one in
+// package memory are consumers.
+type Consumer interface {
+	Run(ctx context.Context, handle kafka.Handler)
+}
@@ -0,0 +1,22 @@
+package events
+
+import (
+	orderpb "github.com/my-store/pkg/api/order"
+	shippingpb "github.com/my-store/pkg/api/shipping"
+)
+
+// Event types the services publish, named <producer>.<event>.
+const (
+	TypeShipmentStatusChanged = "shipping.shipment_status_changed"
+	TypeItemsRestocked        = "order.items_restocked"
+)
+
+// Catalog returns a registry of every event the services publish. A new version
+// of an event is registered here after the versions before it, so the registry
+// rejects it when it breaks their consumers.
+func Catalog() *Registry {
+	r := NewRegistry()
+	r.MustRegister(TypeShipmentStatusChanged, 1, &shippingpb.ShipmentStatusChanged{})
+	r.MustRegister(TypeItemsRestocked, 1, &orderpb.ItemsRestocked{})
+	return r
+}
@@ -0,0 +1,198 @@
+// Command eventctl operates the services' Kafka consumer groups and the read
+// models they build:
+//
+//	go run ./pkg/events/cmd/eventctl replay-dlq -group order-service [-dry-run]
+//	go run ./pkg/events/cmd/eventctl reset-offsets -group order-service -topics shipment-status-changed -to-time 2026-10-01T00:00:00Z [-dry-run]
+//	go run ./pkg/events/cmd/eventctl rebuild-projection -db postgres://... -projection <name> [-detach]
+//
+// replay-dlq sends the group's dead letters back to it, listing each one. With
+// -dry-run it only lists them.
+//
+// reset-offsets moves the group to -to-time, -to-offset or -to-end on the
+// topics, so it reprocesses (or skips) records from there. Stop the group's
+// consumers first.
+//
+// rebuild-projection asks the service owning the database to rebuild a read
+// model from its topics, and follows the rebuild until it finishes unless
+// -detach is given. Live consumption carries on meanwhile.
+//
+// Brokers come from -brokers or KAFKA_BROKERS, the database from -db or
+// DATABASE_URL.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/my-store/pkg/events/consumer"
+	"github.com/my-store/pkg/events/projection"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch os.Args[1] {
+	case "replay-dlq":
+		replayDLQ(ctx, os.Args[2:])
+	case "reset-offsets":
+		resetOffsets(ctx, os.Args[2:])
+	case "rebuild-projection":
+		rebuildProjection(ctx, os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	log.Fatal(`usage:
+  eventctl replay-dlq -group <group> [-brokers <brokers>] [-dry-run]
+  eventctl reset-offsets -group <group> -topics <topics> (-to-time <RFC 3339> | -to-offset <n> | -to-end) [-brokers <brokers>] [-dry-run]
+  eventctl rebuild-projection -projection <name> [-db <url>] [-detach]`)
+}
+
+func replayDLQ(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("replay-dlq", flag.ExitOnError)
+	brokers := fs.String("brokers", os.Getenv("KAFKA_BROKERS"), "comma-separated Kafka bootstrap brokers")
+	group := fs.String("group", "", "consumer group whose dead letters to replay")
+	dryRun := fs.Bool("dry-run", false, "list the dead letters without replaying them")
+	idle := fs.Duration("idle", 10*time.Second, "stop once the dead-letter topic has had nothing new for this long")
+	fs.Parse(args)
+	if *group == "" || *brokers == "" {
+		usage()
+	}
+
+	n, err := consumer.ReplayDLQ(ctx, strings.Split(*brokers, ","), *group,
+		consumer.ReplayOptions{DryRun: *dryRun, Idle: *idle},
+		func(f consumer.Failure) {
+			fmt.Printf("%s/%d@%d key=%s attempts=%d failed_at=%s error=%q\n",
+				f.Topic, f.Partition, f.Offset, f.Key, f.Attempts, f.FailedAt.Format(time.RFC3339), f.Error)
+		})
+	if err != nil {
+		log.Fatalf("Failed to replay %s: %v", consumer.DLQTopic(*group), err)
+	}
+	if *dryRun {
+		log.Printf("%d dead letters in %s", n, consumer.DLQTopic(*group))
+	} else {
+		log.Printf("Replayed %d dead letters to %s", n, consumer.ReplayTopic(*group))
+	}
+}
+
+func resetOffsets(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("reset-offsets", flag.ExitOnError)
+	brokers := fs.String("brokers", os.Getenv("KAFKA_BROKERS"), "comma-separated Kafka bootstrap brokers")
+	group := fs.String("group", "", "consumer group to move")
+	topics := fs.String("topics", "", "comma-separated topics to move the group on")
+	toTime := fs.String("to-time", "", "move to the first record at or after this RFC 3339 time")
+	toOffset := fs.Int64("to-offset", -1, "move to this offset in every partition; 0 is the start")
+	toEnd := fs.Bool("to-end", false, "move to the end, skipping everything published so far")
+	dryRun := fs.Bool("dry-run", false, "show the new offsets without committing them")
+	fs.Parse(args)
+	if *group == "" || *topics == "" || *brokers == "" {
+		usage()
+	}
+
+	var to consumer.ResetTo
+	switch {
+	case *toTime != "":
+		t, err := time.Parse(time.RFC3339, *toTime)
+		if err != nil {
+			log.Fatalf("Invalid -to-time: %v", err)
+		}
+		to.Time = t
+	case *toOffset >= 0:
+		to.Offset = *toOffset
+	case *toEnd:
+		to.Offset = consumer.OffsetEnd
+	default:
+		usage()
+	}
+
+	changes, err := consumer.ResetOffsets(ctx, strings.Split(*brokers, ","), *group, strings.Split(*topics, ","), to, *dryRun)
+	if err != nil {
+		log.Fatalf("Failed to reset %s: %v", *group, err)
+	}
+	for _, c := range changes {
+		fmt.Printf("%s/%d %d -> %d\n", c.Topic, c.Partition, c.From, c.To)
+	}
+	if *dryRun {
+		log.Printf("Dry run; %s was not moved", *group)
+	} else {
+		log.Printf("Moved %s on %d partitions", *group, len(changes))
+	}
+}
+
+func rebuildProjection(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("rebuild-projection", flag.ExitOnError)
+	dsn := fs.String("db", os.Getenv("DATABASE_URL"), "Postgres URL of the service owning the projection")
+	name := fs.String("projection", "", "projection to rebuild")
+	detach := fs.Bool("detach", false, "request the rebuild without following it")
+	fs.Parse(args)
+	if *name == "" || *dsn == "" {
+		usage()
+	}
+
+	db, err := sql.Open("pgx", *dsn)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	id, err := projection.Request(ctx, db, *name)
+	if errors.Is(err, projection.ErrUnknownProjection) {
+		log.Fatalf("No service rebuilds this projection: %v", err)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Rebuild %d of %s requested", id, *name)
+	if *detach {
+		return
+	}
+
+	// The service's rebuilder does the work; this only reports on it.
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Stopped following; rebuild %d carries on", id)
+			return
+		case <-ticker.C:
+		}
+		rebuild, err := projection.Get(ctx, db, id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		switch rebuild.Status {
+		case projection.StatusRequested:
+			log.Printf("Waiting for the service to start rebuild %d", id)
+		case projection.StatusRunning:
+			percent := 0.0
+			if rebuild.Total > 0 {
+				percent = 100 * float64(rebuild.Replayed) / float64(rebuild.Total)
+			}
+			log.Printf("Replayed %d of %d offsets (%.1f%%)", rebuild.Replayed, rebuild.Total, percent)
+		case projection.StatusDone:
+			log.Printf("Rebuilt %s: replayed %d offsets, skipped %d events", *name, rebuild.Replayed, rebuild.Skipped)
+			return
+		case projection.StatusFailed:
+			log.Fatalf("Rebuild of %s failed: %s", *name, rebuild.Error)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CheckCompatible reports whether messages written with newer can be read as
+// older and the other way round, by the protobuf wire format. The rules, applied
+// to nested messages and enums too:
+//
+//   - A field keeps its number's kind, cardinality, map-ness and oneof
+//     membership, and for message and enum fields the type it refers to.
+//   - A removed field's number is reserved, so it can never be reused with
+//     another meaning; a new field doesn't use a number older reserved.
+//   - An enum value's number stays defined or is reserved.
+//
+// Renaming fields and adding fields or enum values are compatible. The error
+// lists every violation and matches ErrIncompatible.
+func CheckCompatible(older, newer protoreflect.MessageDescriptor) error {
+	c := &compatChecker{seen: make(map[protoreflect.FullName]bool)}
+	c.message(older, newer)
+	if len(c.violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrIncompatible, errors.Join(c.violations...))
+}
+
+type compatChecker struct {
+	seen       map[protoreflect.FullName]bool // nested types already checked
+	violations []error
+}
+
+func (c *compatChecker) violate(format string, args ...any) {
+	c.violations = append(c.violations, fmt.Errorf(format, args...))
+}
+
+func (c *compatChecker) message(older, newer protoreflect.MessageDescriptor) {
+	oldFields := older.Fields()
+	for i := range oldFields.Len() {
+		of := oldFields.Get(i)
+		nf := newer.Fields().ByNumber(of.Number())
+		if nf == nil {
+			if !newer.ReservedRanges().Has(of.Number()) {
+				c.violate("%s: field %s (%d) removed without reserving its number", newer.FullName(), of.Name(), of.Number())
+			}
+			continue
+		}
+		c.field(newer.FullName(), of, nf)
+	}
+
+	newFields := newer.Fields()
+	for i := range newFields.Len() {
+		nf := newFields.Get(i)
+		if oldFields.ByNumber(nf.Number()) == nil && older.ReservedRanges().Has(nf.Number()) {
+			c.violate("%s: field %s reuses reserved number %d", newer.FullName(), nf.Name(), nf.Number())
+		}
+	}
+}
+
+func (c *compatChecker) field(msg protoreflect.FullName, of, nf protoreflect.FieldDescriptor) {
+	switch {
+	case of.IsMap() != nf.IsMap():
+		c.violate("%s: field %d changed between map and non-map", msg, of.Number())
+		return
+	case of.Kind() != nf.Kind():
+		c.violate("%s: field %d changed type from %s to %s", msg, of.Number(), of.Kind(), nf.Kind())
+		return
+	case of.Cardinality() != nf.Cardinality():
+		c.violate("%s: field %d changed cardinality from %s to %s", msg, of.Number(), of.Cardinality(), nf.Cardinality())
+		return
+	case (of.ContainingOneof() == nil) != (nf.ContainingOneof() == nil):
+		c.violate("%s: field %d moved into or out of a oneof", msg, of.Number())
+		return
+	}
+
+	switch of.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		om, nm := of.Message(), nf.Message()
+		if om.FullName() != nm.FullName() {
+			c.violate("%s: field %d changed type from %s to %s", msg, of.Number(), om.FullName(), nm.FullName())
+			return
+		}
+		if !c.seen[nm.FullName()] {
+			c.seen[nm.FullName()] = true
+			c.message(om, nm)
+		}
+	case protoreflect.EnumKind:
+		oe, ne := of.Enum(), nf.Enum()
+		if oe.FullName() != ne.FullName() {
+			c.violate("%s: field %d changed type from %s to %s", msg, of.Number(), oe.FullName(), ne.FullName())
+			return
+		}
+		if !c.seen[ne.FullName()] {
+			c.seen[ne.FullName()] = true
+			c.enum(oe, ne)
+		}
+	}
+}
+
+func (c *compatChecker) enum(older, newer protoreflect.EnumDescriptor) {
+	values := older.Values()
+	for i := range values.Len() {
+		v := values.Get(i)
+		if newer.Values().ByNumber(v.Number()) == nil && !newer.ReservedRanges().Has(v.Number()) {
+			c.violate("%s: value %s (%d) removed without reserving its number", newer.FullName(), v.Name(), v.Number())
+		}
+	}
+}
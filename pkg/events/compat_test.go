@@ -0,0 +1,130 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// field returns an optional field of a proto3 message.
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     typ.Enum(),
+	}
+}
+
+// inOneof puts f in the message's oneof with the given index.
+func inOneof(f *descriptorpb.FieldDescriptorProto, index int32) *descriptorpb.FieldDescriptorProto {
+	f.OneofIndex = proto.Int32(index)
+	return f
+}
+
+// reserved reserves a field number.
+func reserved(number int32) *descriptorpb.DescriptorProto_ReservedRange {
+	return &descriptorpb.DescriptorProto_ReservedRange{Start: proto.Int32(number), End: proto.Int32(number + 1)}
+}
+
+// message builds test.Event, with a oneof named "choice" when any field is in it.
+func message(t *testing.T, m *descriptorpb.DescriptorProto) protoreflect.MessageDescriptor {
+	t.Helper()
+	m.Name = proto.String("Event")
+	for _, f := range m.Field {
+		if f.OneofIndex != nil && len(m.OneofDecl) == 0 {
+			m.OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("choice")}}
+		}
+	}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("test/event.proto"),
+		Package:     proto.String("test"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{m},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+const (
+	typeString = descriptorpb.FieldDescriptorProto_TYPE_STRING
+	typeInt64  = descriptorpb.FieldDescriptorProto_TYPE_INT64
+)
+
+func TestCheckCompatible(t *testing.T) {
+	older := &descriptorpb.DescriptorProto{Field: []*descriptorpb.FieldDescriptorProto{
+		field("id", 1, typeInt64),
+		field("note", 2, typeString),
+	}}
+
+	tests := []struct {
+		name  string
+		newer *descriptorpb.DescriptorProto
+		ok    bool
+	}{
+		{"unchanged", &descriptorpb.DescriptorProto{Field: []*descriptorpb.FieldDescriptorProto{
+			field("id", 1, typeInt64), field("note", 2, typeString),
+		}}, true},
+		{"field renamed and added", &descriptorpb.DescriptorProto{Field: []*descriptorpb.FieldDescriptorProto{
+			field("id", 1, typeInt64), field("comment", 2, typeString), field("extra", 3, typeString),
+		}}, true},
+		{"removed field reserved", &descriptorpb.DescriptorProto{
+			Field:         []*descriptorpb.FieldDescriptorProto{field("id", 1, typeInt64)},
+			ReservedRange: []*descriptorpb.DescriptorProto_ReservedRange{reserved(2)},
+		}, true},
+		{"removed field not reserved", &descriptorpb.DescriptorProto{Field: []*descriptorpb.FieldDescriptorProto{
+			field("id", 1, typeInt64),
+		}}, false},
+		{"kind changed", &descriptorpb.DescriptorProto{Field: []*descriptorpb.FieldDescriptorProto{
+			field("id", 1, typeString), field("note", 2, typeString),
+		}}, false},
+		{"moved into a oneof", &descriptorpb.DescriptorProto{Field: []*descriptorpb.FieldDescriptorProto{
+			field("id", 1, typeInt64), inOneof(field("note", 2, typeString), 0),
+		}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCompatible(message(t, older), message(t, tt.newer))
+			if tt.ok && err != nil {
+				t.Fatalf("want compatible, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrIncompatible) {
+				t.Fatalf("want ErrIncompatible, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckCompatibleReservedReuse(t *testing.T) {
+	older := message(t, &descriptorpb.DescriptorProto{
+		Field:         []*descriptorpb.FieldDescriptorProto{field("id", 1, typeInt64)},
+		ReservedRange: []*descriptorpb.DescriptorProto_ReservedRange{reserved(2)},
+	})
+	newer := message(t, &descriptorpb.DescriptorProto{Field: []*descriptorpb.FieldDescriptorProto{
+		field("id", 1, typeInt64), field("note", 2, typeString),
+	}})
+	if err := CheckCompatible(older, newer); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("want ErrIncompatible, got %v", err)
+	}
+}
+
+func TestRegisterRejectsReusedMessage(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("test.event", 1, &timestamppb.Timestamp{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("test.event", 2, &timestamppb.Timestamp{}); err == nil {
+		t.Fatal("registering a version's message again as the next version succeeded")
+	}
+	if err := r.Register("test.other", 1, &timestamppb.Timestamp{}); err == nil {
+		t.Fatal("registering a message for a second event type succeeded")
+	}
+}
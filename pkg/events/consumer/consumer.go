@@ -0,0 +1,360 @@
+// Package consumer runs the services' Kafka consumer groups with what every
+// event consumer needs on top of package kafka's conventions:
+//
+//   - Graceful rebalance and shutdown. Offsets are committed for handled records
+//     before partitions move to another member, and a record in progress when
+//     the service stops gets DrainTimeout to finish instead of being cut off.
+//   - Retry topics. A record whose handler fails is published to the group's
+//     next retry topic and handled again once its delay has passed, without
+//     holding up the records behind it. After the last retry it goes to the
+//     group's dead-letter topic with headers describing the failure, from where
+//     ReplayDLQ sends it back.
+//   - Idempotent handling. Idempotent records each event ID in the service's
+//     processed_events table in the transaction of the handler's changes, so a
+//     redelivered event changes nothing.
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/my-store/pkg/events"
+	"github.com/my-store/pkg/kafka"
+	"github.com/my-store/pkg/metrics"
+	"github.com/my-store/pkg/telemetry"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Headers the consumer adds to records it retries or dead-letters.
+const (
+	HeaderTopic     = "x-original-topic"     // topic the record was first published to
+	HeaderPartition = "x-original-partition" // and its partition there
+	HeaderOffset    = "x-original-offset"    // and its offset there
+	HeaderAttempts  = "x-attempts"           // failed attempts so far
+	HeaderRetryAt   = "x-retry-at"           // RFC 3339 time before which a retry isn't handled
+	HeaderError     = "x-error"              // what the handler returned last
+	HeaderFailedAt  = "x-failed-at"          // RFC 3339 time of the last failure
+	HeaderGroup     = "x-consumer-group"     // group that failed to handle the record
+)
+
+// RetryTopic returns the topic holding the group's records for their nth retry.
+func RetryTopic(group string, n int) string {
+	return fmt.Sprintf("%s.retry.%d", group, n)
+}
+
+// DLQTopic returns the group's dead-letter topic.
+func DLQTopic(group string) string {
+	return group + ".dlq"
+}
+
+// ReplayTopic returns the topic the group reads replayed dead letters from.
+func ReplayTopic(group string) string {
+	return group + ".replay"
+}
+
+// DefaultRetryDelays retry a failed record after ten seconds, a minute and ten
+// minutes.
+var DefaultRetryDelays = []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute}
+
+// RetryDelaysFromEnv returns the delays listed in CONSUMER_RETRY_DELAYS, such as
+// "10s,1m,10m", or DefaultRetryDelays when it isn't set. "none" turns retries
+// off, sending failed records straight to the dead-letter topic.
+func RetryDelaysFromEnv() ([]time.Duration, error) {
+	v := os.Getenv("CONSUMER_RETRY_DELAYS")
+	switch v {
+	case "":
+		return DefaultRetryDelays, nil
+	case "none":
+		return nil, nil
+	}
+	var delays []time.Duration
+	for _, s := range strings.Split(v, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid CONSUMER_RETRY_DELAYS %q", v)
+		}
+		delays = append(delays, d)
+	}
+	return delays, nil
+}
+
+// defaultDrainTimeout is the DrainTimeout when Config leaves it unset.
+const defaultDrainTimeout = 10 * time.Second
+
+// Config configures a consumer group.
+type Config struct {
+	Brokers []string
+	Group   string
+	Topics  []string
+	// RetryDelays are the delays before each retry of a failed record, one retry
+	// topic each. A record still failing after the last is dead-lettered.
+	RetryDelays []time.Duration
+	// DrainTimeout bounds how long a record in progress at shutdown may take.
+	DrainTimeout time.Duration
+}
+
+// Consumer reads topics as a member of a consumer group, retrying and
+// dead-lettering records its handler fails on.
+type Consumer struct {
+	cfg      Config
+	sources  map[string]bool // topics records are first published to
+	client   *kgo.Client
+	producer events.Publisher
+}
+
+// New joins the consumer group for the configured topics, its retry topics and
+// its replay topic. Failed records are published through producer. A new group
+// starts at the earliest offset, so no record published before it first ran is
+// missed.
+func New(cfg Config, producer events.Publisher) (*Consumer, error) {
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = defaultDrainTimeout
+	}
+	topics := append(slices.Clone(cfg.Topics), ReplayTopic(cfg.Group))
+	for n := range len(cfg.RetryDelays) {
+		topics = append(topics, RetryTopic(cfg.Group, n+1))
+	}
+	sources := make(map[string]bool, len(cfg.Topics))
+	for _, t := range cfg.Topics {
+		sources[t] = true
+	}
+
+	group := cfg.Group
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ConsumerGroup(group),
+		kgo.ConsumeTopics(topics...),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		// Offsets are committed only for records the handler has finished with,
+		// including right before partitions move to another member. Rebalances
+		// wait for the records already polled.
+		kgo.AutoCommitMarks(),
+		kgo.BlockRebalanceOnPoll(),
+		kgo.OnPartitionsRevoked(func(ctx context.Context, cl *kgo.Client, _ map[string][]int32) {
+			if err := cl.CommitMarkedOffsets(ctx); err != nil {
+				slog.Warn("Failed to commit offsets on rebalance", "group", group, "error", err)
+			}
+		}),
+		kgo.OnPartitionsLost(func(_ context.Context, _ *kgo.Client, lost map[string][]int32) {
+			slog.Warn("Lost partitions; their uncommitted records will be redelivered", "group", group, "partitions", lost)
+		}),
+		kgo.AllowAutoTopicCreation(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka consumer: %w", err)
+	}
+	return &Consumer{cfg: cfg, sources: sources, client: client, producer: producer}, nil
+}
+
+// Run handles records until ctx is cancelled, then lets the record in progress
+// finish, commits the offsets handled so far and leaves the group. Records from
+// retry and replay topics reach handle with the topic they were first published
+// to. A handler error sends the record to the next retry topic, or once retries
+// are exhausted or the error is Permanent, to the dead-letter topic.
+func (c *Consumer) Run(ctx context.Context, handle kafka.Handler) {
+	defer c.close()
+
+	// Records are handled with a context that outlives ctx by DrainTimeout.
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() { time.AfterFunc(c.cfg.DrainTimeout, cancel) })
+	defer stop()
+
+	for {
+		fetches := c.client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			slog.Warn("Kafka fetch failed", "topic", topic, "partition", partition, "error", err)
+		})
+
+		fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+			for _, rec := range p.Records {
+				if ctx.Err() != nil || c.holdRetry(rec) {
+					return
+				}
+				if !c.handle(drainCtx, rec, handle) {
+					// Cut off by shutdown; the record is redelivered.
+					return
+				}
+				metrics.RecordConsumerLag(rec.Topic, c.cfg.Group, int(rec.Partition), p.HighWatermark, rec.Offset)
+				c.client.MarkCommitRecords(rec)
+			}
+		})
+		c.client.AllowRebalance()
+	}
+}
+
+// holdRetry pauses the partition of a retry that isn't due yet until it is,
+// rewinding the partition so the retry is fetched again then. It reports whether
+// it did.
+func (c *Consumer) holdRetry(rec *kgo.Record) bool {
+	at, err := time.Parse(time.RFC3339Nano, header(rec, HeaderRetryAt))
+	if c.sources[rec.Topic] || err != nil {
+		return false
+	}
+	wait := time.Until(at)
+	if wait <= 0 {
+		return false
+	}
+	partitions := map[string][]int32{rec.Topic: {rec.Partition}}
+	c.client.PauseFetchPartitions(partitions)
+	c.client.SetOffsets(map[string]map[int32]kgo.EpochOffset{
+		rec.Topic: {rec.Partition: {Epoch: rec.LeaderEpoch, Offset: rec.Offset}},
+	})
+	time.AfterFunc(wait, func() { c.client.ResumeFetchPartitions(partitions) })
+	return true
+}
+
+// handle runs handle on rec and routes a failure to the retry or dead-letter
+// topic. It reports whether rec is done with; it isn't when shutdown cut it off.
+func (c *Consumer) handle(ctx context.Context, rec *kgo.Record, handle kafka.Handler) bool {
+	headers := make([]telemetry.Header, len(rec.Headers))
+	for i, h := range rec.Headers {
+		headers[i] = telemetry.Header(h)
+	}
+	ctx, span := telemetry.StartConsumerSpan(ctx, rec.Topic, c.cfg.Group, int(rec.Partition), rec.Offset, headers)
+	defer span.End()
+
+	original := rec
+	if topic := header(rec, HeaderTopic); topic != "" && !c.sources[rec.Topic] {
+		r := *rec
+		r.Topic = topic
+		original = &r
+	}
+
+	err := handle(ctx, original)
+	metrics.RecordConsumed(rec.Topic, c.cfg.Group, err)
+	if err == nil {
+		return true
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	span.SetStatus(codes.Error, err.Error())
+	return c.fail(ctx, rec, original.Topic, err)
+}
+
+// fail publishes rec to the next retry topic or the dead-letter topic, with
+// headers describing the failure.
+func (c *Consumer) fail(ctx context.Context, rec *kgo.Record, topic string, err error) bool {
+	attempts, _ := strconv.Atoi(header(rec, HeaderAttempts))
+	attempts++
+	now := time.Now().UTC()
+
+	headers := failureHeaders(rec.Headers, !c.sources[rec.Topic])
+	if c.sources[rec.Topic] {
+		headers = append(headers,
+			telemetry.Header{Key: HeaderTopic, Value: []byte(rec.Topic)},
+			telemetry.Header{Key: HeaderPartition, Value: []byte(strconv.Itoa(int(rec.Partition)))},
+			telemetry.Header{Key: HeaderOffset, Value: []byte(strconv.FormatInt(rec.Offset, 10))},
+		)
+	}
+	headers = append(headers,
+		telemetry.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		telemetry.Header{Key: HeaderError, Value: []byte(err.Error())},
+		telemetry.Header{Key: HeaderFailedAt, Value: []byte(now.Format(time.RFC3339Nano))},
+	)
+	msg := kafka.Message{Key: rec.Key, Value: rec.Value}
+
+	if !IsPermanent(err) && attempts <= len(c.cfg.RetryDelays) {
+		delay := c.cfg.RetryDelays[attempts-1]
+		msg.Topic = RetryTopic(c.cfg.Group, attempts)
+		msg.Headers = append(headers, telemetry.Header{Key: HeaderRetryAt, Value: []byte(now.Add(delay).Format(time.RFC3339Nano))})
+		slog.WarnContext(ctx, "Retrying record later", "topic", topic, "partition", rec.Partition, "offset", rec.Offset,
+			"attempts", attempts, "retry_in", delay, "error", err)
+		return c.publish(ctx, msg)
+	}
+
+	msg.Topic = DLQTopic(c.cfg.Group)
+	msg.Headers = append(headers, telemetry.Header{Key: HeaderGroup, Value: []byte(c.cfg.Group)})
+	slog.ErrorContext(ctx, "Sending record to the dead-letter topic", "topic", topic, "partition", rec.Partition, "offset", rec.Offset,
+		"attempts", attempts, "error", err)
+	if !c.publish(ctx, msg) {
+		return false
+	}
+	metrics.RecordDeadLettered(topic, c.cfg.Group)
+	return true
+}
+
+// publish publishes msg, retrying until it succeeds or ctx is done, since the
+// failed record can't be committed before it is safely in its next topic.
+func (c *Consumer) publish(ctx context.Context, msg kafka.Message) bool {
+	for attempt := 1; ; attempt++ {
+		err := c.producer.Publish(ctx, msg)
+		if err == nil {
+			return true
+		}
+		slog.WarnContext(ctx, "Failed to publish failed record", "topic", msg.Topic, "error", err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(min(time.Duration(attempt)*time.Second, 30*time.Second)):
+		}
+	}
+}
+
+func (c *Consumer) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.client.CommitMarkedOffsets(ctx); err != nil {
+		slog.Warn("Failed to commit offsets on shutdown", "group", c.cfg.Group, "error", err)
+	}
+	c.client.Close()
+}
+
+// Permanent marks err as one retrying can't fix, so the record goes straight to
+// the dead-letter topic.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// header returns the value of rec's header with the given key.
+func header(rec *kgo.Record, key string) string {
+	for _, h := range rec.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// failureHeaders returns headers without the ones describing a failure, which
+// are set afresh, and unless keepPosition, without the original position.
+func failureHeaders(headers []kgo.RecordHeader, keepPosition bool) []telemetry.Header {
+	var kept []telemetry.Header
+	for _, h := range headers {
+		switch h.Key {
+		case HeaderAttempts, HeaderRetryAt, HeaderError, HeaderFailedAt, HeaderGroup:
+		case HeaderTopic, HeaderPartition, HeaderOffset:
+			if keepPosition {
+				kept = append(kept, telemetry.Header(h))
+			}
+		default:
+			kept = append(kept, telemetry.Header(h))
+		}
+	}
+	return kept
+}
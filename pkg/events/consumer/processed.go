@@ -0,0 +1,65 @@
+package consumer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/my-store/pkg/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// InitSchema creates the processed_events table if it doesn't exist. Each
+// service keeps its own, in its own database.
+func InitSchema(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS processed_events (
+		consumer_group TEXT NOT NULL,
+		event_id TEXT NOT NULL,
+		processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (consumer_group, event_id)
+	);`
+	_, err := db.Exec(query)
+	return err
+}
+
+// TxHandler processes an event, making its changes through tx.
+type TxHandler[T proto.Message] func(ctx context.Context, tx *sql.Tx, meta events.Metadata, event T) error
+
+// Idempotent returns an event handler that runs handle once per event ID for
+// the group. The event's row in processed_events is added in the transaction
+// handle makes its changes in, so they commit together or not at all, and a
+// redelivered event finds the row and is skipped. Deliveries of one event that
+// overlap, say after a rebalance, wait for each other on the row.
+func Idempotent[T proto.Message](db *sql.DB, group string, handle TxHandler[T]) events.Handler[T] {
+	return func(ctx context.Context, meta events.Metadata, event T) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		query := `
+			INSERT INTO processed_events (consumer_group, event_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`
+		res, err := tx.ExecContext(ctx, query, group, meta.EventID)
+		if err != nil {
+			return fmt.Errorf("failed to record event %s: %w", meta.EventID, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to record event %s: %w", meta.EventID, err)
+		} else if n == 0 {
+			slog.DebugContext(ctx, "Skipping event already processed", "group", group, "type", meta.Type, "event_id", meta.EventID)
+			return nil
+		}
+
+		if err := handle(ctx, tx, meta, event); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit event %s: %w", meta.EventID, err)
+		}
+		return nil
+	}
+}
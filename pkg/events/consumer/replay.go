@@ -0,0 +1,123 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/my-store/pkg/kafka"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Failure describes a dead letter: where its record was first published and why
+// the group gave up on it.
+type Failure struct {
+	Group     string
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Attempts  int
+	Error     string
+	FailedAt  time.Time
+}
+
+// FailureOf reads the headers the consumer added to a dead letter.
+func FailureOf(rec *kgo.Record) Failure {
+	f := Failure{
+		Group: header(rec, HeaderGroup),
+		Topic: header(rec, HeaderTopic),
+		Key:   rec.Key,
+		Error: header(rec, HeaderError),
+	}
+	partition, _ := strconv.ParseInt(header(rec, HeaderPartition), 10, 32)
+	f.Partition = int32(partition)
+	f.Offset, _ = strconv.ParseInt(header(rec, HeaderOffset), 10, 64)
+	f.Attempts, _ = strconv.Atoi(header(rec, HeaderAttempts))
+	f.FailedAt, _ = time.Parse(time.RFC3339Nano, header(rec, HeaderFailedAt))
+	return f
+}
+
+// ReplayOptions adjust ReplayDLQ.
+type ReplayOptions struct {
+	// DryRun reports the dead letters without replaying them or marking them
+	// replayed.
+	DryRun bool
+	// Idle is how long the dead-letter topic must have nothing new before the
+	// replay stops; by default 10s, enough to join the replay group.
+	Idle time.Duration
+}
+
+// ReplayDLQ sends the group's dead letters back through its replay topic, where
+// the group handles them like new records, retries included. The dead-letter
+// topic is read as the group "<group>.dlq-replay", so each dead letter is
+// replayed once, however often ReplayDLQ runs. report is called for each dead
+// letter; ReplayDLQ returns how many it replayed, or in a dry run, found.
+func ReplayDLQ(ctx context.Context, brokers []string, group string, opts ReplayOptions, report func(Failure)) (int, error) {
+	if opts.Idle <= 0 {
+		opts.Idle = 10 * time.Second
+	}
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumerGroup(group+".dlq-replay"),
+		kgo.ConsumeTopics(DLQTopic(group)),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.DisableAutoCommit(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create kafka consumer: %w", err)
+	}
+	defer client.Close()
+
+	var producer *kafka.Producer
+	if !opts.DryRun {
+		if producer, err = kafka.NewProducer(brokers); err != nil {
+			return 0, err
+		}
+		defer producer.Close()
+	}
+
+	read := 0
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, opts.Idle)
+		fetches := client.PollFetches(pollCtx)
+		idle := pollCtx.Err() != nil
+		cancel()
+		if err := ctx.Err(); err != nil {
+			return read, err
+		}
+		if idle {
+			return read, nil
+		}
+		var fetchErr error
+		fetches.EachError(func(topic string, partition int32, err error) {
+			fetchErr = errors.Join(fetchErr, fmt.Errorf("failed to read %s partition %d: %w", topic, partition, err))
+		})
+		if fetchErr != nil {
+			return read, fetchErr
+		}
+
+		records := fetches.Records()
+		msgs := make([]kafka.Message, 0, len(records))
+		for _, rec := range records {
+			report(FailureOf(rec))
+			msgs = append(msgs, kafka.Message{
+				Topic:   ReplayTopic(group),
+				Key:     rec.Key,
+				Value:   rec.Value,
+				Headers: failureHeaders(rec.Headers, true),
+			})
+		}
+		if !opts.DryRun {
+			if err := producer.Publish(ctx, msgs...); err != nil {
+				return read, err
+			}
+			if err := client.CommitRecords(ctx, records...); err != nil {
+				return read, fmt.Errorf("failed to commit replayed dead letters: %w", err)
+			}
+		}
+		read += len(records)
+	}
+}
@@ -0,0 +1,120 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// OffsetEnd as ResetTo.Offset moves a group to the end of each partition,
+// skipping everything published so far.
+const OffsetEnd = -1
+
+// ResetTo says where ResetOffsets moves a group: to the first record at or after
+// Time when it is set, otherwise to Offset in every partition. Offset 0 is the
+// start of each partition; offsets beyond a partition's range are moved into it.
+type ResetTo struct {
+	Time   time.Time
+	Offset int64
+}
+
+// OffsetChange is a partition's committed offset before and after a reset. From
+// is -1 when the group had committed none.
+type OffsetChange struct {
+	Topic     string
+	Partition int32
+	From      int64
+	To        int64
+}
+
+// ResetOffsets commits new offsets for the group on the topics, so its consumers
+// reprocess (or skip) records from there when they next start. The group must
+// have no members: Kafka only accepts offsets for a group from its members, so
+// the service's consumers are stopped first, or scaled to zero. A dry run only
+// returns the changes.
+func ResetOffsets(ctx context.Context, brokers []string, group string, topics []string, to ResetTo, dryRun bool) ([]OffsetChange, error) {
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	defer client.Close()
+	adm := kadm.NewClient(client)
+
+	described, err := adm.DescribeGroups(ctx, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe group %s: %w", group, err)
+	}
+	// A group that has never committed doesn't exist yet, which is fine.
+	if g := described[group]; g.Err != nil && !errors.Is(g.Err, kerr.GroupIDNotFound) {
+		return nil, fmt.Errorf("failed to describe group %s: %w", group, g.Err)
+	} else if len(g.Members) > 0 {
+		return nil, fmt.Errorf("group %s has %d active members; stop its consumers first", group, len(g.Members))
+	}
+
+	starts, err := adm.ListStartOffsets(ctx, topics...)
+	if err == nil {
+		err = starts.Error()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list start offsets: %w", err)
+	}
+	ends, err := adm.ListEndOffsets(ctx, topics...)
+	if err == nil {
+		err = ends.Error()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list end offsets: %w", err)
+	}
+	var atTime kadm.ListedOffsets
+	if !to.Time.IsZero() {
+		atTime, err = adm.ListOffsetsAfterMilli(ctx, to.Time.UnixMilli(), topics...)
+		if err == nil {
+			err = atTime.Error()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list offsets at %s: %w", to.Time.Format(time.RFC3339), err)
+		}
+	}
+	committed, err := adm.FetchOffsetsForTopics(ctx, group, topics...)
+	if err != nil && !errors.Is(err, kerr.GroupIDNotFound) {
+		return nil, fmt.Errorf("failed to fetch committed offsets of %s: %w", group, err)
+	}
+
+	var changes []OffsetChange
+	var offsets kadm.Offsets
+	for _, start := range starts.Offsets().Sorted() {
+		end, _ := ends.Lookup(start.Topic, start.Partition)
+		target := to.Offset
+		switch {
+		case !to.Time.IsZero():
+			at, _ := atTime.Lookup(start.Topic, start.Partition)
+			target = at.Offset
+		case target == OffsetEnd:
+			target = end.Offset
+		}
+		target = min(max(target, start.At), end.Offset)
+
+		from := int64(-1)
+		if c, ok := committed.Lookup(start.Topic, start.Partition); ok {
+			from = c.At
+		}
+		changes = append(changes, OffsetChange{Topic: start.Topic, Partition: start.Partition, From: from, To: target})
+		offsets.AddOffset(start.Topic, start.Partition, target, -1)
+	}
+	if len(changes) == 0 {
+		return nil, errors.New("no partitions to reset")
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	if err := adm.CommitAllOffsets(ctx, group, offsets); err != nil {
+		return nil, fmt.Errorf("failed to commit offsets of %s: %w", group, err)
+	}
+	return changes, nil
+}
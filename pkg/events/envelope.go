@@ -0,0 +1,123 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	eventspb "github.com/my-store/pkg/api/events"
+	"github.com/my-store/pkg/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrMalformed is returned for records that aren't valid envelopes.
+var ErrMalformed = errors.New("malformed event envelope")
+
+// Metadata describes the envelope an event arrived in.
+type Metadata struct {
+	EventID      string
+	Type         string
+	Version      uint32
+	OccurredAt   time.Time
+	Producer     string
+	TraceContext map[string]string
+}
+
+// Wrap puts msg in an envelope as the event type and version it is registered
+// with, giving it a new event ID, the current time and the trace context of ctx.
+// producer names the publishing service.
+func (r *Registry) Wrap(ctx context.Context, producer string, msg proto.Message) (*eventspb.Envelope, error) {
+	eventType, version, err := r.TypeOf(msg)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := anypb.New(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", eventType, err)
+	}
+	traceContext := make(map[string]string)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(traceContext))
+
+	return &eventspb.Envelope{
+		EventId:      newEventID(),
+		Type:         eventType,
+		Version:      version,
+		OccurredAt:   timestamppb.Now(),
+		Producer:     producer,
+		TraceContext: traceContext,
+		Payload:      payload,
+	}, nil
+}
+
+// Message wraps msg like Wrap and returns it as a Kafka message for topic,
+// keyed by key so events of one entity stay in order.
+func (r *Registry) Message(ctx context.Context, producer, topic string, key []byte, msg proto.Message) (kafka.Message, error) {
+	env, err := r.Wrap(ctx, producer, msg)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	value, err := proto.Marshal(env)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal %s envelope: %w", env.Type, err)
+	}
+	return kafka.Message{Topic: topic, Key: key, Value: value}, nil
+}
+
+// Unmarshal parses an envelope from a record value.
+func Unmarshal(data []byte) (*eventspb.Envelope, error) {
+	env := &eventspb.Envelope{}
+	if err := proto.Unmarshal(data, env); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	switch {
+	case env.EventId == "":
+		return nil, fmt.Errorf("%w: no event ID", ErrMalformed)
+	case env.Type == "" || env.Version == 0:
+		return nil, fmt.Errorf("%w: no event type and version", ErrMalformed)
+	case env.Payload == nil:
+		return nil, fmt.Errorf("%w: no payload", ErrMalformed)
+	}
+	return env, nil
+}
+
+// Decode returns the payload of env as the message registered for its type and
+// version, or the nearest registered version when a newer or older producer sent
+// it.
+func (r *Registry) Decode(env *eventspb.Envelope) (proto.Message, error) {
+	mt, err := r.lookup(env.Type, env.Version)
+	if err != nil {
+		return nil, err
+	}
+	msg := mt.New().Interface()
+	if err := proto.Unmarshal(env.GetPayload().GetValue(), msg); err != nil {
+		return nil, fmt.Errorf("%w: %s payload: %w", ErrMalformed, env.Type, err)
+	}
+	return msg, nil
+}
+
+// MetadataOf returns the metadata of env.
+func MetadataOf(env *eventspb.Envelope) Metadata {
+	return Metadata{
+		EventID:      env.EventId,
+		Type:         env.Type,
+		Version:      env.Version,
+		OccurredAt:   env.OccurredAt.AsTime(),
+		Producer:     env.Producer,
+		TraceContext: env.TraceContext,
+	}
+}
+
+// newEventID returns a random (version 4) UUID.
+func newEventID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
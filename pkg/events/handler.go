@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/my-store/pkg/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+)
+
+// Handler processes an event of type T. A returned error makes the consumer
+// retry it.
+type Handler[T proto.Message] func(ctx context.Context, meta Metadata, event T) error
+
+// Handle returns a Kafka handler that decodes each record's envelope and passes
+// events of the type T carries to handle. Every version of that type is decoded
+// into T, which compatibility makes safe. Events of other types are skipped, so
+// handlers for several types can share a topic; records that aren't envelopes
+// are logged and dropped, since retrying can't fix them.
+//
+// When the envelope's trace isn't the one the record's headers continue, as for
+// replayed events, the consumer span links to it.
+//
+// Handle panics if no event type in reg is carried by T.
+func Handle[T proto.Message](reg *Registry, handle Handler[T]) kafka.Handler {
+	var zero T
+	eventType, _, err := reg.TypeOf(zero)
+	if err != nil {
+		panic(err)
+	}
+	mt := zero.ProtoReflect().Type()
+
+	return func(ctx context.Context, rec *kafka.Record) error {
+		env, err := Unmarshal(rec.Value)
+		if err != nil {
+			slog.WarnContext(ctx, "Dropping record that isn't an event",
+				"topic", rec.Topic, "partition", rec.Partition, "offset", rec.Offset, "error", err)
+			return nil
+		}
+		if env.Type != eventType {
+			return nil
+		}
+
+		event := mt.New().Interface().(T)
+		if err := proto.Unmarshal(env.Payload.GetValue(), event); err != nil {
+			slog.WarnContext(ctx, "Dropping event with a malformed payload",
+				"type", env.Type, "version", env.Version, "event_id", env.EventId, "error", err)
+			return nil
+		}
+
+		linkTrace(ctx, env.TraceContext)
+		return handle(ctx, MetadataOf(env), event)
+	}
+}
+
+// linkTrace links the span in ctx to the trace recorded in traceContext when
+// it's a different one.
+func linkTrace(ctx context.Context, traceContext map[string]string) {
+	if len(traceContext) == 0 {
+		return
+	}
+	origin := trace.SpanContextFromContext(
+		otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(traceContext)))
+	span := trace.SpanFromContext(ctx)
+	if origin.IsValid() && origin.TraceID() != span.SpanContext().TraceID() {
+		span.AddLink(trace.Link{SpanContext: origin})
+	}
+}
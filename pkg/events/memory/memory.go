@@ -0,0 +1,368 @@
+// Package memory is an in-process message broker for tests of event-driven
+// flows that shouldn't need a running Kafka. It keeps Kafka's guarantees the
+// services rely on: records are partitioned by key, as Kafka's default
+// partitioner does, so one order's events stay in order; each consumer group
+// gets every record, split across its members by partition; offsets are
+// committed once the handler has finished with a record; and when a rebalance
+// moves a partition, its new owner starts from the committed offset, so a record
+// in progress is delivered again.
+//
+// A Broker is a Publisher, and its consumers are Consumers, so services wire
+// them in place of *kafka.Producer and the Kafka consumers:
+//
+//	broker := memory.NewBroker(3)
+//	relay := outbox.NewRelay(db, broker)
+//	go broker.Consumer("order-service", topic).Run(ctx, handle)
+//	...
+//	err := broker.WaitIdle(ctx)
+package memory
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/my-store/pkg/kafka"
+	"github.com/my-store/pkg/telemetry"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// handlerAttempts bounds how often a failing record is retried before it is
+// logged and skipped, as by *kafka.Consumer. Retries are immediate.
+const handlerAttempts = 3
+
+// Broker holds topics and consumer groups in memory.
+type Broker struct {
+	partitions  int
+	partitioner kgo.Partitioner
+
+	mu      sync.Mutex
+	topics  map[string][][]*kafka.Record
+	groups  map[string]*group
+	changed chan struct{} // closed, and replaced, whenever anything changes
+}
+
+// NewBroker creates a broker whose topics, created on first use, have the given
+// number of partitions.
+func NewBroker(partitions int) *Broker {
+	return &Broker{
+		partitions:  max(partitions, 1),
+		partitioner: kgo.StickyKeyPartitioner(nil),
+		topics:      make(map[string][][]*kafka.Record),
+		groups:      make(map[string]*group),
+		changed:     make(chan struct{}),
+	}
+}
+
+// CreateTopic creates a topic with its own number of partitions. It does nothing
+// if the topic exists.
+func (b *Broker) CreateTopic(topic string, partitions int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.createTopic(topic, max(partitions, 1))
+}
+
+// Publish appends the messages to their topics' partitions. Each message gets a
+// producer span, a child of the span in ctx, carried in its headers.
+func (b *Broker) Publish(ctx context.Context, msgs ...kafka.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	records := make([]*kafka.Record, len(msgs))
+	for i, m := range msgs {
+		headers := append([]telemetry.Header(nil), m.Headers...)
+		_, span := telemetry.StartProducerSpan(ctx, m.Topic, &headers)
+		span.End()
+
+		rec := &kafka.Record{Topic: m.Topic, Key: m.Key, Value: m.Value}
+		for _, h := range headers {
+			rec.Headers = append(rec.Headers, kgo.RecordHeader(h))
+		}
+		records[i] = rec
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for _, rec := range records {
+		partitions := b.createTopic(rec.Topic, b.partitions)
+		p := b.partitioner.ForTopic(rec.Topic).Partition(rec, len(partitions))
+		rec.Partition = int32(p)
+		rec.Offset = int64(len(partitions[p]))
+		rec.Timestamp = now
+		partitions[p] = append(partitions[p], rec)
+	}
+	b.notify()
+	return nil
+}
+
+// Records returns the records published to a topic so far, by partition.
+func (b *Broker) Records(topic string) [][]*kafka.Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	partitions := make([][]*kafka.Record, len(b.topics[topic]))
+	for p, recs := range b.topics[topic] {
+		partitions[p] = slices.Clone(recs)
+	}
+	return partitions
+}
+
+// Committed returns the group's committed offset in a partition: the offset of
+// the next record it will handle there.
+func (b *Broker) Committed(group, topic string, partition int32) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if g := b.groups[group]; g != nil {
+		return g.committed[topicPartition{topic, partition}]
+	}
+	return 0
+}
+
+// WaitIdle waits until every consumer group with members has handled every
+// record published so far to its topics, including those its handlers
+// published meanwhile, or ctx is done. Records published later, as by an outbox
+// relay, aren't waited for.
+func (b *Broker) WaitIdle(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		idle, changed := b.idle(), b.changed
+		b.mu.Unlock()
+		if idle {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (b *Broker) idle() bool {
+	for _, g := range b.groups {
+		for tp, owner := range g.owners {
+			if owner != nil && g.committed[tp] < int64(len(b.topics[tp.topic][tp.partition])) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// createTopic creates a topic unless it exists and returns its partitions. The
+// groups subscribed to a new topic rebalance to take it on.
+func (b *Broker) createTopic(topic string, partitions int) [][]*kafka.Record {
+	if existing, ok := b.topics[topic]; ok {
+		return existing
+	}
+	b.topics[topic] = make([][]*kafka.Record, partitions)
+	for _, g := range b.groups {
+		if g.subscribed(topic) {
+			b.rebalance(g)
+		}
+	}
+	b.notify()
+	return b.topics[topic]
+}
+
+// notify wakes everyone waiting for a change.
+func (b *Broker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// group is a consumer group: its members, which of them owns each partition of
+// its topics and its committed offsets.
+type group struct {
+	name      string
+	members   []*member // in the order they joined
+	owners    map[topicPartition]*member
+	committed map[topicPartition]int64
+}
+
+// member is a running consumer. It handles the records of the partitions it
+// owns, each from its own position.
+type member struct {
+	topics []string
+	claims map[topicPartition]*claim
+}
+
+// claim is a member's ownership of a partition. A rebalance that moves the
+// partition replaces it, so the old owner's commits are fenced off.
+type claim struct {
+	next int64
+}
+
+func (g *group) subscribed(topic string) bool {
+	for _, m := range g.members {
+		if slices.Contains(m.topics, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebalance assigns the partitions of the group's topics to its members, each
+// topic's partitions round-robin across the members subscribed to it. Members
+// keep the partitions they still own; the owner of a moved partition resumes it
+// from the committed offset.
+func (b *Broker) rebalance(g *group) {
+	owners := make(map[topicPartition]*member)
+	for _, topic := range slices.Sorted(maps.Keys(b.topics)) {
+		var subscribers []*member
+		for _, m := range g.members {
+			if slices.Contains(m.topics, topic) {
+				subscribers = append(subscribers, m)
+			}
+		}
+		if len(subscribers) == 0 {
+			continue
+		}
+		for p := range b.topics[topic] {
+			owners[topicPartition{topic, int32(p)}] = subscribers[p%len(subscribers)]
+		}
+	}
+
+	for tp, old := range g.owners {
+		if old != nil && owners[tp] != old {
+			delete(old.claims, tp)
+		}
+	}
+	for tp, owner := range owners {
+		if g.owners[tp] != owner {
+			owner.claims[tp] = &claim{next: g.committed[tp]}
+		}
+	}
+	g.owners = owners
+	slog.Debug("Consumer group rebalanced", "group", g.name, "members", len(g.members), "partitions", len(owners))
+}
+
+// Consumer is a member of a consumer group.
+type Consumer struct {
+	broker *Broker
+	group  string
+	topics []string
+}
+
+// Consumer returns a consumer joining the group for the topics when it runs. A
+// new group starts at the earliest offset, as with *kafka.Consumer.
+func (b *Broker) Consumer(group string, topics ...string) *Consumer {
+	return &Consumer{broker: b, group: group, topics: topics}
+}
+
+// Run joins the group and handles records until ctx is cancelled, then leaves
+// the group, which rebalances its partitions to the other members. A handler
+// error retries the record; after the last attempt it is logged and skipped.
+func (c *Consumer) Run(ctx context.Context, handle kafka.Handler) {
+	b := c.broker
+	m := &member{topics: c.topics, claims: make(map[topicPartition]*claim)}
+
+	b.mu.Lock()
+	g := b.groups[c.group]
+	if g == nil {
+		g = &group{name: c.group, owners: make(map[topicPartition]*member), committed: make(map[topicPartition]int64)}
+		b.groups[c.group] = g
+	}
+	for _, topic := range c.topics {
+		b.createTopic(topic, b.partitions)
+	}
+	g.members = append(g.members, m)
+	b.rebalance(g)
+	b.notify()
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		g.members = slices.DeleteFunc(g.members, func(other *member) bool { return other == m })
+		b.rebalance(g)
+		b.notify()
+		b.mu.Unlock()
+	}()
+
+	for {
+		b.mu.Lock()
+		rec, tp, cl := b.next(m)
+		changed := b.changed
+		b.mu.Unlock()
+
+		if rec == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			}
+			continue
+		}
+		c.handle(ctx, rec, handle)
+		if ctx.Err() != nil {
+			return
+		}
+
+		b.mu.Lock()
+		// A partition moved away during the handler is redelivered by its new
+		// owner from the committed offset.
+		if m.claims[tp] == cl {
+			cl.next = rec.Offset + 1
+			g.committed[tp] = cl.next
+			b.notify()
+		}
+		b.mu.Unlock()
+	}
+}
+
+// next returns a copy of the next record for m to handle, from its partition
+// with the oldest one waiting, and m's claim on that partition. The record is nil
+// if m has handled them all.
+func (b *Broker) next(m *member) (*kafka.Record, topicPartition, *claim) {
+	var oldest *kafka.Record
+	for tp, cl := range m.claims {
+		recs := b.topics[tp.topic][tp.partition]
+		if cl.next >= int64(len(recs)) {
+			continue
+		}
+		rec := recs[cl.next]
+		if oldest == nil || cmp.Or(rec.Timestamp.Compare(oldest.Timestamp), cmp.Compare(rec.Topic, oldest.Topic),
+			cmp.Compare(rec.Partition, oldest.Partition)) < 0 {
+			oldest = rec
+		}
+	}
+	if oldest == nil {
+		return nil, topicPartition{}, nil
+	}
+	// Handlers may change the record, as the retrying consumer does.
+	rec := *oldest
+	rec.Headers = slices.Clone(oldest.Headers)
+	tp := topicPartition{oldest.Topic, oldest.Partition}
+	return &rec, tp, m.claims[tp]
+}
+
+func (c *Consumer) handle(ctx context.Context, rec *kafka.Record, handle kafka.Handler) {
+	headers := make([]telemetry.Header, len(rec.Headers))
+	for i, h := range rec.Headers {
+		headers[i] = telemetry.Header(h)
+	}
+	ctx, span := telemetry.StartConsumerSpan(ctx, rec.Topic, c.group, int(rec.Partition), rec.Offset, headers)
+	defer span.End()
+
+	var err error
+	for attempt := 1; attempt <= handlerAttempts; attempt++ {
+		if err = handle(ctx, rec); err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil && ctx.Err() == nil {
+		span.SetStatus(codes.Error, err.Error())
+		slog.ErrorContext(ctx, "Skipping record after failed attempts",
+			"topic", rec.Topic, "partition", rec.Partition, "offset", rec.Offset, "attempts", handlerAttempts, "error", err)
+	}
+}
@@ -0,0 +1,251 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/my-store/pkg/kafka"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// run runs a consumer until the test ends or stop is called. It returns once
+// the consumer has joined its group.
+func run(t *testing.T, c *Consumer, handle kafka.Handler) (stop func()) {
+	t.Helper()
+	members := func() int {
+		c.broker.mu.Lock()
+		defer c.broker.mu.Unlock()
+		if g := c.broker.groups[c.group]; g != nil {
+			return len(g.members)
+		}
+		return 0
+	}
+	before := members()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx, handle)
+	}()
+	stop = sync.OnceFunc(func() {
+		cancel()
+		<-done
+	})
+	t.Cleanup(stop)
+
+	for deadline := time.Now().Add(5 * time.Second); members() == before; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("consumer didn't join its group")
+		}
+	}
+	return stop
+}
+
+func waitIdle(t *testing.T, b *Broker) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.WaitIdle(ctx); err != nil {
+		t.Fatalf("broker didn't go idle: %v", err)
+	}
+}
+
+// recv waits for a value from ch.
+func recv[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		var zero T
+		return zero
+	}
+}
+
+// keyFor returns a key Kafka's default partitioner puts in the partition.
+func keyFor(topic string, partition, partitions int) []byte {
+	p := kgo.StickyKeyPartitioner(nil).ForTopic(topic)
+	for i := 0; ; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		if p.Partition(&kgo.Record{Key: key}, partitions) == partition {
+			return key
+		}
+	}
+}
+
+func publish(t *testing.T, b *Broker, msgs ...kafka.Message) {
+	t.Helper()
+	if err := b.Publish(context.Background(), msgs...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPublishPartitionsByKey(t *testing.T) {
+	b := NewBroker(4)
+	var msgs []kafka.Message
+	for i := range 40 {
+		msgs = append(msgs, kafka.Message{Topic: "orders", Key: []byte(fmt.Sprintf("order-%d", i%5)), Value: []byte{byte(i)}})
+	}
+	publish(t, b, msgs...)
+
+	partitioner := kgo.StickyKeyPartitioner(nil).ForTopic("orders")
+	seen := make(map[string][]byte) // values by key, in partition order
+	for p, recs := range b.Records("orders") {
+		for i, rec := range recs {
+			if want := partitioner.Partition(&kgo.Record{Key: rec.Key}, 4); p != want {
+				t.Errorf("key %s in partition %d, Kafka puts it in %d", rec.Key, p, want)
+			}
+			if rec.Partition != int32(p) || rec.Offset != int64(i) {
+				t.Errorf("record at partition %d offset %d says %d/%d", p, i, rec.Partition, rec.Offset)
+			}
+			seen[string(rec.Key)] = append(seen[string(rec.Key)], rec.Value[0])
+		}
+	}
+	for key, values := range seen {
+		if !slices.IsSorted(values) || len(values) != 8 {
+			t.Errorf("key %s: got values %v, want 8 in publishing order", key, values)
+		}
+	}
+}
+
+func TestEveryGroupGetsEveryRecord(t *testing.T) {
+	b := NewBroker(4)
+	var mu sync.Mutex
+	handled := make(map[string][]string) // record keys by consumer
+	handler := func(name string) kafka.Handler {
+		return func(_ context.Context, rec *kafka.Record) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled[name] = append(handled[name], string(rec.Key))
+			return nil
+		}
+	}
+	run(t, b.Consumer("billing", "orders"), handler("billing-1"))
+	run(t, b.Consumer("billing", "orders"), handler("billing-2"))
+	run(t, b.Consumer("audit", "orders"), handler("audit"))
+
+	var msgs []kafka.Message
+	var keys []string
+	for i := range 20 {
+		key := fmt.Sprintf("order-%d", i)
+		keys = append(keys, key)
+		msgs = append(msgs, kafka.Message{Topic: "orders", Key: []byte(key)})
+	}
+	publish(t, b, msgs...)
+	waitIdle(t, b)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := slices.Sorted(slices.Values(handled["audit"])); !slices.Equal(got, slices.Sorted(slices.Values(keys))) {
+		t.Errorf("audit handled %v, want every record once", got)
+	}
+	billing := append(slices.Clone(handled["billing-1"]), handled["billing-2"]...)
+	if got := slices.Sorted(slices.Values(billing)); !slices.Equal(got, slices.Sorted(slices.Values(keys))) {
+		t.Errorf("billing handled %v, want every record once", got)
+	}
+	if len(handled["billing-1"]) == 0 || len(handled["billing-2"]) == 0 {
+		t.Errorf("billing members handled %d and %d records, want the partitions split between them",
+			len(handled["billing-1"]), len(handled["billing-2"]))
+	}
+}
+
+func TestCommitsAfterHandling(t *testing.T) {
+	b := NewBroker(1)
+	started, release := make(chan struct{}), make(chan struct{})
+	run(t, b.Consumer("billing", "orders"), func(context.Context, *kafka.Record) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	publish(t, b, kafka.Message{Topic: "orders", Key: []byte("order-1")})
+	recv(t, started)
+	if got := b.Committed("billing", "orders", 0); got != 0 {
+		t.Fatalf("committed offset %d while the record is being handled, want 0", got)
+	}
+	close(release)
+	waitIdle(t, b)
+	if got := b.Committed("billing", "orders", 0); got != 1 {
+		t.Fatalf("committed offset %d after handling, want 1", got)
+	}
+}
+
+func TestRebalanceRedeliversRecordInProgress(t *testing.T) {
+	b := NewBroker(2)
+	key := keyFor("orders", 1, 2)
+
+	type delivery struct {
+		consumer string
+		offset   int64
+	}
+	deliveries := make(chan delivery, 10)
+	release := make(chan struct{})
+	run(t, b.Consumer("billing", "orders"), func(_ context.Context, rec *kafka.Record) error {
+		deliveries <- delivery{"first", rec.Offset}
+		<-release
+		return nil
+	})
+	publish(t, b, kafka.Message{Topic: "orders", Key: key})
+	if d := recv(t, deliveries); d != (delivery{"first", 0}) {
+		t.Fatalf("got delivery %+v, want the first consumer to get offset 0", d)
+	}
+
+	// The second member takes partition 1 over while the first is handling it.
+	run(t, b.Consumer("billing", "orders"), func(_ context.Context, rec *kafka.Record) error {
+		deliveries <- delivery{"second", rec.Offset}
+		return nil
+	})
+	if d := recv(t, deliveries); d != (delivery{"second", 0}) {
+		t.Fatalf("got delivery %+v, want the record redelivered to the second consumer", d)
+	}
+	close(release)
+	waitIdle(t, b)
+	if got := b.Committed("billing", "orders", 1); got != 1 {
+		t.Fatalf("committed offset %d, want 1", got)
+	}
+	select {
+	case d := <-deliveries:
+		t.Fatalf("unexpected delivery %+v", d)
+	default:
+	}
+}
+
+func TestLeavingMemberHandsOverFromCommittedOffset(t *testing.T) {
+	b := NewBroker(1)
+	first := make(chan int64, 10)
+	stopFirst := run(t, b.Consumer("billing", "orders"), func(ctx context.Context, rec *kafka.Record) error {
+		first <- rec.Offset
+		if rec.Offset == 1 {
+			<-ctx.Done() // still handling it when the consumer stops
+			return ctx.Err()
+		}
+		return nil
+	})
+	second := make(chan int64, 10)
+	run(t, b.Consumer("billing", "orders"), func(_ context.Context, rec *kafka.Record) error {
+		second <- rec.Offset
+		return nil
+	})
+
+	publish(t, b, kafka.Message{Topic: "orders", Key: []byte("a")}, kafka.Message{Topic: "orders", Key: []byte("b")})
+	if got := recv(t, first); got != 0 {
+		t.Fatalf("first consumer got offset %d, want 0", got)
+	}
+	if got := recv(t, first); got != 1 {
+		t.Fatalf("first consumer got offset %d, want 1", got)
+	}
+	stopFirst()
+	if got := recv(t, second); got != 1 {
+		t.Fatalf("second consumer got offset %d, want 1 again", got)
+	}
+	waitIdle(t, b)
+	if got := b.Committed("billing", "orders", 0); got != 2 {
+		t.Fatalf("committed offset %d, want 2", got)
+	}
+}
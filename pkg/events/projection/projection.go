@@ -0,0 +1,216 @@
+// Package projection rebuilds read models from their event topics. A projection
+// is a set of tables a service keeps up to date from events. After a bug in its
+// handler is fixed, Request asks for a rebuild, and the service's Rebuilder
+// picks it up: it truncates the tables, replays the topics from the beginning up
+// to where they ended at that moment, and records its progress, while the live
+// consumer group carries on with new events.
+//
+// The projection is offline from the moment a rebuild starts until one is done:
+// its tables are missing events in between. Services read a projection in a
+// transaction that starts with ReadLock, which fails with ErrOffline then, and
+// otherwise keeps rebuilds from emptying the tables until the transaction ends.
+// A rebuild that fails leaves the projection offline until another succeeds.
+//
+// The rebuild and live consumption can meet the same events, so both handle them
+// through consumer.Idempotent keyed by the projection's name. The rebuild clears
+// the projection's processed events along with its tables; each event is then
+// applied once, by whichever gets to it first. Projections must tolerate events
+// out of order, as they already do for retries.
+package projection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/my-store/pkg/events/consumer"
+	"github.com/my-store/pkg/kafka"
+)
+
+// Rebuild statuses. A rebuild is REQUESTED, RUNNING and then DONE or FAILED.
+const (
+	StatusRequested = "REQUESTED"
+	StatusRunning   = "RUNNING"
+	StatusDone      = "DONE"
+	StatusFailed    = "FAILED"
+)
+
+var (
+	// ErrRebuildNotFound is returned when no rebuild has the requested ID.
+	ErrRebuildNotFound = errors.New("rebuild not found")
+	// ErrUnknownProjection is matched by the error Request returns for a name no
+	// rebuilder serves.
+	ErrUnknownProjection = errors.New("unknown projection")
+	// ErrOffline is returned by ReadLock while the projection is being rebuilt,
+	// or after its last rebuild failed.
+	ErrOffline = errors.New("projection is offline for a rebuild")
+)
+
+// lockKey is the advisory lock a rebuild holds exclusively, and readers share,
+// for the projection named by the parameter $1.
+const lockKey = `hashtext('projection'), hashtext($1)`
+
+// Projection is a read model built from events.
+type Projection struct {
+	// Name identifies the projection in rebuild requests, and keys its events
+	// in processed_events.
+	Name   string
+	Topics []string
+	// Tables hold the read model; they are truncated before a rebuild.
+	Tables []string
+	// Handle applies an event to the tables, normally the live consumer's
+	// handler built with consumer.Idempotent(db, Name, ...).
+	Handle kafka.Handler
+}
+
+// Rebuild is a rebuild of a projection. Replayed counts the offsets replayed of
+// the Total the topics held when it started.
+type Rebuild struct {
+	ID          int64
+	Projection  string
+	Status      string
+	Replayed    int64
+	Total       int64
+	Skipped     int64 // events the handler failed on permanently
+	Error       string
+	RequestedAt time.Time
+	StartedAt   time.Time
+	FinishedAt  time.Time
+}
+
+// InitSchema creates the projections and projection_rebuilds tables, and
+// processed_events which rebuilds clear, if they don't exist.
+func InitSchema(db *sql.DB) error {
+	query := `
+	-- The projections the service's rebuilders serve, which can be rebuilt.
+	CREATE TABLE IF NOT EXISTS projections (
+		name TEXT PRIMARY KEY,
+		topics TEXT[] NOT NULL,
+		tables TEXT[] NOT NULL,
+		registered_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS projection_rebuilds (
+		id BIGSERIAL PRIMARY KEY,
+		projection TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'REQUESTED',
+		replayed BIGINT NOT NULL DEFAULT 0,
+		total BIGINT NOT NULL DEFAULT 0,
+		skipped BIGINT NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		started_at TIMESTAMPTZ,
+		heartbeat_at TIMESTAMPTZ,
+		finished_at TIMESTAMPTZ
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS projection_rebuilds_pending ON projection_rebuilds (projection)
+		WHERE status IN ('REQUESTED', 'RUNNING');`
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+	return consumer.InitSchema(db)
+}
+
+// Names returns the projections the service's rebuilders serve, in order.
+func Names(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM projections ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projections: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Request asks for a rebuild of the named projection and returns its ID. While a
+// rebuild of the projection is requested or running, that one is returned. A
+// projection no rebuilder has registered fails with ErrUnknownProjection, since
+// nothing would ever run its rebuild.
+func Request(ctx context.Context, db *sql.DB, projection string) (int64, error) {
+	names, err := Names(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(names, projection) {
+		return 0, fmt.Errorf("%w %q; known projections: %s", ErrUnknownProjection, projection, strings.Join(names, ", "))
+	}
+
+	var id int64
+	query := `
+		INSERT INTO projection_rebuilds (projection) VALUES ($1)
+		ON CONFLICT (projection) WHERE status IN ('REQUESTED', 'RUNNING') DO NOTHING
+		RETURNING id`
+	err = db.QueryRowContext(ctx, query, projection).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		query = `SELECT id FROM projection_rebuilds WHERE projection = $1 AND status IN ('REQUESTED', 'RUNNING')`
+		err = db.QueryRowContext(ctx, query, projection).Scan(&id)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to request rebuild of %s: %w", projection, err)
+	}
+	return id, nil
+}
+
+// ReadLock keeps rebuilds of the projection from starting until tx ends, so what
+// tx reads of its tables is whole. It fails with ErrOffline if a rebuild is
+// running or the last one failed. It doesn't wait for a running rebuild.
+func ReadLock(ctx context.Context, tx *sql.Tx, projection string) error {
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock_shared(`+lockKey+`)`, projection).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to lock projection %s: %w", projection, err)
+	}
+	if !locked {
+		return ErrOffline
+	}
+
+	// A rebuild that crashed no longer holds the lock, but left the tables
+	// half built.
+	var status string
+	query := `
+		SELECT status FROM projection_rebuilds
+		WHERE projection = $1 AND started_at IS NOT NULL
+		ORDER BY started_at DESC, id DESC
+		LIMIT 1`
+	err := tx.QueryRowContext(ctx, query, projection).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check rebuilds of %s: %w", projection, err)
+	}
+	if status == StatusRunning || status == StatusFailed {
+		return ErrOffline
+	}
+	return nil
+}
+
+// Get returns a rebuild.
+func Get(ctx context.Context, db *sql.DB, id int64) (*Rebuild, error) {
+	var r Rebuild
+	var startedAt, finishedAt sql.NullTime
+	query := `
+		SELECT id, projection, status, replayed, total, skipped, error, requested_at, started_at, finished_at
+		FROM projection_rebuilds WHERE id = $1`
+	err := db.QueryRowContext(ctx, query, id).Scan(&r.ID, &r.Projection, &r.Status, &r.Replayed, &r.Total,
+		&r.Skipped, &r.Error, &r.RequestedAt, &startedAt, &finishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRebuildNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rebuild %d: %w", id, err)
+	}
+	r.StartedAt, r.FinishedAt = startedAt.Time, finishedAt.Time
+	return &r, nil
+}
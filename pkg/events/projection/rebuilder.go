@@ -0,0 +1,394 @@
+package projection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/my-store/pkg/events/consumer"
+	"github.com/my-store/pkg/telemetry"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	// pollInterval is how often the rebuilder looks for requested rebuilds.
+	pollInterval = 5 * time.Second
+	// pollTimeout bounds how long a rebuild waits for events before recording
+	// its progress again. It is well under staleAfter, so a rebuild waiting on
+	// a slow broker isn't taken for a crashed one.
+	pollTimeout = 10 * time.Second
+	// logInterval is how often a running rebuild logs its progress.
+	logInterval = 5 * time.Second
+	// staleAfter is how long a running rebuild may go without recording
+	// progress before another replica takes it over, as after a crash.
+	staleAfter = time.Minute
+	// handlerAttempts bounds how often an event is retried before the rebuild
+	// fails.
+	handlerAttempts = 3
+	// batchSize bounds the events handled between polls.
+	batchSize = 500
+)
+
+// Rebuilder runs the requested rebuilds of a service's projections.
+type Rebuilder struct {
+	db          *sql.DB
+	brokers     []string
+	projections map[string]Projection
+}
+
+// NewRebuilder creates a rebuilder for the projections.
+func NewRebuilder(db *sql.DB, brokers []string, projections ...Projection) *Rebuilder {
+	r := &Rebuilder{db: db, brokers: brokers, projections: make(map[string]Projection)}
+	for _, p := range projections {
+		r.projections[p.Name] = p
+	}
+	return r
+}
+
+// Run registers the projections, so rebuilds of them can be requested, and runs
+// requested rebuilds until ctx is cancelled. Several replicas may run
+// rebuilders; each rebuild runs on one of them. A rebuild cut off by shutdown is
+// started over, by this replica or another, once it has gone stale.
+func (r *Rebuilder) Run(ctx context.Context) {
+	registered := false
+	for {
+		if !registered {
+			err := r.register(ctx)
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("Failed to register projections", "error", err)
+			}
+			registered = err == nil
+		}
+		rebuild, err := r.claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("Failed to claim projection rebuild", "error", err)
+		}
+		if rebuild != nil {
+			r.run(ctx, rebuild)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// register records the projections in the projections table.
+func (r *Rebuilder) register(ctx context.Context) error {
+	query := `
+		INSERT INTO projections (name, topics, tables) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE
+		SET topics = EXCLUDED.topics, tables = EXCLUDED.tables, registered_at = NOW()`
+	for _, p := range r.projections {
+		// Arrays, not NULL, even without tables.
+		topics, tables := append([]string{}, p.Topics...), append([]string{}, p.Tables...)
+		if _, err := r.db.ExecContext(ctx, query, p.Name, topics, tables); err != nil {
+			return fmt.Errorf("failed to register projection %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// claim marks the oldest requested, or stale running, rebuild of one of the
+// projections as running here.
+func (r *Rebuilder) claim(ctx context.Context) (*Rebuild, error) {
+	names := make([]string, 0, len(r.projections))
+	for name := range r.projections {
+		names = append(names, name)
+	}
+	query := `
+		UPDATE projection_rebuilds
+		SET status = 'RUNNING', started_at = NOW(), heartbeat_at = NOW(), replayed = 0, total = 0, skipped = 0
+		WHERE id = (
+			SELECT id FROM projection_rebuilds
+			WHERE projection = ANY($1)
+				AND (status = 'REQUESTED' OR (status = 'RUNNING' AND heartbeat_at < $2))
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, projection`
+	var rebuild Rebuild
+	err := r.db.QueryRowContext(ctx, query, names, time.Now().Add(-staleAfter)).Scan(&rebuild.ID, &rebuild.Projection)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rebuild, nil
+}
+
+// run rebuilds a claimed projection and records the outcome.
+func (r *Rebuilder) run(ctx context.Context, rebuild *Rebuild) {
+	p := r.projections[rebuild.Projection]
+	log := slog.With("projection", p.Name, "rebuild_id", rebuild.ID)
+	log.Info("Rebuilding projection")
+
+	// Holding the lock until the outcome is recorded keeps the projection
+	// offline (see ReadLock) for the whole rebuild.
+	conn, err := r.lock(ctx, p.Name)
+	if err == nil {
+		defer unlock(conn, p.Name, log)
+		err = r.rebuild(ctx, p, rebuild, log)
+	}
+	if ctx.Err() != nil {
+		// Left RUNNING, to be started over once stale.
+		return
+	}
+	status, message := StatusDone, ""
+	if err != nil {
+		status, message = StatusFailed, err.Error()
+		log.Error("Projection rebuild failed", "error", err)
+	} else {
+		log.Info("Projection rebuilt", "replayed", rebuild.Replayed, "skipped", rebuild.Skipped)
+	}
+	query := `
+		UPDATE projection_rebuilds
+		SET status = $2, error = $3, replayed = $4, skipped = $5, finished_at = NOW()
+		WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, rebuild.ID, status, message, rebuild.Replayed, rebuild.Skipped); err != nil {
+		log.Warn("Failed to record projection rebuild", "status", status, "error", err)
+	}
+}
+
+// rebuild truncates the projection's tables and replays its topics into them,
+// from the start of each partition to its end when the tables were truncated.
+func (r *Rebuilder) rebuild(ctx context.Context, p Projection, rebuild *Rebuild, log *slog.Logger) error {
+	if err := r.truncate(ctx, p); err != nil {
+		return err
+	}
+
+	// Transaction markers take up offsets too, so the replay has to see them to
+	// get to the end.
+	client, err := kgo.NewClient(kgo.SeedBrokers(r.brokers...), kgo.KeepControlRecords())
+	if err != nil {
+		return fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	defer client.Close()
+	adm := kadm.NewClient(client)
+
+	starts, err := adm.ListStartOffsets(ctx, p.Topics...)
+	if err == nil {
+		err = starts.Error()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list start offsets: %w", err)
+	}
+	ends, err := adm.ListEndOffsets(ctx, p.Topics...)
+	if err == nil {
+		err = ends.Error()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list end offsets: %w", err)
+	}
+
+	// Partitions still to replay, by the offset they end at, and the next offset
+	// of each.
+	remaining := make(map[string]map[int32]int64)
+	next := make(map[string]map[int32]int64)
+	from := make(map[string]map[int32]kgo.Offset)
+	starts.Each(func(start kadm.ListedOffset) {
+		end, _ := ends.Lookup(start.Topic, start.Partition)
+		if end.Offset <= start.Offset {
+			return
+		}
+		if remaining[start.Topic] == nil {
+			remaining[start.Topic] = make(map[int32]int64)
+			next[start.Topic] = make(map[int32]int64)
+			from[start.Topic] = make(map[int32]kgo.Offset)
+		}
+		remaining[start.Topic][start.Partition] = end.Offset
+		next[start.Topic][start.Partition] = start.Offset
+		from[start.Topic][start.Partition] = kgo.NewOffset().At(start.Offset)
+		rebuild.Total += end.Offset - start.Offset
+	})
+	if err := r.progress(ctx, rebuild); err != nil {
+		return err
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	// advance moves a partition's position to offset, counting the offsets passed
+	// (not events, so gaps left by compaction count too), and stops replaying the
+	// partition once its position reaches the end. The record at end-1 may never
+	// arrive: it may have been compacted away or be a transaction marker.
+	advance := func(topic string, partition int32, offset int64) {
+		end, ok := remaining[topic][partition]
+		if !ok || offset <= next[topic][partition] {
+			return
+		}
+		rebuild.Replayed += min(offset, end) - next[topic][partition]
+		next[topic][partition] = offset
+		if offset >= end {
+			client.RemoveConsumePartitions(map[string][]int32{topic: {partition}})
+			delete(remaining[topic], partition)
+			if len(remaining[topic]) == 0 {
+				delete(remaining, topic)
+			}
+		}
+	}
+
+	client.AddConsumePartitions(from)
+	lastLog := time.Now()
+	for len(remaining) > 0 {
+		pollCtx, cancel := context.WithTimeout(ctx, pollTimeout)
+		fetches := client.PollRecords(pollCtx, batchSize)
+		cancel()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				log.Warn("Kafka fetch failed", "topic", topic, "partition", partition, "error", err)
+			}
+		})
+
+		var failed error
+		fetches.EachRecord(func(rec *kgo.Record) {
+			end, ok := remaining[rec.Topic][rec.Partition]
+			if failed != nil || !ok {
+				return
+			}
+			if rec.Offset < end && !rec.Attrs.IsControl() {
+				if err := r.handle(ctx, p, rec); consumer.IsPermanent(err) {
+					log.Warn("Skipping event the projection can't apply", "topic", rec.Topic,
+						"partition", rec.Partition, "offset", rec.Offset, "error", err)
+					rebuild.Skipped++
+				} else if err != nil {
+					failed = fmt.Errorf("failed to replay %s partition %d offset %d: %w", rec.Topic, rec.Partition, rec.Offset, err)
+					return
+				}
+			}
+			advance(rec.Topic, rec.Partition, rec.Offset+1)
+		})
+		if failed != nil {
+			return failed
+		}
+
+		// Nothing to fetch may mean retention deleted the rest of a partition; the
+		// replay resumes from its new start, which may be past the end.
+		if fetches.NumRecords() == 0 {
+			starts, err := adm.ListStartOffsets(ctx, p.Topics...)
+			if err != nil {
+				log.Warn("Failed to list start offsets", "error", err)
+			}
+			starts.Each(func(start kadm.ListedOffset) {
+				if start.Err == nil {
+					advance(start.Topic, start.Partition, start.Offset)
+				}
+			})
+		}
+
+		// Recording progress after every poll is also the heartbeat that keeps
+		// the rebuild from being taken for a crashed one.
+		if err := r.progress(ctx, rebuild); err != nil {
+			return err
+		}
+		if time.Since(lastLog) >= logInterval {
+			log.Info("Rebuilding projection", "replayed", rebuild.Replayed, "total", rebuild.Total)
+			lastLog = time.Now()
+		}
+	}
+	return nil
+}
+
+// lock takes the projection's advisory lock exclusively, on a connection of its
+// own, once the reads in progress are done.
+func (r *Rebuilder) lock(ctx context.Context, projection string) (*sql.Conn, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(`+lockKey+`)`, projection); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to lock projection %s: %w", projection, err)
+	}
+	return conn, nil
+}
+
+// unlock releases the lock taken by lock and returns its connection to the pool,
+// or closes the connection if the lock can't be released.
+func unlock(conn *sql.Conn, projection string, log *slog.Logger) {
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(`+lockKey+`)`, projection); err != nil {
+		log.Warn("Failed to unlock projection", "error", err)
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
+
+// truncate empties the projection's tables and forgets its processed events, so
+// the replay applies them again.
+func (r *Rebuilder) truncate(ctx context.Context, p Projection) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if len(p.Tables) > 0 {
+		tables := make([]string, len(p.Tables))
+		for i, t := range p.Tables {
+			tables[i] = pgx.Identifier{t}.Sanitize()
+		}
+		if _, err := tx.ExecContext(ctx, "TRUNCATE "+strings.Join(tables, ", ")); err != nil {
+			return fmt.Errorf("failed to truncate %s: %w", strings.Join(p.Tables, ", "), err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM processed_events WHERE consumer_group = $1`, p.Name); err != nil {
+		return fmt.Errorf("failed to clear processed events: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit truncation: %w", err)
+	}
+	return nil
+}
+
+// handle applies a replayed event, retrying errors that aren't permanent.
+func (r *Rebuilder) handle(ctx context.Context, p Projection, rec *kgo.Record) error {
+	headers := make([]telemetry.Header, len(rec.Headers))
+	for i, h := range rec.Headers {
+		headers[i] = telemetry.Header(h)
+	}
+	ctx, span := telemetry.StartConsumerSpan(ctx, rec.Topic, p.Name+".rebuild", int(rec.Partition), rec.Offset, headers)
+	defer span.End()
+
+	var err error
+	for attempt := 1; attempt <= handlerAttempts; attempt++ {
+		if err = p.Handle(ctx, rec); err == nil || consumer.IsPermanent(err) || ctx.Err() != nil {
+			break
+		}
+		if attempt < handlerAttempts {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// progress records how far the rebuild has come, which also shows it is alive.
+func (r *Rebuilder) progress(ctx context.Context, rebuild *Rebuild) error {
+	query := `
+		UPDATE projection_rebuilds SET replayed = $2, total = $3, skipped = $4, heartbeat_at = NOW()
+		WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, rebuild.ID, rebuild.Replayed, rebuild.Total, rebuild.Skipped); err != nil {
+		return fmt.Errorf("failed to record progress: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Package events defines how the services exchange events over Kafka. Every
+// event travels in an eventspb.Envelope that names its type and schema version;
+// a Registry maps those to the protobuf messages that carry them and enforces
+// that versions of a type stay wire compatible, so producers and consumers can
+// be upgraded independently. Handle turns a typed function into a Kafka handler.
+package events
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	// ErrUnknownType is returned for event types or messages that aren't
+	// registered.
+	ErrUnknownType = errors.New("unknown event type")
+	// ErrIncompatible is matched by the error returned when a new version of an
+	// event type can't be read by consumers of the previous one, or vice versa.
+	ErrIncompatible = errors.New("incompatible event schema")
+)
+
+// Registry maps event types and versions to their messages. It is safe for
+// concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	versions  map[string][]schema // by type, oldest first
+	byMessage map[protoreflect.FullName]schema
+}
+
+// schema is a version of an event type.
+type schema struct {
+	eventType string
+	version   uint32
+	message   protoreflect.MessageType
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		versions:  make(map[string][]schema),
+		byMessage: make(map[protoreflect.FullName]schema),
+	}
+}
+
+// Register adds a version of an event type, carried by messages like msg.
+// Versions of a type are registered in increasing order, and each must be
+// compatible with the one before it (see CheckCompatible). Each version has its
+// own message, and a message carries one event type only: a message evolved in
+// place would only be checked against itself. Released versions are also
+// snapshotted under testdata/schemas, and the package's tests check the messages
+// against those, so evolving one in place is caught too.
+func (r *Registry) Register(eventType string, version uint32, msg proto.Message) error {
+	if eventType == "" {
+		return errors.New("event type is required")
+	}
+	if version == 0 {
+		return fmt.Errorf("event type %s: versions start at 1", eventType)
+	}
+	mt := msg.ProtoReflect().Type()
+	name := mt.Descriptor().FullName()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if other, ok := r.byMessage[name]; ok {
+		if other.eventType != eventType {
+			return fmt.Errorf("event type %s: message %s already carries %s", eventType, name, other.eventType)
+		}
+		return fmt.Errorf("event type %s version %d: message %s already carries version %d", eventType, version, name, other.version)
+	}
+	versions := r.versions[eventType]
+	if n := len(versions); n > 0 {
+		latest := versions[n-1]
+		if version <= latest.version {
+			return fmt.Errorf("event type %s: version %d registered after version %d", eventType, version, latest.version)
+		}
+		if err := CheckCompatible(latest.message.Descriptor(), mt.Descriptor()); err != nil {
+			return fmt.Errorf("event type %s version %d: %w", eventType, version, err)
+		}
+	}
+
+	s := schema{eventType: eventType, version: version, message: mt}
+	r.versions[eventType] = append(versions, s)
+	r.byMessage[name] = s
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is meant for
+// registering a service's events at startup.
+func (r *Registry) MustRegister(eventType string, version uint32, msg proto.Message) {
+	if err := r.Register(eventType, version, msg); err != nil {
+		panic(err)
+	}
+}
+
+// TypeOf returns the event type msg carries and the version registered with its
+// message, which is the version it is published as.
+func (r *Registry) TypeOf(msg proto.Message) (string, uint32, error) {
+	name := msg.ProtoReflect().Descriptor().FullName()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.byMessage[name]
+	if !ok {
+		return "", 0, fmt.Errorf("%w: no event type is carried by %s", ErrUnknownType, name)
+	}
+	return s.eventType, s.version, nil
+}
+
+// lookup returns the message to decode a version of an event type into: that
+// version's or, for a version this registry doesn't know, the nearest one (the
+// newest older version, failing that the oldest). Compatibility guarantees it
+// can read the payload.
+func (r *Registry) lookup(eventType string, version uint32) (protoreflect.MessageType, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := r.versions[eventType]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, eventType)
+	}
+	i, found := slices.BinarySearchFunc(versions, version, func(s schema, v uint32) int {
+		return cmp.Compare(s.version, v)
+	})
+	switch {
+	case found:
+		return versions[i].message, nil
+	case i > 0:
+		return versions[i-1].message, nil
+	default:
+		return versions[0].message, nil
+	}
+}
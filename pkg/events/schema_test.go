@@ -0,0 +1,102 @@
+package events
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var update = flag.Bool("update", false, "snapshot event versions that have no snapshot yet")
+
+// TestCatalogSnapshots checks every registered event version against the
+// descriptors it was released with. Register only compares versions with each
+// other, so this is what catches a message edited in place.
+func TestCatalogSnapshots(t *testing.T) {
+	for eventType, versions := range Catalog().versions {
+		for _, s := range versions {
+			path := filepath.Join("testdata", "schemas", eventType, "v"+strconv.FormatUint(uint64(s.version), 10)+".txtpb")
+			desc := s.message.Descriptor()
+
+			released, err := loadSnapshot(path, desc.FullName())
+			if errors.Is(err, os.ErrNotExist) && *update {
+				writeSnapshot(t, path, desc)
+				continue
+			}
+			if errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s version %d has no snapshot; run go test ./pkg/events -update", eventType, s.version)
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := CheckCompatible(released, desc); err != nil {
+				t.Errorf("%s version %d changed since release: %v", eventType, s.version, err)
+			}
+		}
+	}
+}
+
+// loadSnapshot reads the descriptor set at path and finds the message named name.
+func loadSnapshot(path string, name protoreflect.FullName) (protoreflect.MessageDescriptor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := prototext.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, err
+	}
+	d, err := files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, errors.New(string(name) + " is not a message")
+	}
+	return md, nil
+}
+
+// writeSnapshot writes the file declaring desc and every file it imports.
+func writeSnapshot(t *testing.T, path string, desc protoreflect.MessageDescriptor) {
+	t.Helper()
+	var set descriptorpb.FileDescriptorSet
+	seen := make(map[string]bool)
+	var add func(protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := range imports.Len() {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(desc.ParentFile())
+
+	b, err := prototext.MarshalOptions{Multiline: true}.Marshal(&set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("wrote %s", path)
+}
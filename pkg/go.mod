@@ -1,3 +1,5 @@
 module github.com/my-store/pkg
 
 go 1.25.4
+
+require github.com/twmb/franz-go/pkg/kadm v1.17.2
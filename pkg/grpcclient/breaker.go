@@ -0,0 +1,98 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/my-store/pkg/apierr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errDomain marks errors raised by the client itself rather than the service.
+const errDomain apierr.Domain = "grpcclient.my-store"
+
+// ReasonCircuitOpen is the ErrorInfo reason of calls rejected by an open breaker.
+const ReasonCircuitOpen = "CIRCUIT_OPEN"
+
+var breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "grpc_client_circuit_breaker_state",
+	Help: "Circuit breaker state per downstream service: 0 closed, 1 half-open, 2 open.",
+}, []string{"grpc_service"})
+
+// BreakerConfig configures a circuit breaker.
+type BreakerConfig struct {
+	// Failures is the number of consecutive failed calls that opens the breaker.
+	Failures int
+	// Cooldown is how long the breaker stays open before letting a trial call through.
+	Cooldown time.Duration
+}
+
+// DefaultBreakerConfig returns the breaker settings used unless overridden.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{Failures: 5, Cooldown: 10 * time.Second}
+}
+
+// breaker fails calls to a service fast once it has failed repeatedly, giving it
+// room to recover and sparing callers the full deadline. After the cooldown one
+// trial call is let through; it closes the breaker again if it succeeds.
+type breaker struct {
+	cb       *gobreaker.CircuitBreaker
+	cooldown time.Duration
+	openedAt atomic.Int64 // unix nanos of the last transition to open
+}
+
+func newBreaker(service string, cfg BreakerConfig) *breaker {
+	b := &breaker{cooldown: cfg.Cooldown}
+	b.cb = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        service,
+		MaxRequests: 1,
+		Timeout:     cfg.Cooldown,
+		ReadyToTrip: func(c gobreaker.Counts) bool {
+			return c.ConsecutiveFailures >= uint32(cfg.Failures)
+		},
+		IsSuccessful: func(err error) bool { return !isOutage(err) },
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			if to == gobreaker.StateOpen {
+				b.openedAt.Store(time.Now().UnixNano())
+			}
+			breakerState.WithLabelValues(name).Set(float64(to))
+			slog.Warn("Circuit breaker state changed", "grpc_service", name, "from", from.String(), "to", to.String())
+		},
+	})
+	breakerState.WithLabelValues(service).Set(0)
+	return b
+}
+
+func (b *breaker) unary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	_, err := b.cb.Execute(func() (any, error) {
+		return nil, invoker(ctx, method, req, reply, cc, opts...)
+	})
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return errDomain.Unavailable(ReasonCircuitOpen, b.cb.Name()+" is unavailable", b.retryAfter())
+	}
+	return err
+}
+
+// retryAfter estimates when the breaker will let calls through again.
+func (b *breaker) retryAfter() time.Duration {
+	remaining := b.cooldown - time.Since(time.Unix(0, b.openedAt.Load()))
+	return max(remaining, time.Second)
+}
+
+// isOutage reports whether err suggests the service itself is failing, as
+// opposed to rejecting a request or the caller giving up.
+func isOutage(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.DataLoss:
+		return true
+	}
+	return false
+}
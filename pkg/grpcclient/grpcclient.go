@@ -0,0 +1,150 @@
+// Package grpcclient dials downstream gRPC services with the resilience settings
+// every caller should have:
+//
+//   - client-side round-robin load balancing over the addresses DNS returns for
+//     the target, so a headless Kubernetes Service spreads calls across replicas,
+//     with replicas reporting NOT_SERVING (e.g. while draining) skipped;
+//   - a default deadline per call and per-method overrides, set through the gRPC
+//     service config so callers don't need their own timeouts;
+//   - retries with exponential backoff for methods declared idempotent;
+//   - keepalive pings that detect dead connections between calls;
+//   - a circuit breaker that fails calls fast while the service is down (see breaker.go).
+package grpcclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/health" // registers client-side health checking
+	"google.golang.org/grpc/keepalive"
+)
+
+// Config describes how to reach and call one downstream service.
+type Config struct {
+	// Target is "host:port" (resolved through DNS) or any gRPC target URI.
+	Target string
+	// Timeout is the deadline of calls to methods without their own.
+	Timeout time.Duration
+	// Methods holds per-method settings, keyed by method name (e.g. "GetOrder").
+	Methods map[string]Method
+	// MaxAttempts bounds the attempts of a retried call, the first included.
+	MaxAttempts int
+	// KeepaliveTime is the idle time after which the connection is pinged.
+	KeepaliveTime time.Duration
+	// Breaker configures the circuit breaker.
+	Breaker BreakerConfig
+}
+
+// Method holds the settings of a single method.
+type Method struct {
+	Timeout time.Duration // overrides Config.Timeout when set
+	Retry   bool          // safe to retry: the method has no side effects, or repeats them harmlessly
+}
+
+// DefaultConfig returns the settings used unless a caller overrides them.
+func DefaultConfig(target string) Config {
+	return Config{
+		Target:        target,
+		Timeout:       3 * time.Second,
+		MaxAttempts:   3,
+		KeepaliveTime: 30 * time.Second,
+		Breaker:       DefaultBreakerConfig(),
+	}
+}
+
+// Dial creates a client connection to service (its full protobuf name, e.g.
+// "order.OrderService"). Like grpc.NewClient it doesn't connect until the first call.
+func Dial(service string, cfg Config, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	sc, err := serviceConfig(service, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	brk := newBreaker(service, cfg.Breaker)
+	opts = append([]grpc.DialOption{
+		grpc.WithDefaultServiceConfig(sc),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.KeepaliveTime,
+			Timeout:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+		// The breaker sees each call once, after all of its retries.
+		grpc.WithChainUnaryInterceptor(brk.unary),
+	}, opts...)
+	return grpc.NewClient(target(cfg.Target), opts...)
+}
+
+// target resolves bare host:port addresses through DNS, which returns every
+// replica behind a headless Service, rather than the default passthrough resolver.
+func target(addr string) string {
+	if strings.Contains(addr, ":///") {
+		return addr
+	}
+	return "dns:///" + addr
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+type methodConfig struct {
+	Name        []methodName `json:"name"`
+	Timeout     string       `json:"timeout,omitempty"`
+	RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+}
+
+// serviceConfig renders the gRPC service config (see
+// https://github.com/grpc/grpc/blob/master/doc/service_config.md) for cfg.
+func serviceConfig(service string, cfg Config) (string, error) {
+	methods := []methodConfig{{
+		Name:    []methodName{{Service: service}},
+		Timeout: duration(cfg.Timeout),
+	}}
+	for name, m := range cfg.Methods {
+		mc := methodConfig{
+			Name:    []methodName{{Service: service, Method: name}},
+			Timeout: duration(cfg.Timeout),
+		}
+		if m.Timeout > 0 {
+			mc.Timeout = duration(m.Timeout)
+		}
+		if m.Retry && cfg.MaxAttempts > 1 {
+			mc.RetryPolicy = &retryPolicy{
+				MaxAttempts:       cfg.MaxAttempts,
+				InitialBackoff:    "0.1s",
+				MaxBackoff:        "1s",
+				BackoffMultiplier: 2,
+				// Only failures where the service most likely never ran the call.
+				RetryableStatusCodes: []string{"UNAVAILABLE"},
+			}
+		}
+		methods = append(methods, mc)
+	}
+
+	sc, err := json.Marshal(map[string]any{
+		"loadBalancingConfig": []map[string]any{{"round_robin": map[string]any{}}},
+		"healthCheckConfig":   map[string]string{"serviceName": ""},
+		"methodConfig":        methods,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build service config: %w", err)
+	}
+	return string(sc), nil
+}
+
+// duration formats d the way the service config expects, e.g. "2.5s".
+func duration(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
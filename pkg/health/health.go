@@ -0,0 +1,180 @@
+// Package health runs dependency checks and serves them as Kubernetes-style
+// liveness and readiness probes.
+//
+// Liveness only says the process is up and able to answer; it never looks at
+// dependencies, so an outage of Postgres doesn't get every pod restarted.
+// Readiness runs every registered check and fails if any of them does, which
+// takes the pod out of load balancing until its dependencies recover. Optional
+// checks cover dependencies the process can work around; their failures only
+// mark it degraded.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether a dependency is usable. It should respect ctx's deadline.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of a readiness run.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded" // only optional checks fail; still ready
+	StatusFail     = "fail"
+)
+
+// Checker holds the readiness checks of a process.
+type Checker struct {
+	timeout  time.Duration
+	mu       sync.RWMutex
+	checks   map[string]check
+	draining atomic.Bool
+}
+
+type check struct {
+	run      Check
+	optional bool
+}
+
+// NewChecker creates a Checker whose checks each get at most timeout to complete.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]check),
+	}
+}
+
+// Add registers a named readiness check, replacing any check with the same name.
+func (c *Checker) Add(name string, fn Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check{run: fn}
+}
+
+// AddOptional registers a check whose failure degrades the process without
+// making it unready, for dependencies only some requests need.
+func (c *Checker) AddOptional(name string, fn Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check{run: fn, optional: true}
+}
+
+// SetDraining marks the process as shutting down. A draining process is never
+// ready, whatever its checks say, so it receives no new traffic.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Run executes every check concurrently and reports the combined result.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.runOne(ctx, checks[i].run)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		switch {
+		case results[i].Status == StatusOK:
+		case checks[i].optional:
+			if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		default:
+			report.Status = StatusFail
+		}
+	}
+	if c.draining.Load() {
+		report.Status = StatusFail
+	}
+	return report
+}
+
+func (c *Checker) runOne(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	res := Result{Status: StatusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Ready reports whether every required check currently passes.
+func (c *Checker) Ready(ctx context.Context) bool {
+	return c.Run(ctx).Status != StatusFail
+}
+
+// LivenessHandler answers 200 as long as the process can serve HTTP.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler runs the checks and answers 200 if the required ones pass, or 503 otherwise.
+// The body lists every check so a failing probe explains itself.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+		code := http.StatusOK
+		if report.Status == StatusFail {
+			code = http.StatusServiceUnavailable
+		}
+		writeReport(w, code, report)
+	})
+}
+
+// Register mounts the probes on mux at /livez and /readyz.
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.Handle("GET /livez", c.LivenessHandler())
+	mux.Handle("GET /readyz", c.ReadinessHandler())
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,51 @@
+package identity
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/my-store/pkg/apierr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+const errDomain apierr.Domain = "identity.my-store"
+
+// ReasonInvalidIdentity is the ErrorInfo reason of calls whose identity token
+// doesn't verify.
+const ReasonInvalidIdentity = "INVALID_IDENTITY"
+
+// UnaryClientInterceptor signs the identity carried by the call's context, if
+// any, and sends it as metadata.
+func UnaryClientInterceptor(s *Signer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if id, ok := FromContext(ctx); ok {
+			token, err := s.Sign(id)
+			if err != nil {
+				return err
+			}
+			ctx = metadata.AppendToOutgoingContext(ctx, MetadataKey, token)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// UnaryServerInterceptor verifies the identity token sent by the caller and
+// makes the identity available through FromContext. Calls without a token pass
+// through without an identity; methods that act for a user must check for one.
+// Calls with an invalid token are rejected.
+func UnaryServerInterceptor(s *Signer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		tokens := metadata.ValueFromIncomingContext(ctx, MetadataKey)
+		if len(tokens) == 0 {
+			return handler(ctx, req)
+		}
+		id, err := s.Verify(tokens[0])
+		if err != nil {
+			slog.WarnContext(ctx, "Rejected identity token", "method", info.FullMethod, "error", err)
+			return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidIdentity, "Invalid identity token")
+		}
+		return handler(NewContext(ctx, id), req)
+	}
+}
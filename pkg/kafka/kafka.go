@@ -0,0 +1,180 @@
+// Package kafka produces and consumes Kafka records with the conventions shared
+// by the services: producer and consumer spans that carry the trace and request
+// ID across the topic (see package telemetry), consumer lag and outcome metrics,
+// and at-least-once consumption with offsets committed after the handler runs.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/my-store/pkg/metrics"
+	"github.com/my-store/pkg/telemetry"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Message is a record to publish.
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers []telemetry.Header
+}
+
+// Producer publishes messages, waiting for the brokers to acknowledge each batch.
+type Producer struct {
+	client *kgo.Client
+}
+
+// NewProducer connects a producer to the brokers. Topics are created on first use
+// when the brokers allow it.
+func NewProducer(brokers []string) (*Producer, error) {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.AllowAutoTopicCreation(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka producer: %w", err)
+	}
+	return &Producer{client: client}, nil
+}
+
+// Publish writes the messages and returns once all are acknowledged, or the first
+// error. Each message gets a producer span, a child of the span in ctx.
+func (p *Producer) Publish(ctx context.Context, msgs ...Message) error {
+	records := make([]*kgo.Record, len(msgs))
+	for i, m := range msgs {
+		headers := append([]telemetry.Header(nil), m.Headers...)
+		_, span := telemetry.StartProducerSpan(ctx, m.Topic, &headers)
+		defer span.End()
+
+		rec := &kgo.Record{Topic: m.Topic, Key: m.Key, Value: m.Value}
+		for _, h := range headers {
+			rec.Headers = append(rec.Headers, kgo.RecordHeader(h))
+		}
+		records[i] = rec
+	}
+
+	results := p.client.ProduceSync(ctx, records...)
+	for _, res := range results {
+		if res.Err != nil {
+			return fmt.Errorf("failed to publish to %s: %w", res.Record.Topic, res.Err)
+		}
+	}
+	return nil
+}
+
+// Close flushes buffered records and disconnects.
+func (p *Producer) Close() error {
+	p.client.Close()
+	return nil
+}
+
+// Record is a consumed message.
+type Record = kgo.Record
+
+// Handler processes a record. A returned error makes the consumer retry it.
+type Handler func(ctx context.Context, rec *Record) error
+
+// handlerAttempts bounds how often a failing record is retried before it is
+// logged and skipped, so one bad record can't stall its partition.
+const handlerAttempts = 3
+
+// Consumer reads topics as a member of a consumer group.
+type Consumer struct {
+	client *kgo.Client
+	group  string
+}
+
+// NewConsumer joins the consumer group for the topics. A new group starts at the
+// earliest offset, so no record published before it first ran is missed.
+func NewConsumer(brokers []string, group string, topics ...string) (*Consumer, error) {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumerGroup(group),
+		kgo.ConsumeTopics(topics...),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		// Offsets are committed only for records the handler has finished with,
+		// including right before partitions move to another member.
+		kgo.AutoCommitMarks(),
+		kgo.BlockRebalanceOnPoll(),
+		kgo.OnPartitionsRevoked(func(ctx context.Context, cl *kgo.Client, _ map[string][]int32) {
+			if err := cl.CommitMarkedOffsets(ctx); err != nil {
+				slog.Warn("Failed to commit offsets on rebalance", "group", group, "error", err)
+			}
+		}),
+		kgo.AllowAutoTopicCreation(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka consumer: %w", err)
+	}
+	return &Consumer{client: client, group: group}, nil
+}
+
+// Run handles records until ctx is cancelled, then commits the offsets handled so
+// far and leaves the group.
+func (c *Consumer) Run(ctx context.Context, handle Handler) {
+	defer c.close()
+	for {
+		fetches := c.client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			slog.Warn("Kafka fetch failed", "topic", topic, "partition", partition, "error", err)
+		})
+
+		fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+			for _, rec := range p.Records {
+				if ctx.Err() != nil {
+					return
+				}
+				c.handle(ctx, rec, handle)
+				metrics.RecordConsumerLag(rec.Topic, c.group, int(rec.Partition), p.HighWatermark, rec.Offset)
+				c.client.MarkCommitRecords(rec)
+			}
+		})
+		c.client.AllowRebalance()
+	}
+}
+
+func (c *Consumer) handle(ctx context.Context, rec *kgo.Record, handle Handler) {
+	headers := make([]telemetry.Header, len(rec.Headers))
+	for i, h := range rec.Headers {
+		headers[i] = telemetry.Header(h)
+	}
+	ctx, span := telemetry.StartConsumerSpan(ctx, rec.Topic, c.group, int(rec.Partition), rec.Offset, headers)
+	defer span.End()
+
+	var err error
+	for attempt := 1; attempt <= handlerAttempts; attempt++ {
+		if err = handle(ctx, rec); err == nil || errors.Is(err, context.Canceled) {
+			break
+		}
+		if attempt < handlerAttempts {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+	}
+	metrics.RecordConsumed(rec.Topic, c.group, err)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		slog.ErrorContext(ctx, "Skipping record after failed attempts",
+			"topic", rec.Topic, "partition", rec.Partition, "offset", rec.Offset, "attempts", handlerAttempts, "error", err)
+	}
+}
+
+func (c *Consumer) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.client.CommitMarkedOffsets(ctx); err != nil {
+		slog.Warn("Failed to commit offsets on shutdown", "group", c.group, "error", err)
+	}
+	c.client.Close()
+}
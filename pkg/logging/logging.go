@@ -0,0 +1,89 @@
+// Package logging configures structured logging with log/slog.
+//
+// Services log JSON to stdout, one object per line, with the service name, the
+// request ID and, when tracing is enabled, the trace and span IDs attached to
+// every record logged with a context. Attributes that may carry credentials or
+// personal data are redacted before they are written (see redact.go).
+//
+// The level is set with LOG_LEVEL (debug, info, warn or error; info by default)
+// and LOG_FORMAT=text switches to human-readable output for local runs.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup installs the default logger for the named service. Output of the
+// standard log package goes through it too, at info level.
+func Setup(service string) *slog.Logger {
+	logger, err := New(os.Stdout, service, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		logger, _ = New(os.Stdout, service, "", os.Getenv("LOG_FORMAT"))
+		logger.Warn("Ignoring invalid log level", "error", err)
+	}
+	slog.SetDefault(logger)
+	return logger
+}
+
+// New builds a logger writing to w. An empty level means info and an empty
+// format means JSON.
+func New(w io.Writer, service, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL %q", level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q", format)
+	}
+	return slog.New(contextHandler{h}).With("service", service), nil
+}
+
+// Fatal logs msg at error level and exits. It is for startup failures in main.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// contextHandler adds the request ID and trace context carried by the context
+// passed to the *Context logging methods.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces the value of attributes that must never be logged.
+const redacted = "[REDACTED]"
+
+// secretKeys are substrings of attribute keys whose values are dropped entirely.
+var secretKeys = []string{"password", "token", "secret", "authorization", "cookie", "mfa_code", "recovery_code"}
+
+// emailPattern finds email addresses inside free-form values such as error messages.
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@([A-Za-z0-9\-]+\.)+[A-Za-z]{2,}`)
+
+// redact is the slog ReplaceAttr hook. Secrets are replaced outright; email
+// addresses keep only their domain, which is enough to spot a misbehaving
+// provider without identifying the user.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+
+	switch v := a.Value.Any().(type) {
+	case string:
+		if strings.Contains(key, "email") {
+			return slog.String(a.Key, maskEmail(v))
+		}
+		if strings.Contains(v, "@") {
+			return slog.String(a.Key, emailPattern.ReplaceAllStringFunc(v, maskEmail))
+		}
+	case error:
+		if msg := v.Error(); strings.Contains(msg, "@") {
+			return slog.String(a.Key, emailPattern.ReplaceAllStringFunc(msg, maskEmail))
+		}
+	}
+	return a
+}
+
+// maskEmail turns "jane.doe@example.com" into "***@example.com".
+func maskEmail(email string) string {
+	if _, domain, ok := strings.Cut(email, "@"); ok {
+		return "***@" + domain
+	}
+	return redacted
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	consumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Messages between a consumer group's position and the end of the partition.",
+	}, []string{"topic", "group", "partition"})
+
+	consumerProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_messages_total",
+		Help: "Messages handled by event consumers, by outcome (ok or error).",
+	}, []string{"topic", "group", "outcome"})
+
+	consumerDeadLetters = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_dead_letters_total",
+		Help: "Messages event consumers gave up on and sent to their dead-letter topic.",
+	}, []string{"topic", "group"})
+)
+
+// RecordConsumerLag sets the lag of a partition from its high water mark and the
+// offset of the message just handled.
+func RecordConsumerLag(topic, group string, partition int, highWaterMark, offset int64) {
+	lag := highWaterMark - offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	consumerLag.WithLabelValues(topic, group, strconv.Itoa(partition)).Set(float64(lag))
+}
+
+// RecordConsumed counts a handled message; err is what the handler returned.
+func RecordConsumed(topic, group string, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	consumerProcessed.WithLabelValues(topic, group, outcome).Inc()
+}
+
+// RecordDeadLettered counts a message from topic sent to the group's dead-letter
+// topic.
+func RecordDeadLettered(topic, group string) {
+	consumerDeadLetters.WithLabelValues(topic, group).Inc()
+}
@@ -0,0 +1,15 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterDB exports the sql.DBStats of db as go_sql_* metrics labelled with
+// db_name: open, in-use and idle connections, and how often and how long callers
+// waited for one.
+func RegisterDB(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
@@ -0,0 +1,23 @@
+// Package metrics exposes Prometheus metrics for the services.
+//
+// Everything is registered with the default registry, which also carries the Go
+// runtime and process collectors, and is served by Handler on /metrics. The
+// package provides the RED metrics (rate, errors, duration) for gRPC servers and
+// HTTP handlers, connection pool gauges and consumer lag; business counters live
+// next to the code that increments them in each service.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// latencyBuckets covers calls from a millisecond up to the BFF's 5s client timeout
+// and a little beyond.
+var latencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
@@ -0,0 +1,75 @@
+package mtls
+
+import (
+	"context"
+	"crypto/x509"
+	"slices"
+	"strings"
+
+	"github.com/my-store/pkg/apierr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// SPIFFEPrefix starts the URI SAN that names a workload in its certificate,
+// e.g. "spiffe://my-store/bff".
+const SPIFFEPrefix = "spiffe://my-store/"
+
+const errDomain apierr.Domain = "mtls.my-store"
+
+// ReasonCallerNotAllowed is the ErrorInfo reason of calls rejected by Authorize.
+const ReasonCallerNotAllowed = "CALLER_NOT_ALLOWED"
+
+// Rules maps full method names ("/order.OrderService/CreateOrder") to the
+// workloads allowed to call them. Methods without a rule are open to any caller
+// holding a certificate from the trusted CA.
+type Rules map[string][]string
+
+// Identities returns the workload names a certificate vouches for: the name in
+// each SPIFFE URI SAN, and each DNS SAN.
+func Identities(cert *x509.Certificate) []string {
+	var ids []string
+	for _, u := range cert.URIs {
+		if name, ok := strings.CutPrefix(u.String(), SPIFFEPrefix); ok {
+			ids = append(ids, name)
+		}
+	}
+	return append(ids, cert.DNSNames...)
+}
+
+// Caller returns the verified identities of the peer that made the call in ctx,
+// or nil when the connection isn't mutually authenticated.
+func Caller(ctx context.Context) []string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return Identities(info.State.VerifiedChains[0][0])
+}
+
+// Authorize returns an interceptor enforcing rules. It must only be installed on
+// servers that require client certificates; calls to a ruled method without a
+// verified caller are denied.
+func Authorize(rules Rules) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if allowed, ok := rules[info.FullMethod]; ok && !permitted(Caller(ctx), allowed) {
+			return nil, errDomain.Error(codes.PermissionDenied, ReasonCallerNotAllowed, "Caller is not allowed to call "+info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func permitted(caller, allowed []string) bool {
+	for _, id := range caller {
+		if slices.Contains(allowed, id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+// Command dev-ca creates a local certificate authority and issues a certificate
+// for each service named on the command line, for running the services with
+// mutual TLS in development:
+//
+//	go run ./pkg/mtls/cmd/dev-ca -out certs bff auth order shipping
+//
+// An existing CA in the output directory is reused, so certificates can be
+// added or renewed without re-issuing the others.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/my-store/pkg/mtls/devca"
+)
+
+func main() {
+	out := flag.String("out", "certs", "output directory")
+	namespace := flag.String("namespace", "default", "Kubernetes namespace added to the certificates' DNS names")
+	days := flag.Int("days", 365, "validity of issued certificates in days")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] service...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatalf("Failed to create %s: %v", *out, err)
+	}
+	validity := time.Duration(*days) * 24 * time.Hour
+
+	ca, err := devca.Load(filepath.Join(*out, "ca.pem"), filepath.Join(*out, "ca-key.pem"))
+	if errors.Is(err, fs.ErrNotExist) {
+		// The CA outlives the certificates it issues, so renewals don't need a new trust root.
+		if ca, err = devca.New(10 * validity); err == nil {
+			_, err = ca.WriteFiles(*out)
+		}
+		if err == nil {
+			log.Printf("Created CA in %s", *out)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to set up CA: %v", err)
+	}
+
+	for _, name := range flag.Args() {
+		hosts := []string{
+			fmt.Sprintf("%s.%s.svc", name, *namespace),
+			fmt.Sprintf("%s.%s.svc.cluster.local", name, *namespace),
+			"localhost",
+			"127.0.0.1",
+		}
+		cert, err := ca.Issue(name, hosts, validity)
+		if err != nil {
+			log.Fatalf("Failed to issue certificate for %s: %v", name, err)
+		}
+		files, err := cert.WriteFiles(*out)
+		if err != nil {
+			log.Fatalf("Failed to write certificate for %s: %v", name, err)
+		}
+		log.Printf("Issued %s (key %s)", files.Cert, files.Key)
+	}
+}
@@ -0,0 +1,194 @@
+// Package mtls provides TLS and mutual TLS transport credentials for gRPC, with
+// certificates read from PEM files and picked up again when the files change,
+// so rotated certificates (e.g. a renewed Kubernetes secret) take effect
+// without a restart.
+//
+// With only a CA file, a client verifies servers; adding a certificate and key
+// makes it present them as its identity. A server needs a certificate and key;
+// with a CA file as well it requires and verifies client certificates, which
+// lets Authorize decide per method which callers are allowed.
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// reloadInterval bounds how often the files are checked for changes. Checks
+// happen lazily, on handshakes, so an idle process does no work.
+const reloadInterval = 10 * time.Second
+
+// Files names the PEM files of a TLS identity.
+type Files struct {
+	Cert string // certificate chain presented to peers
+	Key  string // private key of Cert
+	CA   string // CA bundle used to verify peers
+}
+
+// Enabled reports whether any TLS file is configured.
+func (f Files) Enabled() bool {
+	return f.Cert != "" || f.Key != "" || f.CA != ""
+}
+
+// Source holds the current certificate and CA pool loaded from Files.
+type Source struct {
+	files Files
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  [3]time.Time
+	lastCheck time.Time
+}
+
+// NewSource loads the files, failing if they are missing or invalid. Cert and
+// Key must be given together.
+func NewSource(files Files) (*Source, error) {
+	if (files.Cert == "") != (files.Key == "") {
+		return nil, fmt.Errorf("TLS certificate and key must be configured together")
+	}
+	s := &Source{files: files}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.lastCheck = time.Now()
+	return s, nil
+}
+
+func (s *Source) load() error {
+	var cert *tls.Certificate
+	if s.files.Cert != "" {
+		c, err := tls.LoadX509KeyPair(s.files.Cert, s.files.Key)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS key pair: %w", err)
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if s.files.CA != "" {
+		pem, err := os.ReadFile(s.files.CA)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", s.files.CA)
+		}
+	}
+
+	s.cert, s.pool, s.modTimes = cert, pool, s.stat()
+	return nil
+}
+
+func (s *Source) stat() [3]time.Time {
+	var times [3]time.Time
+	for i, name := range []string{s.files.Cert, s.files.Key, s.files.CA} {
+		if name == "" {
+			continue
+		}
+		if fi, err := os.Stat(name); err == nil {
+			times[i] = fi.ModTime()
+		}
+	}
+	return times
+}
+
+// current returns the loaded material, reloading it first if the files changed.
+// A failed reload keeps the previous material, since a half-written rotation is
+// usually complete by the next check.
+func (s *Source) current() (*tls.Certificate, *x509.CertPool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastCheck) >= reloadInterval {
+		s.lastCheck = time.Now()
+		if s.stat() != s.modTimes {
+			if err := s.load(); err != nil {
+				slog.Error("Failed to reload TLS certificates", "error", err)
+			} else {
+				slog.Info("Reloaded TLS certificates")
+			}
+		}
+	}
+	return s.cert, s.pool
+}
+
+// ServerCredentials returns credentials for a gRPC server. Client certificates
+// are required when the source has a CA.
+func (s *Source) ServerCredentials() credentials.TransportCredentials {
+	return &reloading{config: func() *tls.Config {
+		cert, pool := s.current()
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if cert != nil {
+			cfg.Certificates = []tls.Certificate{*cert}
+		}
+		if pool != nil {
+			cfg.ClientCAs = pool
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		return cfg
+	}}
+}
+
+// ClientCredentials returns credentials for a gRPC client. Servers are verified
+// against the source's CA, or the system roots without one, and the source's
+// certificate, if any, is presented for mutual TLS.
+func (s *Source) ClientCredentials() credentials.TransportCredentials {
+	return &reloading{config: func() *tls.Config {
+		cert, pool := s.current()
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: pool}
+		if cert != nil {
+			cfg.Certificates = []tls.Certificate{*cert}
+		}
+		return cfg
+	}}
+}
+
+// reloading builds fresh TLS credentials from the current material for every
+// handshake, so established connections keep their session and new ones use
+// rotated certificates.
+type reloading struct {
+	config     func() *tls.Config
+	serverName string
+}
+
+func (r *reloading) creds() credentials.TransportCredentials {
+	cfg := r.config()
+	cfg.ServerName = r.serverName
+	return credentials.NewTLS(cfg)
+}
+
+func (r *reloading) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return r.creds().ClientHandshake(ctx, authority, conn)
+}
+
+func (r *reloading) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return r.creds().ServerHandshake(conn)
+}
+
+func (r *reloading) Info() credentials.ProtocolInfo {
+	return r.creds().Info()
+}
+
+func (r *reloading) Clone() credentials.TransportCredentials {
+	c := *r
+	return &c
+}
+
+// OverrideServerName implements credentials.TransportCredentials.
+//
+// Deprecated: use grpc.WithAuthority instead.
+func (r *reloading) OverrideServerName(name string) error {
+	r.serverName = name
+	return nil
+}
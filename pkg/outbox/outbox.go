@@ -0,0 +1,147 @@
+// Package outbox publishes Kafka messages reliably from services that change
+// state in Postgres. Instead of publishing directly, which could fail after the
+// transaction commits or succeed for a transaction that then rolls back, a
+// service adds the message to the outbox table in the same transaction as its
+// change. A Relay then publishes pending messages in order and removes them, so
+// every committed change is published at least once.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/my-store/pkg/events"
+	"github.com/my-store/pkg/kafka"
+	"github.com/my-store/pkg/telemetry"
+)
+
+// InitSchema creates the outbox table if it doesn't exist.
+func InitSchema(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS outbox (
+		id BIGSERIAL PRIMARY KEY,
+		topic TEXT NOT NULL,
+		key BYTEA,
+		value BYTEA NOT NULL,
+		headers JSONB NOT NULL DEFAULT '[]',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`
+	_, err := db.Exec(query)
+	return err
+}
+
+// Add queues msg in tx. The trace context and request ID of ctx travel with it,
+// so consumers continue the trace of the request that caused the change.
+func Add(ctx context.Context, tx *sql.Tx, msg kafka.Message) error {
+	headers := append([]telemetry.Header(nil), msg.Headers...)
+	telemetry.InjectHeaders(ctx, &headers)
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal headers: %w", err)
+	}
+
+	query := `INSERT INTO outbox (topic, key, value, headers) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, query, msg.Topic, msg.Key, msg.Value, headersJSON); err != nil {
+		return fmt.Errorf("failed to add outbox message: %w", err)
+	}
+	return nil
+}
+
+const (
+	// batchSize bounds the messages published per transaction.
+	batchSize = 100
+	// pollInterval is how long the relay waits when the outbox is drained.
+	pollInterval = time.Second
+)
+
+// Relay publishes the messages added to the outbox.
+type Relay struct {
+	db       *sql.DB
+	producer events.Publisher
+}
+
+// NewRelay creates a relay publishing through producer.
+func NewRelay(db *sql.DB, producer events.Publisher) *Relay {
+	return &Relay{db: db, producer: producer}
+}
+
+// Run publishes pending messages until ctx is cancelled. Several replicas may run
+// relays on the same table; each message is published by one of them.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		n, err := r.publishBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("Failed to publish outbox messages", "error", err)
+		}
+		if n == batchSize && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// publishBatch publishes the oldest pending messages and deletes them. The rows
+// stay locked until then, so other relays skip them; if publishing fails, the
+// transaction rolls back and they are retried.
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, topic, key, value, headers FROM outbox
+		ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query outbox: %w", err)
+	}
+
+	var ids []int64
+	var msgs []kafka.Message
+	for rows.Next() {
+		var id int64
+		var msg kafka.Message
+		var headersJSON []byte
+		if err := rows.Scan(&id, &msg.Topic, &msg.Key, &msg.Value, &headersJSON); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if err := json.Unmarshal(headersJSON, &msg.Headers); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to unmarshal headers: %w", err)
+		}
+		ids = append(ids, id)
+		msgs = append(msgs, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+
+	// One at a time, so each message's producer span joins its own trace.
+	for _, msg := range msgs {
+		if err := r.producer.Publish(telemetry.ExtractHeaders(ctx, msg.Headers), msg); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, ids); err != nil {
+		return 0, fmt.Errorf("failed to delete published messages: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox batch: %w", err)
+	}
+	return len(msgs), nil
+}
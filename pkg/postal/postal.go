@@ -0,0 +1,68 @@
+// Package postal validates the postal addresses the services exchange, so an
+// address saved to the address book, put on an order or printed on a label is
+// held to the same rules everywhere.
+package postal
+
+import (
+	"regexp"
+	"strings"
+
+	commonpb "github.com/my-store/pkg/api/common"
+	"github.com/my-store/pkg/apierr"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// Field violation codes.
+const (
+	CodeRequired = "required"
+	CodeInvalid  = "invalid"
+)
+
+var (
+	// PhonePattern matches the phone numbers accepted on addresses and profiles.
+	PhonePattern   = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{5,18}[0-9]$`)
+	countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// Normalize trims every field and upper-cases the country code.
+func Normalize(a *commonpb.Address) {
+	a.RecipientName = strings.TrimSpace(a.RecipientName)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.Region = strings.TrimSpace(a.Region)
+	a.PostalCode = strings.TrimSpace(a.PostalCode)
+	a.CountryCode = strings.ToUpper(strings.TrimSpace(a.CountryCode))
+	a.Phone = strings.TrimSpace(a.Phone)
+}
+
+// Validate normalizes the address in place and reports missing or malformed
+// fields, named under field, e.g. "address.city". A nil address is missing every
+// required field.
+func Validate(field string, a *commonpb.Address) []*errdetails.BadRequest_FieldViolation {
+	if a == nil {
+		a = &commonpb.Address{}
+	}
+	Normalize(a)
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	required := []struct{ name, value string }{
+		{"recipient_name", a.RecipientName},
+		{"line1", a.Line1},
+		{"city", a.City},
+		{"postal_code", a.PostalCode},
+		{"country_code", a.CountryCode},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			violations = append(violations, apierr.FieldViolation(field+"."+r.name, CodeRequired, "This field is required"))
+		}
+	}
+	if a.CountryCode != "" && !countryPattern.MatchString(a.CountryCode) {
+		violations = append(violations, apierr.FieldViolation(field+".country_code", CodeInvalid, "Country must be a two-letter ISO code"))
+	}
+	if a.Phone != "" && !PhonePattern.MatchString(a.Phone) {
+		violations = append(violations, apierr.FieldViolation(field+".phone", CodeInvalid, "Phone number is invalid"))
+	}
+	return violations
+}
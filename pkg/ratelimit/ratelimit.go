@@ -0,0 +1,104 @@
+// Package ratelimit implements token-bucket rate limiting with interchangeable
+// backends: Memory for a single process and Redis for limits shared by every
+// replica of a service.
+//
+// A bucket holds up to Burst tokens and refills at Limit tokens per Period. Each
+// request takes one token and is rejected while the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy describes a token bucket.
+type Policy struct {
+	Limit  int           // tokens added per Period
+	Period time.Duration // refill period
+	Burst  int           // bucket capacity; Limit when zero
+}
+
+// ParsePolicy parses "limit/period" or "limit/period/burst", e.g. "10/1m" or "100/1h/20".
+func ParsePolicy(s string) (Policy, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Policy{}, fmt.Errorf("invalid rate limit %q, want limit/period[/burst]", s)
+	}
+	var p Policy
+	var err error
+	if p.Limit, err = strconv.Atoi(parts[0]); err != nil {
+		return Policy{}, fmt.Errorf("invalid rate limit %q: %w", s, err)
+	}
+	if p.Period, err = time.ParseDuration(parts[1]); err != nil {
+		return Policy{}, fmt.Errorf("invalid rate limit %q: %w", s, err)
+	}
+	if len(parts) == 3 {
+		if p.Burst, err = strconv.Atoi(parts[2]); err != nil {
+			return Policy{}, fmt.Errorf("invalid rate limit %q: %w", s, err)
+		}
+	}
+	if p.Limit < 1 || p.Period <= 0 || p.Burst < 0 {
+		return Policy{}, fmt.Errorf("invalid rate limit %q", s)
+	}
+	return p, nil
+}
+
+// String formats the policy as accepted by ParsePolicy.
+func (p Policy) String() string {
+	if p.Burst != 0 && p.Burst != p.Limit {
+		return fmt.Sprintf("%d/%s/%d", p.Limit, p.Period, p.Burst)
+	}
+	return fmt.Sprintf("%d/%s", p.Limit, p.Period)
+}
+
+// Capacity returns the bucket size.
+func (p Policy) Capacity() int {
+	if p.Burst > 0 {
+		return p.Burst
+	}
+	return p.Limit
+}
+
+// rate returns the refill rate in tokens per second.
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed    bool
+	Limit      int           // bucket capacity
+	Remaining  int           // whole tokens left after this request
+	RetryAfter time.Duration // until the next token, when not allowed
+	Reset      time.Duration // until the bucket is full again
+}
+
+// Limiter takes tokens from the bucket identified by key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, p Policy) (Result, error)
+}
+
+// result builds a Result from the tokens left in a bucket after a request.
+func result(p Policy, allowed bool, tokens float64) Result {
+	rate := p.rate()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     p.Capacity(),
+		Remaining: int(tokens),
+		Reset:     seconds((float64(p.Capacity()) - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s < 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeToken refills and takes from a bucket stored as a hash, atomically. It
+// uses the Redis server clock so replicas with skewed clocks agree, and expires
+// the key once the bucket would be full again.
+//
+// KEYS[1] bucket key; ARGV[1] capacity; ARGV[2] rate in tokens per second.
+// Returns {allowed (0 or 1), tokens left as a string}.
+var takeToken = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// Redis keeps buckets in Redis (or a compatible server such as Valkey or
+// Dragonfly), so every replica shares the same limits.
+type Redis struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedis creates a limiter storing buckets under keys starting with prefix.
+func NewRedis(client redis.Scripter, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+// Allow takes a token from the bucket for key.
+func (r *Redis) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	vals, err := takeToken.Run(ctx, r.client, []string{r.prefix + key},
+		p.Capacity(), strconv.FormatFloat(p.rate(), 'f', -1, 64)).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take token: %w", err)
+	}
+	if len(vals) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", vals)
+	}
+	allowed, _ := vals[0].(int64)
+	s, _ := vals[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", vals)
+	}
+	return result(p, allowed == 1, tokens), nil
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/my-store/pkg/mtls"
+)
+
+// Config holds the settings shared by every gRPC service. Each setting can be
+// given as a command-line flag; otherwise it is read from the environment
+// variable named in the flag's usage, and failing that it takes its default.
+type Config struct {
+	Name            string
+	Port            int
+	Reflection      bool
+	ShutdownTimeout time.Duration // how long in-flight calls get to finish on shutdown
+	HealthPort      int           // HTTP port serving /livez, /readyz and /metrics
+	HealthInterval  time.Duration // how often checks refresh the gRPC health status
+	KafkaBrokers    []string
+	TLS             mtls.Files // serve TLS when set; with a CA, require client certificates
+	DB              DBConfig
+}
+
+// DBConfig describes the service's Postgres database.
+type DBConfig struct {
+	Host           string
+	Port           int
+	User           string
+	Password       string
+	Name           string
+	SSLMode        string
+	ConnectTimeout time.Duration // how long to keep retrying the first connection
+}
+
+// DSN returns the connection URL for the pgx driver.
+func (c DBConfig) DSN() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
+// LoadConfig reads the configuration of the named service from the command
+// line and the environment. defaultPort is used when neither sets the port.
+func LoadConfig(name string, defaultPort int) (*Config, error) {
+	return loadConfig(name, defaultPort, os.Args[1:])
+}
+
+func loadConfig(name string, defaultPort int, args []string) (*Config, error) {
+	var env envReader
+	cfg := &Config{Name: name}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.IntVar(&cfg.Port, "port", env.int("GRPC_PORT", defaultPort), "gRPC listen port (GRPC_PORT)")
+	fs.BoolVar(&cfg.Reflection, "reflection", env.bool("GRPC_REFLECTION", true), "register the gRPC reflection service (GRPC_REFLECTION)")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", env.duration("SHUTDOWN_TIMEOUT", 15*time.Second), "time allowed to drain in-flight calls (SHUTDOWN_TIMEOUT)")
+	fs.IntVar(&cfg.HealthPort, "health-port", env.int("HEALTH_PORT", 8081), "HTTP port for the liveness and readiness probes (HEALTH_PORT)")
+	fs.DurationVar(&cfg.HealthInterval, "health-interval", env.duration("HEALTH_CHECK_INTERVAL", 10*time.Second), "interval between dependency checks (HEALTH_CHECK_INTERVAL)")
+	fs.StringVar(&cfg.TLS.Cert, "tls-cert", env.string("TLS_CERT_FILE", ""), "PEM certificate served to clients (TLS_CERT_FILE)")
+	fs.StringVar(&cfg.TLS.Key, "tls-key", env.string("TLS_KEY_FILE", ""), "PEM private key of the certificate (TLS_KEY_FILE)")
+	fs.StringVar(&cfg.TLS.CA, "tls-ca", env.string("TLS_CA_FILE", ""), "PEM CA bundle for verifying client certificates; enables mTLS (TLS_CA_FILE)")
+	brokers := fs.String("kafka-brokers", env.string("KAFKA_BROKERS", ""), "comma-separated Kafka bootstrap brokers (KAFKA_BROKERS)")
+
+	fs.StringVar(&cfg.DB.Host, "db-host", env.string("POSTGRES_HOST", "localhost"), "Postgres host (POSTGRES_HOST)")
+	fs.IntVar(&cfg.DB.Port, "db-port", env.int("POSTGRES_PORT", 5432), "Postgres port (POSTGRES_PORT)")
+	fs.StringVar(&cfg.DB.User, "db-user", env.string("POSTGRES_USER", ""), "Postgres user (POSTGRES_USER)")
+	fs.StringVar(&cfg.DB.Password, "db-password", env.string("POSTGRES_PASSWORD", ""), "Postgres password (POSTGRES_PASSWORD)")
+	fs.StringVar(&cfg.DB.Name, "db-name", env.string("POSTGRES_DB", ""), "Postgres database (POSTGRES_DB)")
+	fs.StringVar(&cfg.DB.SSLMode, "db-sslmode", env.string("POSTGRES_SSLMODE", "disable"), "Postgres sslmode (POSTGRES_SSLMODE)")
+	fs.DurationVar(&cfg.DB.ConnectTimeout, "db-connect-timeout", env.duration("POSTGRES_CONNECT_TIMEOUT", time.Minute), "how long to wait for Postgres at startup (POSTGRES_CONNECT_TIMEOUT)")
+
+	if env.err != nil {
+		return nil, env.err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", cfg.Port)
+	}
+	if cfg.HealthPort < 1 || cfg.HealthPort > 65535 || cfg.HealthPort == cfg.Port {
+		return nil, fmt.Errorf("invalid health port %d", cfg.HealthPort)
+	}
+	if cfg.TLS.Enabled() && (cfg.TLS.Cert == "" || cfg.TLS.Key == "") {
+		return nil, fmt.Errorf("TLS requires both a certificate and a key")
+	}
+	for _, b := range strings.Split(*brokers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			cfg.KafkaBrokers = append(cfg.KafkaBrokers, b)
+		}
+	}
+	return cfg, nil
+}
+
+// envReader reads typed environment variables, remembering the first malformed one.
+type envReader struct {
+	err error
+}
+
+func (e *envReader) string(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func (e *envReader) int(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.fail(key, v)
+		return def
+	}
+	return n
+}
+
+func (e *envReader) bool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.fail(key, v)
+		return def
+	}
+	return b
+}
+
+func (e *envReader) duration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.fail(key, v)
+		return def
+	}
+	return d
+}
+
+func (e *envReader) fail(key, value string) {
+	if e.err == nil {
+		e.err = fmt.Errorf("invalid value %q for %s", value, key)
+	}
+}
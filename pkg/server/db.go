@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+)
+
+const (
+	initialBackoff = 250 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+)
+
+// OpenDB connects to Postgres, retrying with exponential backoff until the
+// database answers or cfg.ConnectTimeout elapses. The caller must register the
+// "pgx" database/sql driver.
+//
+// Queries made with a context (QueryContext, ExecContext, ...) are traced as
+// children of the span in that context.
+func OpenDB(ctx context.Context, cfg DBConfig) (*sql.DB, error) {
+	db, err := otelsql.Open("pgx", cfg.DSN(),
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL, semconv.DBNamespace(cfg.Name)),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			slog.Info("Connected to database", "database", cfg.Name, "host", cfg.Host)
+			return db, nil
+		}
+
+		// Full jitter keeps replicas that start together from retrying in lockstep.
+		wait := rand.N(backoff) + time.Millisecond
+		slog.Warn("Waiting for database", "attempt", attempt, "retry_in", wait.Round(time.Millisecond).String(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", attempt, err)
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
@@ -0,0 +1,378 @@
+// Package server is the common bootstrap for the gRPC services: configuration,
+// the database connection, the interceptor chain, reflection, health checking
+// and graceful shutdown. A service's main loads a Config, registers its handlers
+// on a Server and calls Run.
+//
+// Health is exposed twice: through grpc.health.v1 on the gRPC port, where the
+// overall ("") status and every registered service follow the readiness checks,
+// and as HTTP /livez and /readyz probes on HealthPort for Kubernetes, next to the
+// Prometheus /metrics endpoint.
+//
+// With Config.TLS set the gRPC port serves TLS, requiring client certificates
+// when a CA is given; certificates are reloaded when their files change.
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/my-store/pkg/health"
+	"github.com/my-store/pkg/logging"
+	"github.com/my-store/pkg/metrics"
+	"github.com/my-store/pkg/mtls"
+	"github.com/my-store/pkg/telemetry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+// checkTimeout bounds each dependency check.
+const checkTimeout = 2 * time.Second
+
+// Server is a gRPC server with the shared middleware and lifecycle. It implements
+// grpc.ServiceRegistrar, so generated Register functions accept it directly.
+type Server struct {
+	cfg      *Config
+	grpc     *grpc.Server
+	health   *grpchealth.Server
+	checker  *health.Checker
+	services []string
+	closers  []io.Closer
+	handlers []*http.Server
+	workers  []func(context.Context)
+	running  sync.WaitGroup
+}
+
+type options struct {
+	unary      []grpc.UnaryServerInterceptor
+	stream     []grpc.StreamServerInterceptor
+	serverOpts []grpc.ServerOption
+	authz      mtls.Rules
+}
+
+// Option customises a Server.
+type Option func(*options)
+
+// WithUnaryInterceptors appends unary interceptors after the built-in recovery and logging ones.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *options) { o.unary = append(o.unary, interceptors...) }
+}
+
+// WithStreamInterceptors appends stream interceptors after the built-in recovery one.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(o *options) { o.stream = append(o.stream, interceptors...) }
+}
+
+// WithServerOptions passes extra options to grpc.NewServer.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *options) { o.serverOpts = append(o.serverOpts, opts...) }
+}
+
+// WithAuthorization restricts methods to the callers named in rules, identified
+// by their client certificates (see mtls.Authorize). The rules are only enforced
+// when the server is configured for mutual TLS.
+func WithAuthorization(rules mtls.Rules) Option {
+	return func(o *options) { o.authz = rules }
+}
+
+// New creates a Server with the standard health service registered and tracing
+// configured (see package telemetry). If the config names Kafka brokers, a
+// "kafka" readiness check is added.
+func New(cfg *Config, opts ...Option) *Server {
+	// Tracing is best effort: a bad exporter config shouldn't keep the service down.
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg.Name)
+	if err != nil {
+		slog.Warn("Tracing disabled", "error", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// The request ID comes first so every later log line carries it. Metrics wrap
+	// recovery so panics are counted as the Internal errors they become.
+	unary := []grpc.UnaryServerInterceptor{logging.UnaryServerInterceptor, metrics.UnaryServerInterceptor, recoverUnary, logUnary}
+	stream := []grpc.StreamServerInterceptor{logging.StreamServerInterceptor, metrics.StreamServerInterceptor, recoverStream}
+	if o.authz != nil {
+		if cfg.TLS.CA != "" {
+			unary = append(unary, mtls.Authorize(o.authz))
+		} else {
+			slog.Warn("Caller authorization disabled: mutual TLS is not configured")
+		}
+	}
+	unary = append(unary, o.unary...)
+	stream = append(stream, o.stream...)
+
+	serverOpts := append([]grpc.ServerOption{
+		// Server spans continue the caller's trace; health checks are too frequent to be worth tracing.
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+		// Accept the keepalive pings of pkg/grpcclient (every 30s, even when idle).
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             15 * time.Second,
+			PermitWithoutStream: true,
+		}),
+		// Recycle connections now and then so clients balancing over DNS pick up
+		// replicas added since they connected.
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionAge:      5 * time.Minute,
+			MaxConnectionAgeGrace: 30 * time.Second,
+		}),
+	}, o.serverOpts...)
+	if cfg.TLS.Enabled() {
+		// Serving plaintext when TLS was asked for would be worse than not starting.
+		source, err := mtls.NewSource(cfg.TLS)
+		if err != nil {
+			logging.Fatal("Failed to load TLS certificates", "error", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(source.ServerCredentials()))
+	}
+
+	s := &Server{
+		cfg:     cfg,
+		grpc:    grpc.NewServer(serverOpts...),
+		health:  grpchealth.NewServer(),
+		checker: health.NewChecker(checkTimeout),
+		// Closed last, so spans recorded during shutdown are still flushed.
+		closers: []io.Closer{closerFunc(func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return shutdownTracing(ctx)
+		})},
+	}
+	healthpb.RegisterHealthServer(s.grpc, s.health)
+	// Nothing is ready until the first round of checks has passed.
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	if len(cfg.KafkaBrokers) > 0 {
+		s.AddCheck("kafka", health.Kafka(cfg.KafkaBrokers))
+	}
+	return s
+}
+
+// RegisterService registers a service implementation. Its health status follows
+// the server's readiness checks.
+func (s *Server) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	s.grpc.RegisterService(desc, impl)
+	s.services = append(s.services, desc.ServiceName)
+	s.health.SetServingStatus(desc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// AddCheck adds a readiness check for a dependency the service can't work without.
+func (s *Server) AddCheck(name string, check health.Check) {
+	s.checker.Add(name, check)
+}
+
+// AddHTTPHandler serves h on port alongside the gRPC server, e.g. for webhooks
+// that must be reachable over plain HTTP. It is shut down gracefully with the
+// gRPC server.
+func (s *Server) AddHTTPHandler(port int, h http.Handler) {
+	s.handlers = append(s.handlers, &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           h,
+		ReadHeaderTimeout: 5 * time.Second,
+	})
+}
+
+// AddWorker runs fn in the background while the server runs, e.g. a Kafka
+// consumer. fn must return once its context is cancelled, which happens on
+// shutdown after in-flight calls have drained and before the closers run, so
+// workers can still use the database.
+func (s *Server) AddWorker(fn func(ctx context.Context)) {
+	s.workers = append(s.workers, fn)
+}
+
+// AddCloser registers c to be closed on shutdown, after the workers have
+// stopped. Closers run in reverse order of registration.
+func (s *Server) AddCloser(c io.Closer) {
+	s.closers = append(s.closers, c)
+}
+
+// OpenDB connects to the configured database and adds a "postgres" readiness
+// check for it. The connection is closed once the server has drained on shutdown.
+func (s *Server) OpenDB() (*sql.DB, error) {
+	db, err := OpenDB(context.Background(), s.cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+	s.closers = append(s.closers, db)
+	s.AddCheck("postgres", health.Postgres(db))
+	if err := metrics.RegisterDB(db, s.cfg.DB.Name); err != nil {
+		slog.Warn("Failed to register database metrics", "error", err)
+	}
+	return db, nil
+}
+
+// Run serves until the process receives SIGINT or SIGTERM, then shuts down gracefully.
+func (s *Server) Run() error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.cfg.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	if s.cfg.Reflection {
+		reflection.Register(s.grpc)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	probes := s.probeServer()
+	probeLis, err := net.Listen("tcp", probes.Addr)
+	if err != nil {
+		lis.Close()
+		return fmt.Errorf("failed to listen for probes: %w", err)
+	}
+
+	serveErr := make(chan error, 2+len(s.handlers))
+	go func() {
+		serveErr <- s.grpc.Serve(lis)
+	}()
+	go func() {
+		if err := probes.Serve(probeLis); !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+	for _, h := range s.handlers {
+		go func() {
+			if err := h.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- err
+			}
+		}()
+	}
+	go s.watchHealth(ctx)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	for _, fn := range s.workers {
+		s.running.Go(func() { fn(workerCtx) })
+	}
+	slog.Info("Service listening", "port", s.cfg.Port, "health_port", s.cfg.HealthPort)
+
+	select {
+	case err := <-serveErr:
+		stopWorkers()
+		s.running.Wait()
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down server")
+	s.shutdown(stopWorkers)
+	probes.Close()
+	return nil
+}
+
+func (s *Server) probeServer() *http.Server {
+	mux := http.NewServeMux()
+	s.checker.Register(mux)
+	mux.Handle("GET /metrics", metrics.Handler())
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.cfg.HealthPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// watchHealth keeps the grpc.health.v1 statuses in line with the readiness checks
+// until ctx is cancelled.
+func (s *Server) watchHealth(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.HealthInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		report := s.checker.Run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		status := healthpb.HealthCheckResponse_SERVING
+		if report.Status == health.StatusFail {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		if status != last {
+			if status == healthpb.HealthCheckResponse_SERVING {
+				slog.Info("Readiness checks passing")
+			} else {
+				for name, res := range report.Checks {
+					if res.Status != health.StatusOK {
+						slog.Warn("Readiness check failing", "check", name, "error", res.Error)
+					}
+				}
+			}
+			s.health.SetServingStatus("", status)
+			for _, svc := range s.services {
+				s.health.SetServingStatus(svc, status)
+			}
+			last = status
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// shutdown stops accepting calls and waits up to ShutdownTimeout for in-flight
+// ones to finish before closing their connections. The workers are stopped next,
+// and the closers run last.
+func (s *Server) shutdown(stopWorkers context.CancelFunc) {
+	// Report NOT_SERVING first so health-checking clients and the readiness
+	// probe stop routing new calls here.
+	s.checker.SetDraining()
+	s.health.Shutdown()
+
+	drained := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(drained)
+	}()
+
+	var handlersDone sync.WaitGroup
+	httpCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	for _, h := range s.handlers {
+		handlersDone.Go(func() { h.Shutdown(httpCtx) })
+	}
+
+	select {
+	case <-drained:
+	case <-time.After(s.cfg.ShutdownTimeout):
+		slog.Warn("Calls still running after shutdown timeout, closing connections", "timeout", s.cfg.ShutdownTimeout.String())
+		s.grpc.Stop()
+		<-drained
+	}
+	handlersDone.Wait()
+
+	stopWorkers()
+	s.running.Wait()
+
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i].Close(); err != nil {
+			slog.Error("Shutdown failed", "error", err)
+		}
+	}
+}
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/my-store/pkg/logging"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header is a Kafka record header. It has the same shape as the header types of
+// the Go Kafka clients, so their header slices convert field by field.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// HeaderCarrier adapts Kafka record headers to propagation.TextMapCarrier.
+type HeaderCarrier struct {
+	Headers *[]Header
+}
+
+// Get returns the value of the first header with the given key.
+func (c HeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces the header with the given key, or appends it.
+func (c HeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if h.Key == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, Header{Key: key, Value: []byte(value)})
+}
+
+// Keys lists the header keys.
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, len(*c.Headers))
+	for i, h := range *c.Headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
+
+// StartProducerSpan starts a span for publishing a message to topic and writes
+// the span's trace context and the request ID into headers. The caller ends the
+// span once the broker has acknowledged the write.
+func StartProducerSpan(ctx context.Context, topic string, headers *[]Header) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, "send "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingDestinationName(topic),
+		),
+	)
+	InjectHeaders(ctx, headers)
+	return ctx, span
+}
+
+// InjectHeaders writes the trace context and request ID of ctx into headers.
+func InjectHeaders(ctx context.Context, headers *[]Header) {
+	carrier := HeaderCarrier{Headers: headers}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if id := logging.RequestID(ctx); id != "" {
+		carrier.Set(logging.RequestIDKey, id)
+	}
+}
+
+// ExtractHeaders returns ctx with the trace context and request ID recorded in
+// headers by InjectHeaders.
+func ExtractHeaders(ctx context.Context, headers []Header) context.Context {
+	carrier := HeaderCarrier{Headers: &headers}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	if id := carrier.Get(logging.RequestIDKey); logging.ValidRequestID(id) {
+		ctx = logging.WithRequestID(ctx, id)
+	}
+	return ctx
+}
+
+// StartConsumerSpan starts a span for processing a message received from topic,
+// continuing the trace and request recorded in its headers by the producer.
+func StartConsumerSpan(ctx context.Context, topic, group string, partition int, offset int64, headers []Header) (context.Context, trace.Span) {
+	return Tracer().Start(ExtractHeaders(ctx, headers), "process "+topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeProcess,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingConsumerGroupName(group),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(partition)),
+			semconv.MessagingKafkaOffset(int(offset)),
+		),
+	)
+}
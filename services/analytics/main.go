@@ -1,26 +1,22 @@
 package main
 
 import (
-	"fmt"
-	"log"
-	"net"
-	"os"
-	"os/signal"
-	"syscall"
+	"github.com/my-store/pkg/logging"
+	"github.com/my-store/pkg/server"
 )
 
 func main() {
-	port := 50055
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	logging.Setup("analytics")
+
+	cfg, err := server.LoadConfig("analytics", 50055)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logging.Fatal("Invalid configuration", "error", err)
 	}
-	defer lis.Close()
-	log.Printf("Analytics Service listening on port %d", port)
+	srv := server.New(cfg)
 
-	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+	// The consumers aren't implemented yet; the server answers health checks,
+	// including readiness of the Kafka brokers listed in KAFKA_BROKERS.
+	if err := srv.Run(); err != nil {
+		logging.Fatal("Analytics service failed", "error", err)
+	}
 }
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	commonpb "github.com/my-store/pkg/api/common"
+)
+
+// ErrAddressNotFound is returned when an address doesn't exist or belongs to another user.
+var ErrAddressNotFound = errors.New("address not found")
+
+// Address is an entry in a user's address book.
+type Address struct {
+	ID            int64
+	UserID        int64
+	Label         string
+	RecipientName string
+	Line1         string
+	Line2         string
+	City          string
+	Region        string
+	PostalCode    string
+	CountryCode   string
+	Phone         string
+	IsDefault     bool
+}
+
+const addressColumns = `id, user_id, label, recipient_name, line1, line2, city, region, postal_code, country_code, phone, is_default`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAddress(row rowScanner) (*Address, error) {
+	var a Address
+	err := row.Scan(&a.ID, &a.UserID, &a.Label, &a.RecipientName, &a.Line1, &a.Line2,
+		&a.City, &a.Region, &a.PostalCode, &a.CountryCode, &a.Phone, &a.IsDefault)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAddressNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListAddresses returns the user's addresses, default first.
+func (s *UserStore) ListAddresses(ctx context.Context, userID int64) ([]*Address, error) {
+	query := `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1 ORDER BY is_default DESC, id`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []*Address
+	for rows.Next() {
+		a, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// GetAddress retrieves one of the user's addresses.
+func (s *UserStore) GetAddress(ctx context.Context, userID, addressID int64) (*Address, error) {
+	query := `SELECT ` + addressColumns + ` FROM addresses WHERE id = $1 AND user_id = $2`
+	return scanAddress(s.db.QueryRowContext(ctx, query, addressID, userID))
+}
+
+// GetDefaultAddress retrieves the user's default shipping address.
+func (s *UserStore) GetDefaultAddress(ctx context.Context, userID int64) (*Address, error) {
+	query := `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1 AND is_default`
+	return scanAddress(s.db.QueryRowContext(ctx, query, userID))
+}
+
+// CreateAddress inserts an address. The user's first address always becomes the default.
+func (s *UserStore) CreateAddress(ctx context.Context, a *Address, makeDefault bool) (*Address, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var hasDefault bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM addresses WHERE user_id = $1 AND is_default)`, a.UserID).Scan(&hasDefault); err != nil {
+		return nil, err
+	}
+	a.IsDefault = makeDefault || !hasDefault
+	if a.IsDefault && hasDefault {
+		if _, err := tx.ExecContext(ctx, `UPDATE addresses SET is_default = FALSE WHERE user_id = $1 AND is_default`, a.UserID); err != nil {
+			return nil, fmt.Errorf("failed to clear default address: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO addresses (user_id, label, recipient_name, line1, line2, city, region, postal_code, country_code, phone, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id`
+	err = tx.QueryRowContext(ctx, query, a.UserID, a.Label, a.RecipientName, a.Line1, a.Line2,
+		a.City, a.Region, a.PostalCode, a.CountryCode, a.Phone, a.IsDefault).Scan(&a.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert address: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// UpdateAddress replaces the label and postal fields of an address.
+func (s *UserStore) UpdateAddress(ctx context.Context, a *Address) (*Address, error) {
+	query := `
+		UPDATE addresses SET label = $3, recipient_name = $4, line1 = $5, line2 = $6,
+			city = $7, region = $8, postal_code = $9, country_code = $10, phone = $11
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + addressColumns
+	return scanAddress(s.db.QueryRowContext(ctx, query, a.ID, a.UserID, a.Label, a.RecipientName, a.Line1, a.Line2,
+		a.City, a.Region, a.PostalCode, a.CountryCode, a.Phone))
+}
+
+// DeleteAddress removes an address. If it was the default, the oldest remaining
+// address is promoted so the user keeps a default whenever they have any address.
+func (s *UserStore) DeleteAddress(ctx context.Context, userID, addressID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var wasDefault bool
+	err = tx.QueryRowContext(ctx, `DELETE FROM addresses WHERE id = $1 AND user_id = $2 RETURNING is_default`, addressID, userID).Scan(&wasDefault)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAddressNotFound
+		}
+		return err
+	}
+
+	if wasDefault {
+		query := `
+			UPDATE addresses SET is_default = TRUE
+			WHERE id = (SELECT id FROM addresses WHERE user_id = $1 ORDER BY id LIMIT 1)`
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return fmt.Errorf("failed to promote default address: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// SetDefaultAddress makes the given address the user's default.
+func (s *UserStore) SetDefaultAddress(ctx context.Context, userID, addressID int64) (*Address, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE addresses SET is_default = FALSE WHERE user_id = $1 AND is_default AND id <> $2`, userID, addressID); err != nil {
+		return nil, fmt.Errorf("failed to clear default address: %w", err)
+	}
+	query := `UPDATE addresses SET is_default = TRUE WHERE id = $1 AND user_id = $2 RETURNING ` + addressColumns
+	a, err := scanAddress(tx.QueryRowContext(ctx, query, addressID, userID))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// addressFromProto copies postal fields from the shared API message.
+func addressFromProto(userID int64, label string, in *commonpb.Address) *Address {
+	if in == nil {
+		in = &commonpb.Address{}
+	}
+	return &Address{
+		UserID:        userID,
+		Label:         label,
+		RecipientName: in.RecipientName,
+		Line1:         in.Line1,
+		Line2:         in.Line2,
+		City:          in.City,
+		Region:        in.Region,
+		PostalCode:    in.PostalCode,
+		CountryCode:   in.CountryCode,
+		Phone:         in.Phone,
+	}
+}
+
+// Proto returns the address in the shared API format.
+func (a *Address) Proto() *commonpb.Address {
+	return &commonpb.Address{
+		RecipientName: a.RecipientName,
+		Line1:         a.Line1,
+		Line2:         a.Line2,
+		City:          a.City,
+		Region:        a.Region,
+		PostalCode:    a.PostalCode,
+		CountryCode:   a.CountryCode,
+		Phone:         a.Phone,
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// breachedList is a bundled set of SHA-1 hashes of known compromised passwords,
+// stored in the "PREFIX:SUFFIX" range format used by k-anonymity APIs.
+//
+//go:embed data/breached_sha1.txt
+var breachedList string
+
+// prefixLength is the number of hex characters used to bucket hashes.
+const prefixLength = 5
+
+// BreachedChecker looks up password hashes in an offline list of compromised passwords.
+// Hashes are grouped by prefix so a lookup only scans a single small range,
+// mirroring how remote k-anonymity services are queried.
+type BreachedChecker struct {
+	ranges map[string]map[string]struct{}
+}
+
+// NewBreachedChecker loads the bundled list, plus an optional extra file from
+// BREACHED_PASSWORDS_FILE in the same format.
+func NewBreachedChecker() (*BreachedChecker, error) {
+	c := &BreachedChecker{ranges: make(map[string]map[string]struct{})}
+	if err := c.load(strings.NewReader(breachedList)); err != nil {
+		return nil, fmt.Errorf("failed to load bundled breached list: %w", err)
+	}
+
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open breached list: %w", err)
+		}
+		defer f.Close()
+		if err := c.load(f); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+func (c *BreachedChecker) load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		prefix, suffix, ok := strings.Cut(text, ":")
+		if !ok || len(prefix) != prefixLength || len(prefix)+len(suffix) != sha1.Size*2 {
+			return fmt.Errorf("line %d: malformed entry", line)
+		}
+		prefix, suffix = strings.ToUpper(prefix), strings.ToUpper(suffix)
+		bucket, ok := c.ranges[prefix]
+		if !ok {
+			bucket = make(map[string]struct{})
+			c.ranges[prefix] = bucket
+		}
+		bucket[suffix] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// IsBreached reports whether the password's hash appears in the list.
+func (c *BreachedChecker) IsBreached(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	bucket, ok := c.ranges[hash[:prefixLength]]
+	if !ok {
+		return false
+	}
+	_, found := bucket[hash[prefixLength:]]
+	return found
+}
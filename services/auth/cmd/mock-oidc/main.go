@@ -0,0 +1,40 @@
+// Command mock-oidc runs a local OpenID Connect issuer for exercising the
+// social login flow without a real identity provider.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/my-store/pkg/oidcmock"
+)
+
+func main() {
+	addr := os.Getenv("MOCK_OIDC_ADDR")
+	if addr == "" {
+		addr = ":9000"
+	}
+	issuerURL := os.Getenv("MOCK_OIDC_ISSUER")
+	if issuerURL == "" {
+		issuerURL = "http://localhost:9000"
+	}
+	clientID := os.Getenv("MOCK_OIDC_CLIENT_ID")
+	if clientID == "" {
+		clientID = "my-store"
+	}
+	clientSecret := os.Getenv("MOCK_OIDC_CLIENT_SECRET")
+	if clientSecret == "" {
+		clientSecret = "my-store-secret"
+	}
+
+	issuer, err := oidcmock.NewIssuer(issuerURL, clientID, clientSecret)
+	if err != nil {
+		log.Fatalf("Failed to create issuer: %v", err)
+	}
+
+	log.Printf("Mock OIDC issuer %s listening on %s (client_id=%s)", issuerURL, addr, clientID)
+	if err := http.ListenAndServe(addr, issuer); err != nil {
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
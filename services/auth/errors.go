@@ -0,0 +1,29 @@
+package main
+
+import "github.com/my-store/pkg/apierr"
+
+// errDomain is the ErrorInfo domain of every error returned by this service.
+const errDomain apierr.Domain = "auth.my-store"
+
+// Error reasons returned in ErrorInfo. They are part of the API: clients branch on
+// them, so existing values must not change.
+const (
+	ReasonValidationFailed    = "VALIDATION_FAILED"
+	ReasonEmailTaken          = "EMAIL_TAKEN"
+	ReasonInvalidCredentials  = "INVALID_CREDENTIALS"
+	ReasonInvalidToken        = "INVALID_TOKEN"
+	ReasonUserNotFound        = "USER_NOT_FOUND"
+	ReasonInvalidMfaChallenge = "INVALID_MFA_CHALLENGE"
+	ReasonInvalidMfaCode      = "INVALID_MFA_CODE"
+	ReasonMfaAlreadyEnabled   = "MFA_ALREADY_ENABLED"
+	ReasonMfaNotEnabled       = "MFA_NOT_ENABLED"
+	ReasonMfaNotEnrolled      = "MFA_ENROLLMENT_NOT_STARTED"
+	ReasonMfaLocked           = "MFA_LOCKED"
+	ReasonUnknownProvider     = "UNKNOWN_IDENTITY_PROVIDER"
+	ReasonProviderUnavailable = "IDENTITY_PROVIDER_UNAVAILABLE"
+	ReasonInvalidOidcState    = "INVALID_OIDC_STATE"
+	ReasonOidcLoginFailed     = "OIDC_LOGIN_FAILED"
+	ReasonEmailNotVerified    = "EMAIL_NOT_VERIFIED"
+	ReasonAddressNotFound     = "ADDRESS_NOT_FOUND"
+	ReasonSessionNotFound     = "SESSION_NOT_FOUND"
+)
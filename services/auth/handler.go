@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 
 	pb "github.com/my-store/pkg/api/auth"
+	"github.com/my-store/pkg/apierr"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -12,23 +15,29 @@ import (
 // AuthServer implements the generated AuthServiceServer interface.
 type AuthServer struct {
 	pb.UnimplementedAuthServiceServer
-	store *UserStore
+	store     *UserStore
+	policy    *PasswordPolicy
+	providers map[string]*OidcProvider
 }
 
 // NewAuthServer creates a new instance of our gRPC server.
-func NewAuthServer(store *UserStore) *AuthServer {
+func NewAuthServer(store *UserStore, policy *PasswordPolicy, providers map[string]*OidcProvider) *AuthServer {
 	return &AuthServer{
-		store: store,
+		store:     store,
+		policy:    policy,
+		providers: providers,
 	}
 }
 
 // Register handles user registration.
 func (s *AuthServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
-	if req.Email == "" || req.Password == "" {
-		return &pb.RegisterResponse{
-			Status: int32(codes.InvalidArgument),
-			Error:  "Email and password are required",
-		}, nil
+	var violations []*errdetails.BadRequest_FieldViolation
+	if req.Email == "" {
+		violations = append(violations, apierr.FieldViolation("email", CodeRequired, "Email is required"))
+	}
+	violations = append(violations, s.policy.Validate(req.Email, req.Password)...)
+	if len(violations) > 0 {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Registration details do not meet requirements", violations)
 	}
 
 	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -36,62 +45,76 @@ func (s *AuthServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb
 		return nil, status.Errorf(codes.Internal, "Failed to hash password")
 	}
 
-	_, err = s.store.Create(req.Email, string(hashedBytes))
+	_, err = s.store.Create(ctx, req.Email, string(hashedBytes))
+	if errors.Is(err, ErrEmailTaken) {
+		return nil, errDomain.Error(codes.AlreadyExists, ReasonEmailTaken, "User already exists")
+	}
 	if err != nil {
-		// In a real app, check for specific DB error code (e.g., unique constraint violation)
-		// For now, we assume most errors are duplicates or connection issues
-		return &pb.RegisterResponse{
-			Status: int32(codes.AlreadyExists),
-			Error:  "User already exists or database error",
-		}, nil
+		return nil, status.Errorf(codes.Internal, "Failed to create user")
 	}
+	registrations.WithLabelValues(methodPassword).Inc()
 
-	return &pb.RegisterResponse{
-		Status: int32(codes.OK),
-	}, nil
+	return &pb.RegisterResponse{}, nil
 }
 
 // Login handles user authentication.
 func (s *AuthServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
-	user, err := s.store.FindByEmail(req.Email)
+	// Unknown users and wrong passwords get the same error so Login can't be used
+	// to find out which emails are registered.
+	user, err := s.store.FindByEmail(ctx, req.Email)
 	if err != nil {
-		return &pb.LoginResponse{
-			Status: int32(codes.NotFound),
-			Error:  "User not found",
-		}, nil
+		loginFailures.WithLabelValues(methodPassword, ReasonInvalidCredentials).Inc()
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidCredentials, "Invalid credentials")
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
+		loginFailures.WithLabelValues(methodPassword, ReasonInvalidCredentials).Inc()
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidCredentials, "Invalid credentials")
+	}
+
+	return s.loginResponse(ctx, user, req.Client)
+}
+
+// loginResponse issues an access token, or an MFA challenge if the user has MFA enabled.
+func (s *AuthServer) loginResponse(ctx context.Context, user *User, client *pb.ClientInfo) (*pb.LoginResponse, error) {
+	if user.MfaEnabled {
+		challenge, err := GenerateMfaChallengeToken(user.ID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to generate challenge token")
+		}
 		return &pb.LoginResponse{
-			Status: int32(codes.Unauthenticated),
-			Error:  "Invalid credentials",
+			MfaRequired:    true,
+			ChallengeToken: challenge,
 		}, nil
 	}
 
-	token, err := GenerateToken(user.ID)
+	token, err := s.issueAccessToken(ctx, user, client)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to generate token")
 	}
 
 	return &pb.LoginResponse{
-		Status: int32(codes.OK),
-		Token:  token,
+		Token: token,
 	}, nil
 }
 
-// Validate checks if a token is valid.
+// Validate checks if a token is valid and its session has not been revoked.
 func (s *AuthServer) Validate(ctx context.Context, req *pb.ValidateRequest) (*pb.ValidateResponse, error) {
-	claims, err := ValidateToken(req.Token)
+	claims, err := s.authenticate(ctx, req.Token, req.Client)
 	if err != nil {
-		return &pb.ValidateResponse{
-			Status: int32(codes.Unauthenticated),
-			Error:  "Invalid token",
-		}, nil
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidToken, "Invalid token")
+	}
+
+	// Looked up on every call so granting or revoking admin takes effect immediately.
+	user, err := s.store.FindByID(ctx, claims.UserId)
+	if err != nil {
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidToken, "Invalid token")
 	}
 
 	return &pb.ValidateResponse{
-		Status: int32(codes.OK),
-		UserId: claims.UserId,
+		UserId:    claims.UserId,
+		SessionId: claims.SessionId,
+		IsAdmin:   user.IsAdmin,
 	}, nil
 }
@@ -11,19 +11,28 @@ import (
 // In a real application, this should come from environment variables.
 var jwtSecret = []byte("super-secret-key")
 
+// purposeMfa marks a short-lived token that only proves the password step of login.
+const purposeMfa = "mfa"
+
 // Claims defines the structure of the JWT payload.
 type Claims struct {
-	UserId int64 `json:"userId"`
+	UserId    int64  `json:"userId"`
+	SessionId string `json:"sid,omitempty"`     // Session the access token belongs to
+	Purpose   string `json:"purpose,omitempty"` // Empty for access tokens
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for a given user ID.
-func GenerateToken(userId int64) (string, error) {
+// accessTokenTTL is how long an access token (and its session) stays valid.
+const accessTokenTTL = 24 * time.Hour
+
+// GenerateToken creates a new JWT token for a given user and session.
+func GenerateToken(userId int64, sessionId string, expiresAt time.Time) (string, error) {
 	// Create the claims
 	claims := &Claims{
-		UserId: userId,
+		UserId:    userId,
+		SessionId: sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token valid for 24 hours
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -35,8 +44,48 @@ func GenerateToken(userId int64) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
-// ValidateToken parses and validates a JWT token.
+// GenerateMfaChallengeToken creates a token that can only be exchanged via VerifyMfa.
+func GenerateMfaChallengeToken(userId int64) (string, error) {
+	claims := &Claims{
+		UserId:  userId,
+		Purpose: purposeMfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// ValidateToken parses and validates an access token.
+// MFA challenge tokens are rejected so they can't be used to call protected APIs.
 func ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" || claims.SessionId == "" {
+		return nil, errors.New("not an access token")
+	}
+	return claims, nil
+}
+
+// ValidateMfaChallengeToken parses a token issued by GenerateMfaChallengeToken.
+func ValidateMfaChallengeToken(tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purposeMfa {
+		return nil, errors.New("not an mfa challenge token")
+	}
+	return claims, nil
+}
+
+// parseToken parses and validates a JWT token.
+func parseToken(tokenString string) (*Claims, error) {
 	// Parse the token
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		// Validate the signing method
@@ -1,85 +1,52 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
-	"log"
-	"net"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
 	pb "github.com/my-store/pkg/api/auth"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
+	"github.com/my-store/pkg/logging"
+	"github.com/my-store/pkg/server"
 )
 
 func main() {
-	// 1. Connect to Database
-	dbHost := os.Getenv("POSTGRES_HOST")
-	dbUser := os.Getenv("POSTGRES_USER")
-	dbPass := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DB")
+	logging.Setup("auth")
 
-	if dbHost == "" {
-		dbHost = "localhost" // Fallback for local dev outside docker
+	cfg, err := server.LoadConfig("auth", 50051)
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
 	}
+	srv := server.New(cfg)
 
-	// Connection string for pgx
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:5432/%s?sslmode=disable", dbUser, dbPass, dbHost, dbName)
-	
-	var db *sql.DB
-	var err error
-
-	// Retry logic for DB connection (wait for Postgres to start)
-	for i := 0; i < 10; i++ {
-		db, err = sql.Open("pgx", dsn)
-		if err == nil {
-			err = db.Ping()
-			if err == nil {
-				log.Println("Connected to database")
-				break
-			}
-		}
-		log.Printf("Waiting for database... (%d/10)", i+1)
-		time.Sleep(2 * time.Second)
-	}
+	// 1. Connect to Database
+	db, err := srv.OpenDB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logging.Fatal("Failed to connect to database", "error", err)
 	}
-	defer db.Close()
 
 	// 2. Initialize Store & Schema
 	store := NewUserStore(db)
 	if err := store.InitSchema(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+		logging.Fatal("Failed to run migrations", "error", err)
 	}
 
-	// 3. Start gRPC Server
-	port := 50051
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	// 3. Load Password Policy
+	checker, err := NewBreachedChecker()
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logging.Fatal("Failed to load breached password list", "error", err)
+	}
+	policy, err := LoadPasswordPolicy(checker)
+	if err != nil {
+		logging.Fatal("Invalid password policy", "error", err)
 	}
-	
-	s := grpc.NewServer()
-	authServer := NewAuthServer(store)
-	pb.RegisterAuthServiceServer(s, authServer)
-	reflection.Register(s)
 
-	log.Printf("Auth Service listening on port %d", port)
+	providers, err := LoadOidcProviders()
+	if err != nil {
+		logging.Fatal("Invalid OIDC configuration", "error", err)
+	}
 
-	go func() {
-		if err := s.Serve(lis); err != nil {
-			log.Fatalf("failed to serve: %v", err)
-		}
-	}()
+	// 4. Register Handlers
+	pb.RegisterAuthServiceServer(srv, NewAuthServer(store, policy, providers))
 
-	// 4. Graceful Shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
-	s.GracefulStop()
+	// 5. Serve until SIGINT/SIGTERM, then drain in-flight calls
+	if err := srv.Run(); err != nil {
+		logging.Fatal("Auth service failed", "error", err)
+	}
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	registrations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_registrations_total",
+		Help: "Accounts created, by sign-up method (password or the identity provider).",
+	}, []string{"method"})
+
+	loginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_login_failures_total",
+		Help: "Rejected login attempts, by login method and error reason.",
+	}, []string{"method", "reason"})
+)
+
+// Login methods used as metric labels.
+const (
+	methodPassword = "password"
+	methodMfa      = "mfa"
+	methodOidc     = "oidc"
+)
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	pb "github.com/my-store/pkg/api/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// mfaMaxFailures is how many wrong codes in a row lock MFA verification.
+	mfaMaxFailures = 5
+	// mfaLockout is how long MFA verification stays locked.
+	mfaLockout = 15 * time.Minute
+)
+
+// VerifyMfa exchanges a login challenge token and a second factor for an access token.
+// Wrong codes are counted per user, across challenges: after mfaMaxFailures in a
+// row, verification is locked for mfaLockout and the challenges issued so far
+// are void, so codes can't be guessed by retrying or by logging in again.
+func (s *AuthServer) VerifyMfa(ctx context.Context, req *pb.VerifyMfaRequest) (*pb.VerifyMfaResponse, error) {
+	claims, err := ValidateMfaChallengeToken(req.ChallengeToken)
+	if err != nil {
+		loginFailures.WithLabelValues(methodMfa, ReasonInvalidMfaChallenge).Inc()
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidMfaChallenge, "Invalid or expired challenge")
+	}
+
+	user, err := s.store.FindByID(ctx, claims.UserId)
+	if err != nil || !user.MfaEnabled {
+		loginFailures.WithLabelValues(methodMfa, ReasonInvalidMfaChallenge).Inc()
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidMfaChallenge, "Invalid or expired challenge")
+	}
+
+	if wait := time.Until(user.MfaLockedUntil); wait > 0 {
+		loginFailures.WithLabelValues(methodMfa, ReasonMfaLocked).Inc()
+		return nil, mfaLockedError(wait)
+	}
+	// Issue times are in whole seconds.
+	if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(user.MfaLockedUntil.Truncate(time.Second)) {
+		loginFailures.WithLabelValues(methodMfa, ReasonInvalidMfaChallenge).Inc()
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidMfaChallenge, "Invalid or expired challenge")
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, req.Code)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to verify code")
+	}
+	if !ok {
+		lockedUntil, err := s.store.RecordMfaFailure(ctx, user.ID, mfaMaxFailures, time.Now().Add(mfaLockout))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to verify code")
+		}
+		if !lockedUntil.IsZero() {
+			slog.WarnContext(ctx, "Locked MFA verification after too many wrong codes", "user_id", user.ID, "until", lockedUntil)
+			loginFailures.WithLabelValues(methodMfa, ReasonMfaLocked).Inc()
+			return nil, mfaLockedError(time.Until(lockedUntil))
+		}
+		loginFailures.WithLabelValues(methodMfa, ReasonInvalidMfaCode).Inc()
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidMfaCode, "Invalid code")
+	}
+	if err := s.store.ResetMfaFailures(ctx, user.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to verify code")
+	}
+
+	token, err := s.issueAccessToken(ctx, user, req.Client)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate token")
+	}
+
+	return &pb.VerifyMfaResponse{
+		Token: token,
+	}, nil
+}
+
+// mfaLockedError tells the caller to sign in again once verification unlocks.
+func mfaLockedError(wait time.Duration) error {
+	return errDomain.ResourceExhausted(ReasonMfaLocked, "Too many invalid codes; sign in again later", wait)
+}
+
+// EnrollMfa starts TOTP enrollment by generating a secret for the user to scan.
+// MFA stays disabled until the user proves they can produce codes via ConfirmMfa.
+func (s *AuthServer) EnrollMfa(ctx context.Context, req *pb.EnrollMfaRequest) (*pb.EnrollMfaResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if user.MfaEnabled {
+		return nil, errDomain.Error(codes.FailedPrecondition, ReasonMfaAlreadyEnabled, "MFA is already enabled")
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate secret")
+	}
+	if err := s.store.SetPendingMfaSecret(ctx, user.ID, secret); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to store secret")
+	}
+
+	uri := TOTPURI(secret, user.Email)
+	qr, err := TOTPQRCode(uri)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to render QR code")
+	}
+
+	return &pb.EnrollMfaResponse{
+		Secret:     secret,
+		OtpauthUri: uri,
+		QrPng:      qr,
+	}, nil
+}
+
+// ConfirmMfa verifies the first TOTP code, enables MFA and issues recovery codes.
+func (s *AuthServer) ConfirmMfa(ctx context.Context, req *pb.ConfirmMfaRequest) (*pb.ConfirmMfaResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if user.MfaEnabled {
+		return nil, errDomain.Error(codes.FailedPrecondition, ReasonMfaAlreadyEnabled, "MFA is already enabled")
+	}
+	if user.MfaSecret == "" {
+		return nil, errDomain.Error(codes.FailedPrecondition, ReasonMfaNotEnrolled, "MFA enrollment has not been started")
+	}
+
+	step, ok := VerifyTOTP(user.MfaSecret, req.Code, time.Now())
+	if !ok {
+		return nil, errDomain.Error(codes.InvalidArgument, ReasonInvalidMfaCode, "Invalid code")
+	}
+
+	recoveryCodes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate recovery codes")
+	}
+	hashes := make([]string, len(recoveryCodes))
+	for i, c := range recoveryCodes {
+		hashes[i] = HashRecoveryCode(c)
+	}
+
+	if err := s.store.EnableMfa(ctx, user.ID, step, hashes); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to enable MFA")
+	}
+
+	return &pb.ConfirmMfaResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// DisableMfa turns MFA off after checking a current TOTP or recovery code.
+func (s *AuthServer) DisableMfa(ctx context.Context, req *pb.DisableMfaRequest) (*pb.DisableMfaResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MfaEnabled {
+		return nil, errDomain.Error(codes.FailedPrecondition, ReasonMfaNotEnabled, "MFA is not enabled")
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, req.Code)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to verify code")
+	}
+	if !ok {
+		return nil, errDomain.Error(codes.InvalidArgument, ReasonInvalidMfaCode, "Invalid code")
+	}
+
+	if err := s.store.DisableMfa(ctx, user.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to disable MFA")
+	}
+
+	return &pb.DisableMfaResponse{}, nil
+}
+
+// userFromToken resolves the caller of an account management RPC from their access token.
+// On failure it returns the status error to send back.
+func (s *AuthServer) userFromToken(ctx context.Context, token string) (*User, error) {
+	claims, err := s.authenticate(ctx, token, nil)
+	if err != nil {
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidToken, "Invalid token")
+	}
+	user, err := s.store.FindByID(ctx, claims.UserId)
+	if err != nil {
+		return nil, errDomain.Error(codes.NotFound, ReasonUserNotFound, "User not found")
+	}
+	return user, nil
+}
+
+// verifySecondFactor accepts either a fresh TOTP code or an unused recovery code.
+func (s *AuthServer) verifySecondFactor(ctx context.Context, user *User, code string) (bool, error) {
+	if step, ok := VerifyTOTP(user.MfaSecret, code, time.Now()); ok {
+		return s.store.AdvanceMfaStep(ctx, user.ID, step)
+	}
+
+	used, err := s.store.UseRecoveryCode(ctx, user.ID, HashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	if used {
+		slog.InfoContext(ctx, "User signed in with a recovery code", "user_id", user.ID)
+	}
+	return used, nil
+}
@@ -0,0 +1,136 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// testDB returns a connection to a schema of its own in the database at
+// TEST_DATABASE_URL, dropped when the test ends.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Fatal("TEST_DATABASE_URL is not set; integration tests need Postgres")
+	}
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	cfg.RuntimeParams["search_path"] = schema
+	db := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// mfaUser returns a store and a user with MFA enabled at step 100.
+func mfaUser(t *testing.T) (*UserStore, *User) {
+	t.Helper()
+	ctx := context.Background()
+	store := NewUserStore(testDB(t))
+	if err := store.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.Create(ctx, "ada@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetPendingMfaSecret(ctx, user.ID, rfcSecret); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnableMfa(ctx, user.ID, 100, []string{HashRecoveryCode("abcde-fghij")}); err != nil {
+		t.Fatal(err)
+	}
+	return store, user
+}
+
+func TestAdvanceMfaStepRejectsReplays(t *testing.T) {
+	store, user := mfaUser(t)
+	tests := []struct {
+		name string
+		step int64
+		want bool
+	}{
+		{"step of the confirmation code", 100, false},
+		{"next step", 101, true},
+		{"same code again", 101, false},
+		{"earlier code still in the window", 100, false},
+		{"later step", 103, true},
+	}
+	for _, tt := range tests {
+		got, err := store.AdvanceMfaStep(context.Background(), user.ID, tt.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: AdvanceMfaStep(%d) = %v, want %v", tt.name, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestMfaLockout(t *testing.T) {
+	ctx := context.Background()
+	store, user := mfaUser(t)
+	lockUntil := time.Now().Add(mfaLockout).Truncate(time.Microsecond)
+
+	for i := 1; i <= mfaMaxFailures+1; i++ {
+		got, err := store.RecordMfaFailure(ctx, user.ID, mfaMaxFailures, lockUntil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The count starts over once it locks.
+		if wantLock := i == mfaMaxFailures; got.IsZero() == wantLock {
+			t.Errorf("failure %d: locked until %v, want locked: %v", i, got, wantLock)
+		}
+	}
+	locked, err := store.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locked.MfaLockedUntil.Equal(lockUntil) {
+		t.Errorf("locked until %v, want %v", locked.MfaLockedUntil, lockUntil)
+	}
+
+	// A right code forgets the failures before it.
+	if err := store.ResetMfaFailures(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < mfaMaxFailures; i++ {
+		if got, err := store.RecordMfaFailure(ctx, user.ID, mfaMaxFailures, lockUntil); err != nil || !got.IsZero() {
+			t.Fatalf("failure %d after a reset: locked until %v, %v", i, got, err)
+		}
+	}
+
+	// Turning MFA off clears the lock and the count, so it can be turned back on.
+	if err := store.DisableMfa(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	disabled, err := store.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !disabled.MfaLockedUntil.IsZero() {
+		t.Errorf("locked until %v after disabling MFA", disabled.MfaLockedUntil)
+	}
+	if got, err := store.RecordMfaFailure(ctx, user.ID, mfaMaxFailures, lockUntil); err != nil || !got.IsZero() {
+		t.Errorf("first failure after disabling MFA: locked until %v, %v", got, err)
+	}
+}
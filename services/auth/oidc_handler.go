@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	pb "github.com/my-store/pkg/api/auth"
+	"github.com/my-store/pkg/apierr"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// oidcStateTTL bounds how long a user may take at the identity provider.
+const oidcStateTTL = 10 * time.Minute
+
+// StartOidcLogin creates the state, nonce and PKCE verifier for a new login
+// and returns the provider's authorization URL.
+func (s *AuthServer) StartOidcLogin(ctx context.Context, req *pb.StartOidcLoginRequest) (*pb.StartOidcLoginResponse, error) {
+	provider, ok := s.providers[req.Provider]
+	if !ok {
+		return nil, errDomain.Error(codes.NotFound, ReasonUnknownProvider, "Unknown identity provider")
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate state")
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate nonce")
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		slog.WarnContext(ctx, "OIDC provider unavailable", "provider", provider.Name, "error", err)
+		return nil, errDomain.Error(codes.Unavailable, ReasonProviderUnavailable, "Identity provider is unavailable")
+	}
+
+	loginState := OidcLoginState{Nonce: nonce, CodeVerifier: verifier}
+	if err := s.store.SaveOidcState(ctx, state, provider.Name, loginState, time.Now().Add(oidcStateTTL)); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to store login state")
+	}
+
+	return &pb.StartOidcLoginResponse{
+		AuthorizationUrl: authURL,
+		State:            state,
+	}, nil
+}
+
+// CompleteOidcLogin handles the provider callback: it validates the state,
+// exchanges the code, verifies the ID token and signs the linked user in.
+func (s *AuthServer) CompleteOidcLogin(ctx context.Context, req *pb.CompleteOidcLoginRequest) (*pb.LoginResponse, error) {
+	provider, ok := s.providers[req.Provider]
+	if !ok {
+		return nil, errDomain.Error(codes.NotFound, ReasonUnknownProvider, "Unknown identity provider")
+	}
+
+	loginState, err := s.store.ConsumeOidcState(ctx, req.State, provider.Name)
+	if err != nil {
+		loginFailures.WithLabelValues(methodOidc, ReasonInvalidOidcState).Inc()
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidOidcState, "Invalid or expired login state")
+	}
+
+	identity, err := provider.Exchange(ctx, req.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		slog.WarnContext(ctx, "OIDC login failed", "provider", provider.Name, "error", err)
+		loginFailures.WithLabelValues(methodOidc, ReasonOidcLoginFailed).Inc()
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonOidcLoginFailed, "Identity provider login failed")
+	}
+
+	user, err := s.resolveExternalUser(ctx, provider.Name, identity)
+	if err != nil {
+		if reason := apierr.Reason(err); reason != "" {
+			loginFailures.WithLabelValues(methodOidc, reason).Inc()
+		}
+		return nil, err
+	}
+
+	return s.loginResponse(ctx, user, req.Client)
+}
+
+// resolveExternalUser finds the user for an external identity. Unknown identities are
+// linked to an existing account only when the provider has verified the email;
+// otherwise a new account is created.
+func (s *AuthServer) resolveExternalUser(ctx context.Context, provider string, identity *ExternalIdentity) (*User, error) {
+	if user, err := s.store.FindByIdentity(ctx, provider, identity.Subject); err == nil {
+		return user, nil
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errDomain.Error(codes.PermissionDenied, ReasonEmailNotVerified, "Identity provider did not supply a verified email")
+	}
+
+	if user, err := s.store.FindByEmail(ctx, identity.Email); err == nil {
+		if err := s.store.LinkIdentity(ctx, user.ID, provider, identity.Subject, identity.Email); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to link identity")
+		}
+		return user, nil
+	}
+
+	user, err := s.store.CreateWithIdentity(ctx, identity.Email, provider, identity.Subject)
+	if errors.Is(err, ErrEmailTaken) {
+		return nil, errDomain.Error(codes.AlreadyExists, ReasonEmailTaken, "User already exists")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to create user")
+	}
+	registrations.WithLabelValues(provider).Inc()
+	return user, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/my-store/pkg/apierr"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// Field violation reasons returned to clients so the frontend can localise messages.
+const (
+	CodeRequired      = "required"
+	CodeInvalid       = "invalid"
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeMissingUpper  = "missing_uppercase"
+	CodeMissingLower  = "missing_lowercase"
+	CodeMissingDigit  = "missing_digit"
+	CodeMissingSymbol = "missing_symbol"
+	CodeContainsEmail = "contains_email"
+	CodeBreached      = "breached"
+)
+
+// PasswordPolicy defines the rules a password must satisfy on registration.
+type PasswordPolicy struct {
+	MinLength       int
+	MaxLength       int
+	RequireUpper    bool
+	RequireLower    bool
+	RequireDigit    bool
+	RequireSymbol   bool
+	DisallowEmail   bool
+	CheckBreached   bool
+	breachedChecker *BreachedChecker
+}
+
+// DefaultPasswordPolicy returns the policy used when no overrides are configured.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:     10,
+		MaxLength:     72, // bcrypt ignores everything past 72 bytes
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: false,
+		DisallowEmail: true,
+		CheckBreached: true,
+	}
+}
+
+// LoadPasswordPolicy builds the policy from environment variables, falling back to defaults.
+func LoadPasswordPolicy(checker *BreachedChecker) (*PasswordPolicy, error) {
+	p := DefaultPasswordPolicy()
+	p.breachedChecker = checker
+
+	var err error
+	if p.MinLength, err = envInt("PASSWORD_MIN_LENGTH", p.MinLength); err != nil {
+		return nil, err
+	}
+	if p.MaxLength, err = envInt("PASSWORD_MAX_LENGTH", p.MaxLength); err != nil {
+		return nil, err
+	}
+	if p.RequireUpper, err = envBool("PASSWORD_REQUIRE_UPPER", p.RequireUpper); err != nil {
+		return nil, err
+	}
+	if p.RequireLower, err = envBool("PASSWORD_REQUIRE_LOWER", p.RequireLower); err != nil {
+		return nil, err
+	}
+	if p.RequireDigit, err = envBool("PASSWORD_REQUIRE_DIGIT", p.RequireDigit); err != nil {
+		return nil, err
+	}
+	if p.RequireSymbol, err = envBool("PASSWORD_REQUIRE_SYMBOL", p.RequireSymbol); err != nil {
+		return nil, err
+	}
+	if p.DisallowEmail, err = envBool("PASSWORD_DISALLOW_EMAIL", p.DisallowEmail); err != nil {
+		return nil, err
+	}
+	if p.CheckBreached, err = envBool("PASSWORD_CHECK_BREACHED", p.CheckBreached); err != nil {
+		return nil, err
+	}
+
+	if p.MinLength < 1 || p.MaxLength < p.MinLength {
+		return nil, fmt.Errorf("invalid password length bounds: min=%d max=%d", p.MinLength, p.MaxLength)
+	}
+	return p, nil
+}
+
+// Validate checks a password against the policy and returns one error per violated rule.
+func (p *PasswordPolicy) Validate(email, password string) []*errdetails.BadRequest_FieldViolation {
+	if password == "" {
+		return []*errdetails.BadRequest_FieldViolation{passwordError(CodeRequired, "Password is required")}
+	}
+
+	var errs []*errdetails.BadRequest_FieldViolation
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		errs = append(errs, passwordError(CodeTooShort, fmt.Sprintf("Password must be at least %d characters", p.MinLength)))
+	}
+	if len(password) > p.MaxLength {
+		errs = append(errs, passwordError(CodeTooLong, fmt.Sprintf("Password must be at most %d bytes", p.MaxLength)))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		errs = append(errs, passwordError(CodeMissingUpper, "Password must contain an uppercase letter"))
+	}
+	if p.RequireLower && !hasLower {
+		errs = append(errs, passwordError(CodeMissingLower, "Password must contain a lowercase letter"))
+	}
+	if p.RequireDigit && !hasDigit {
+		errs = append(errs, passwordError(CodeMissingDigit, "Password must contain a digit"))
+	}
+	if p.RequireSymbol && !hasSymbol {
+		errs = append(errs, passwordError(CodeMissingSymbol, "Password must contain a symbol"))
+	}
+
+	if p.DisallowEmail && containsEmail(email, password) {
+		errs = append(errs, passwordError(CodeContainsEmail, "Password must not contain your email address"))
+	}
+
+	if p.CheckBreached && p.breachedChecker != nil && p.breachedChecker.IsBreached(password) {
+		errs = append(errs, passwordError(CodeBreached, "This password has appeared in a data breach, please choose another"))
+	}
+
+	return errs
+}
+
+// containsEmail reports whether the password matches the email or its local part.
+func containsEmail(email, password string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	pw := strings.ToLower(password)
+	if strings.Contains(pw, email) {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	// Very short local parts ("a@x.io") would reject too many passwords.
+	return len(local) >= 3 && strings.Contains(pw, local)
+}
+
+func passwordError(code, message string) *errdetails.BadRequest_FieldViolation {
+	return apierr.FieldViolation("password", code, message)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+func envBool(key string, fallback bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return b, nil
+}
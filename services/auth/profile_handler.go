@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	pb "github.com/my-store/pkg/api/auth"
+	"github.com/my-store/pkg/apierr"
+	"github.com/my-store/pkg/postal"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxNameLength = 100
+
+// GetProfile returns the caller's profile.
+func (s *AuthServer) GetProfile(ctx context.Context, req *pb.GetProfileRequest) (*pb.ProfileResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ProfileResponse{
+		Profile: profileProto(user),
+	}, nil
+}
+
+// UpdateProfile changes the caller's name and phone number.
+func (s *AuthServer) UpdateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.ProfileResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	phone := strings.TrimSpace(req.Phone)
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	if len([]rune(name)) > maxNameLength {
+		violations = append(violations, apierr.FieldViolation("name", CodeTooLong, "Name is too long"))
+	}
+	if phone != "" && !postal.PhonePattern.MatchString(phone) {
+		violations = append(violations, apierr.FieldViolation("phone", CodeInvalid, "Phone number is invalid"))
+	}
+	if len(violations) > 0 {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Profile details are invalid", violations)
+	}
+
+	if err := s.store.UpdateProfile(ctx, user.ID, name, phone); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to update profile")
+	}
+	user.Name, user.Phone = name, phone
+
+	return &pb.ProfileResponse{
+		Profile: profileProto(user),
+	}, nil
+}
+
+// ListAddresses returns the caller's address book.
+func (s *AuthServer) ListAddresses(ctx context.Context, req *pb.ListAddressesRequest) (*pb.ListAddressesResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := s.store.ListAddresses(ctx, user.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to list addresses")
+	}
+
+	resp := &pb.ListAddressesResponse{}
+	for _, a := range addresses {
+		resp.Addresses = append(resp.Addresses, savedAddressProto(a))
+	}
+	return resp, nil
+}
+
+// GetAddress returns one of the caller's addresses, or their default when no ID is given.
+func (s *AuthServer) GetAddress(ctx context.Context, req *pb.GetAddressRequest) (*pb.AddressResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	var address *Address
+	if req.AddressId == 0 {
+		address, err = s.store.GetDefaultAddress(ctx, user.ID)
+	} else {
+		address, err = s.store.GetAddress(ctx, user.ID, req.AddressId)
+	}
+	return addressResponse(address, err)
+}
+
+// CreateAddress adds an address to the caller's address book.
+func (s *AuthServer) CreateAddress(ctx context.Context, req *pb.CreateAddressRequest) (*pb.AddressResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := postal.Validate("address", req.Address); len(violations) > 0 {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Address is invalid", violations)
+	}
+	address := addressFromProto(user.ID, strings.TrimSpace(req.Label), req.Address)
+
+	return addressResponse(s.store.CreateAddress(ctx, address, req.MakeDefault))
+}
+
+// UpdateAddress replaces the contents of one of the caller's addresses.
+func (s *AuthServer) UpdateAddress(ctx context.Context, req *pb.UpdateAddressRequest) (*pb.AddressResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := postal.Validate("address", req.Address); len(violations) > 0 {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Address is invalid", violations)
+	}
+	address := addressFromProto(user.ID, strings.TrimSpace(req.Label), req.Address)
+	address.ID = req.AddressId
+
+	return addressResponse(s.store.UpdateAddress(ctx, address))
+}
+
+// DeleteAddress removes one of the caller's addresses.
+func (s *AuthServer) DeleteAddress(ctx context.Context, req *pb.DeleteAddressRequest) (*pb.DeleteAddressResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.DeleteAddress(ctx, user.ID, req.AddressId)
+	if errors.Is(err, ErrAddressNotFound) {
+		return nil, errDomain.Error(codes.NotFound, ReasonAddressNotFound, "Address not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete address")
+	}
+
+	return &pb.DeleteAddressResponse{}, nil
+}
+
+// SetDefaultAddress marks one of the caller's addresses as the default for shipping.
+func (s *AuthServer) SetDefaultAddress(ctx context.Context, req *pb.SetDefaultAddressRequest) (*pb.AddressResponse, error) {
+	user, err := s.userFromToken(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return addressResponse(s.store.SetDefaultAddress(ctx, user.ID, req.AddressId))
+}
+
+// addressResponse maps a store result onto the RPC response.
+func addressResponse(address *Address, err error) (*pb.AddressResponse, error) {
+	if errors.Is(err, ErrAddressNotFound) {
+		return nil, errDomain.Error(codes.NotFound, ReasonAddressNotFound, "Address not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Address operation failed")
+	}
+
+	return &pb.AddressResponse{
+		Address: savedAddressProto(address),
+	}, nil
+}
+
+func profileProto(u *User) *pb.Profile {
+	return &pb.Profile{
+		UserId: u.ID,
+		Email:  u.Email,
+		Name:   u.Name,
+		Phone:  u.Phone,
+	}
+}
+
+func savedAddressProto(a *Address) *pb.SavedAddress {
+	return &pb.SavedAddress{
+		Id:        a.ID,
+		Label:     a.Label,
+		IsDefault: a.IsDefault,
+		Address:   a.Proto(),
+	}
+}
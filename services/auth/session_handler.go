@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	pb "github.com/my-store/pkg/api/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ListSessions returns the caller's active sessions.
+func (s *AuthServer) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	claims, err := s.authenticate(ctx, req.Token, nil)
+	if err != nil {
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidToken, "Invalid token")
+	}
+
+	sessions, err := s.store.ListSessions(ctx, claims.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to list sessions")
+	}
+
+	resp := &pb.ListSessionsResponse{}
+	for _, sess := range sessions {
+		resp.Sessions = append(resp.Sessions, &pb.Session{
+			Id:         sess.ID,
+			Device:     sess.Device,
+			UserAgent:  sess.UserAgent,
+			Ip:         sess.IP,
+			CreatedAt:  timestamppb.New(sess.CreatedAt),
+			LastSeenAt: timestamppb.New(sess.LastSeenAt),
+			Current:    sess.ID == claims.SessionId,
+		})
+	}
+	return resp, nil
+}
+
+// RevokeSession signs one of the caller's sessions out. Revoking the current session logs out.
+func (s *AuthServer) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionResponse, error) {
+	claims, err := s.authenticate(ctx, req.Token, nil)
+	if err != nil {
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidToken, "Invalid token")
+	}
+
+	err = s.store.RevokeSession(ctx, claims.UserId, req.SessionId)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil, errDomain.Error(codes.NotFound, ReasonSessionNotFound, "Session not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to revoke session")
+	}
+
+	return &pb.RevokeSessionResponse{}, nil
+}
+
+// RevokeAllOtherSessions signs the caller out everywhere except the current session.
+func (s *AuthServer) RevokeAllOtherSessions(ctx context.Context, req *pb.RevokeAllOtherSessionsRequest) (*pb.RevokeAllOtherSessionsResponse, error) {
+	claims, err := s.authenticate(ctx, req.Token, nil)
+	if err != nil {
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonInvalidToken, "Invalid token")
+	}
+
+	n, err := s.store.RevokeOtherSessions(ctx, claims.UserId, claims.SessionId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to revoke sessions")
+	}
+
+	return &pb.RevokeAllOtherSessionsResponse{
+		RevokedCount: int32(n),
+	}, nil
+}
+
+// issueAccessToken starts a new session for the user and returns its access token.
+func (s *AuthServer) issueAccessToken(ctx context.Context, user *User, client *pb.ClientInfo) (string, error) {
+	sess := &Session{
+		UserID:    user.ID,
+		UserAgent: client.GetUserAgent(),
+		IP:        client.GetIp(),
+		Device:    client.GetDevice(),
+		ExpiresAt: time.Now().Add(accessTokenTTL),
+	}
+	if sess.Device == "" {
+		sess.Device = describeDevice(sess.UserAgent)
+	}
+
+	sess, err := s.store.CreateSession(ctx, sess)
+	if err != nil {
+		return "", err
+	}
+	return GenerateToken(user.ID, sess.ID, sess.ExpiresAt)
+}
+
+// authenticate validates an access token and checks that its session is still active.
+func (s *AuthServer) authenticate(ctx context.Context, token string, client *pb.ClientInfo) (*Claims, error) {
+	claims, err := ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := s.store.TouchSession(ctx, claims.SessionId, claims.UserId, client.GetIp())
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, errors.New("session revoked")
+	}
+	return claims, nil
+}
+
+// describeDevice derives a short label such as "Chrome on macOS" from a user agent.
+func describeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		// Order matters: Edge and Opera also advertise Chrome, and Chrome advertises Safari.
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	os := ""
+	for _, o := range []struct{ token, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, o.token) {
+			os = o.name
+			break
+		}
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " on " + os
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSessionNotFound is returned when a session doesn't exist or belongs to another user.
+var ErrSessionNotFound = errors.New("session not found")
+
+// lastSeenResolution limits how often last_seen_at is written, so validating
+// every request doesn't turn into a write per request.
+const lastSeenResolution = time.Minute
+
+// Session is a signed-in device. Every access token carries the ID of its session.
+type Session struct {
+	ID         string
+	UserID     int64
+	Device     string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// CreateSession records a new session for a successful login.
+func (s *UserStore) CreateSession(ctx context.Context, sess *Session) (*Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	sess.ID = hex.EncodeToString(id)
+
+	query := `
+		INSERT INTO sessions (id, user_id, device, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, last_seen_at`
+	err := s.db.QueryRowContext(ctx, query, sess.ID, sess.UserID, sess.Device, sess.UserAgent, sess.IP, sess.ExpiresAt).
+		Scan(&sess.CreatedAt, &sess.LastSeenAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert session: %w", err)
+	}
+	return sess, nil
+}
+
+// ListSessions returns the user's active sessions, most recently used first.
+func (s *UserStore) ListSessions(ctx context.Context, userID int64) ([]*Session, error) {
+	query := `
+		SELECT id, user_id, device, user_agent, ip, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		var sess Session
+		if err := rows.Scan(&sess.ID, &sess.UserID, &sess.Device, &sess.UserAgent, &sess.IP,
+			&sess.CreatedAt, &sess.LastSeenAt, &sess.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &sess)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchSession reports whether the session is still active and, if so, refreshes
+// its last seen time and IP (at most once per lastSeenResolution).
+func (s *UserStore) TouchSession(ctx context.Context, sessionID string, userID int64, ip string) (bool, error) {
+	query := `
+		SELECT revoked_at IS NULL AND expires_at > NOW(), last_seen_at < $3
+		FROM sessions WHERE id = $1 AND user_id = $2`
+
+	var active, stale bool
+	err := s.db.QueryRowContext(ctx, query, sessionID, userID, time.Now().Add(-lastSeenResolution)).Scan(&active, &stale)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if active && stale {
+		update := `UPDATE sessions SET last_seen_at = NOW(), ip = COALESCE(NULLIF($2, ''), ip) WHERE id = $1`
+		if _, err := s.db.ExecContext(ctx, update, sessionID, ip); err != nil {
+			return false, fmt.Errorf("failed to update session: %w", err)
+		}
+	}
+	return active, nil
+}
+
+// RevokeSession revokes one of the user's sessions.
+func (s *UserStore) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	res, err := s.db.ExecContext(ctx, query, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions revokes every active session of the user except keepID.
+func (s *UserStore) RevokeOtherSessions(ctx context.Context, userID int64, keepID string) (int64, error) {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+	res, err := s.db.ExecContext(ctx, query, userID, keepID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return res.RowsAffected()
+}
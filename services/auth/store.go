@@ -1,18 +1,36 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib" // Register pgx driver for database/sql
 )
 
+// ErrEmailTaken is returned by Create when another user already has the email.
+var ErrEmailTaken = errors.New("email already registered")
+
+// uniqueViolation is the Postgres error code for a unique constraint violation.
+const uniqueViolation = "23505"
+
 // User represents a user in our system.
 type User struct {
-	ID       int64
-	Email    string
-	Password string // Hashed password
+	ID          int64
+	Email       string
+	Password    string // Hashed password
+	MfaSecret   string // Base32 TOTP secret, set once enrollment starts
+	MfaEnabled  bool
+	MfaLastStep int64 // Last accepted TOTP time step, used to reject replays
+	// MfaLockedUntil is when MFA verification unlocks after too many wrong codes.
+	// Challenges issued before it are void.
+	MfaLockedUntil time.Time
+	Name           string
+	Phone          string
+	IsAdmin        bool // Granted directly in the database; there is no API for it
 }
 
 // UserStore handles database interactions for users.
@@ -25,28 +43,96 @@ func NewUserStore(db *sql.DB) *UserStore {
 	return &UserStore{db: db}
 }
 
-// InitSchema creates the users table if it doesn't exist.
+// InitSchema creates the users and MFA tables if they don't exist.
 func (s *UserStore) InitSchema() error {
 	query := `
 	CREATE TABLE IF NOT EXISTS users (
 		id SERIAL PRIMARY KEY,
 		email TEXT UNIQUE NOT NULL,
 		password TEXT NOT NULL
+	);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_secret TEXT;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_last_step BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_failed_attempts INT NOT NULL DEFAULT 0;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_locked_until TIMESTAMPTZ;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS name TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS phone TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+	CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+		id SERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash TEXT NOT NULL,
+		used_at TIMESTAMPTZ,
+		UNIQUE (user_id, code_hash)
+	);
+
+	CREATE TABLE IF NOT EXISTS user_identities (
+		id SERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		provider TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		email TEXT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (provider, subject)
+	);
+
+	CREATE TABLE IF NOT EXISTS addresses (
+		id SERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		label TEXT NOT NULL DEFAULT '',
+		recipient_name TEXT NOT NULL,
+		line1 TEXT NOT NULL,
+		line2 TEXT NOT NULL DEFAULT '',
+		city TEXT NOT NULL,
+		region TEXT NOT NULL DEFAULT '',
+		postal_code TEXT NOT NULL,
+		country_code TEXT NOT NULL,
+		phone TEXT NOT NULL DEFAULT '',
+		is_default BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS addresses_one_default ON addresses (user_id) WHERE is_default;
+
+	CREATE TABLE IF NOT EXISTS sessions (
+		id TEXT PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		device TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMPTZ NOT NULL,
+		revoked_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS sessions_user_id ON sessions (user_id);
+
+	CREATE TABLE IF NOT EXISTS oidc_login_states (
+		state TEXT PRIMARY KEY,
+		provider TEXT NOT NULL,
+		nonce TEXT NOT NULL,
+		code_verifier TEXT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL
 	);`
 	_, err := s.db.Exec(query)
 	return err
 }
 
 // Create adds a new user to the database.
-func (s *UserStore) Create(email, hashedPassword string) (*User, error) {
+func (s *UserStore) Create(ctx context.Context, email, hashedPassword string) (*User, error) {
 	query := `
 		INSERT INTO users (email, password) 
 		VALUES ($1, $2) 
 		RETURNING id`
 
 	var id int64
-	err := s.db.QueryRow(query, email, hashedPassword).Scan(&id)
+	err := s.db.QueryRowContext(ctx, query, email, hashedPassword).Scan(&id)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, ErrEmailTaken
+		}
 		return nil, fmt.Errorf("failed to insert user: %w", err)
 	}
 
@@ -57,17 +143,236 @@ func (s *UserStore) Create(email, hashedPassword string) (*User, error) {
 	}, nil
 }
 
-// FindByEmail retrieves a user by email from the database.
-func (s *UserStore) FindByEmail(email string) (*User, error) {
-	query := `SELECT id, email, password FROM users WHERE email = $1`
+const userColumns = `id, email, password, COALESCE(mfa_secret, ''), mfa_enabled, mfa_last_step, mfa_locked_until, name, phone, is_admin`
 
+func scanUser(row *sql.Row) (*User, error) {
 	var user User
-	err := s.db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Password)
+	var lockedUntil sql.NullTime
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.MfaSecret, &user.MfaEnabled, &user.MfaLastStep, &lockedUntil, &user.Name, &user.Phone, &user.IsAdmin)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("user not found")
 		}
 		return nil, err
 	}
+	user.MfaLockedUntil = lockedUntil.Time
 	return &user, nil
 }
+
+// FindByEmail retrieves a user by email from the database.
+func (s *UserStore) FindByEmail(ctx context.Context, email string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	return scanUser(s.db.QueryRowContext(ctx, query, email))
+}
+
+// FindByID retrieves a user by ID from the database.
+func (s *UserStore) FindByID(ctx context.Context, id int64) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return scanUser(s.db.QueryRowContext(ctx, query, id))
+}
+
+// UpdateProfile sets the user's display name and phone number.
+func (s *UserStore) UpdateProfile(ctx context.Context, userID int64, name, phone string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE users SET name = $2, phone = $3 WHERE id = $1`, userID, name, phone)
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	return nil
+}
+
+// SetPendingMfaSecret stores a new TOTP secret without enabling MFA.
+// It refuses to overwrite the secret of an account that already has MFA enabled.
+func (s *UserStore) SetPendingMfaSecret(ctx context.Context, userID int64, secret string) error {
+	query := `UPDATE users SET mfa_secret = $2, mfa_last_step = 0 WHERE id = $1 AND NOT mfa_enabled`
+	res, err := s.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to store mfa secret: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("mfa already enabled")
+	}
+	return nil
+}
+
+// EnableMfa turns MFA on and replaces the user's recovery codes in one transaction.
+func (s *UserStore) EnableMfa(ctx context.Context, userID, step int64, codeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET mfa_enabled = TRUE, mfa_last_step = $2 WHERE id = $1`, userID, step); err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DisableMfa clears the TOTP secret and any lockout, and deletes all recovery
+// codes.
+func (s *UserStore) DisableMfa(ctx context.Context, userID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET mfa_enabled = FALSE, mfa_secret = NULL, mfa_last_step = 0,
+			mfa_failed_attempts = 0, mfa_locked_until = NULL
+		WHERE id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	return tx.Commit()
+}
+
+// AdvanceMfaStep records a used TOTP step. It returns false if the step (or a later one)
+// was already used, so a code can't be replayed even by concurrent requests.
+func (s *UserStore) AdvanceMfaStep(ctx context.Context, userID, step int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET mfa_last_step = $2 WHERE id = $1 AND mfa_last_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// RecordMfaFailure counts a wrong MFA code. The maxFailures-th in a row locks MFA
+// verification until lockUntil and starts the count over; the returned time is
+// then lockUntil, otherwise zero. Concurrent failures are each counted.
+func (s *UserStore) RecordMfaFailure(ctx context.Context, userID int64, maxFailures int, lockUntil time.Time) (time.Time, error) {
+	query := `
+		UPDATE users SET
+			mfa_failed_attempts = CASE WHEN mfa_failed_attempts + 1 >= $2 THEN 0 ELSE mfa_failed_attempts + 1 END,
+			mfa_locked_until = CASE WHEN mfa_failed_attempts + 1 >= $2 THEN $3 ELSE mfa_locked_until END
+		WHERE id = $1
+		RETURNING mfa_failed_attempts = 0`
+	var locked bool
+	if err := s.db.QueryRowContext(ctx, query, userID, maxFailures, lockUntil).Scan(&locked); err != nil {
+		return time.Time{}, fmt.Errorf("failed to record mfa failure: %w", err)
+	}
+	if !locked {
+		return time.Time{}, nil
+	}
+	return lockUntil, nil
+}
+
+// ResetMfaFailures forgets the wrong MFA codes entered before a right one.
+func (s *UserStore) ResetMfaFailures(ctx context.Context, userID int64) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE users SET mfa_failed_attempts = 0 WHERE id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to reset mfa failures: %w", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode marks a matching unused recovery code as consumed.
+func (s *UserStore) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	res, err := s.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// OidcLoginState is the server-side half of an in-flight OIDC login.
+type OidcLoginState struct {
+	Nonce        string
+	CodeVerifier string
+}
+
+// SaveOidcState stores the nonce and PKCE verifier for a login attempt.
+// Expired rows are pruned opportunistically.
+func (s *UserStore) SaveOidcState(ctx context.Context, state, provider string, loginState OidcLoginState, expiresAt time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("failed to prune login states: %w", err)
+	}
+	query := `
+		INSERT INTO oidc_login_states (state, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+	_, err := s.db.ExecContext(ctx, query, state, provider, loginState.Nonce, loginState.CodeVerifier, expiresAt)
+	return err
+}
+
+// ConsumeOidcState deletes and returns an unexpired login state, so each state is single use.
+func (s *UserStore) ConsumeOidcState(ctx context.Context, state, provider string) (*OidcLoginState, error) {
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state = $1 AND provider = $2 AND expires_at > NOW()
+		RETURNING nonce, code_verifier`
+
+	var ls OidcLoginState
+	err := s.db.QueryRowContext(ctx, query, state, provider).Scan(&ls.Nonce, &ls.CodeVerifier)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("login state not found")
+		}
+		return nil, err
+	}
+	return &ls, nil
+}
+
+// FindByIdentity retrieves the user linked to an external identity.
+func (s *UserStore) FindByIdentity(ctx context.Context, provider, subject string) (*User, error) {
+	query := `
+		SELECT ` + userColumns + ` FROM users
+		WHERE id = (SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2)`
+	return scanUser(s.db.QueryRowContext(ctx, query, provider, subject))
+}
+
+// LinkIdentity attaches an external identity to an existing user.
+func (s *UserStore) LinkIdentity(ctx context.Context, userID int64, provider, subject, email string) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)`
+	if _, err := s.db.ExecContext(ctx, query, userID, provider, subject, email); err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+// CreateWithIdentity creates a user that signs in only through an external provider.
+// The stored password is not a valid bcrypt hash, so password login always fails.
+// It returns ErrEmailTaken if a user already has the email.
+func (s *UserStore) CreateWithIdentity(ctx context.Context, email, provider, subject string) (*User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO users (email, password) VALUES ($1, '!') RETURNING id`, email).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("failed to insert user: %w", err)
+	}
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, query, id, provider, subject, email); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &User{ID: id, Email: email, Password: "!"}, nil
+}
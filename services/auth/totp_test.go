@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890".
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, cut to our 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		step := tt.unix / int64(totpPeriod.Seconds())
+		got, err := totpCode(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPCodeAcceptsLowercaseSecret(t *testing.T) {
+	got, err := totpCode(strings.ToLower(rfcSecret), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("code = %s, want 287082", got)
+	}
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	code := func(step int64) string {
+		c, err := totpCode(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfcSecret, code(current), current, true},
+		{"previous step", rfcSecret, code(current - 1), current - 1, true},
+		{"next step", rfcSecret, code(current + 1), current + 1, true},
+		{"surrounding whitespace", rfcSecret, " " + code(current) + "\n", current, true},
+		{"two steps old", rfcSecret, code(current - 2), 0, false},
+		{"two steps ahead", rfcSecret, code(current + 2), 0, false},
+		{"wrong code", rfcSecret, "000000", 0, false},
+		{"too short", rfcSecret, code(current)[:5], 0, false},
+		{"too long", rfcSecret, code(current) + "0", 0, false},
+		{"empty", rfcSecret, "", 0, false},
+		{"other secret", totpEncoding.EncodeToString([]byte("another secret 12345")), code(current), 0, false},
+		{"no secret", "", code(current), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := VerifyTOTP(tt.secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("VerifyTOTP() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q isn't base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("key is %d bytes, want 20", len(key))
+	}
+	c, err := totpCode(secret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := VerifyTOTP(secret, c, time.Unix(int64(totpPeriod.Seconds()), 0)); !ok {
+		t.Error("a code of the generated secret doesn't verify")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' {
+			t.Errorf("code %q isn't formatted xxxxx-xxxxx", c)
+		}
+	}
+	// Users may type codes without the dash, in capitals or with spaces around.
+	c := codes[0]
+	for _, typed := range []string{strings.ToUpper(c), strings.ReplaceAll(c, "-", ""), " " + c + " "} {
+		if HashRecoveryCode(typed) != HashRecoveryCode(c) {
+			t.Errorf("%q hashes differently from %q", typed, c)
+		}
+	}
+	if HashRecoveryCode(codes[0]) == HashRecoveryCode(codes[1]) {
+		t.Error("different codes hash the same")
+	}
+}
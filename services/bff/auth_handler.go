@@ -1,10 +1,8 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
-	"time"
 
 	authpb "github.com/my-store/pkg/api/auth"
 )
@@ -16,25 +14,19 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	ctx := r.Context()
 
-	resp, err := s.clients.Auth.Register(ctx, &authpb.RegisterRequest{
+	_, err := s.clients.Auth.Register(ctx, &authpb.RegisterRequest{
 		Email:    req.Email,
 		Password: req.Password,
 	})
 
 	if err != nil {
-		http.Error(w, "Failed to register: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if resp.Status != 0 { // Assuming 0 is OK/Success (standard gRPC code)
-		http.Error(w, resp.Error, http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -49,25 +41,29 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	ctx := r.Context()
 
 	resp, err := s.clients.Auth.Login(ctx, &authpb.LoginRequest{
 		Email:    req.Email,
 		Password: req.Password,
+		Client:   s.clientInfo(r),
 	})
 
 	if err != nil {
-		http.Error(w, "Login failed: "+err.Error(), http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	if resp.Status != 0 {
-		http.Error(w, resp.Error, http.StatusUnauthorized)
+	if resp.MfaRequired {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]any{
+			"mfa_required":    true,
+			"challenge_token": resp.ChallengeToken,
+		})
 		return
 	}
 
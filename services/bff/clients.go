@@ -1,47 +1,207 @@
 package main
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 
 	authpb "github.com/my-store/pkg/api/auth"
 	orderpb "github.com/my-store/pkg/api/order"
+	shippingpb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/grpcclient"
+	"github.com/my-store/pkg/health"
+	"github.com/my-store/pkg/identity"
+	"github.com/my-store/pkg/logging"
+	"github.com/my-store/pkg/mtls"
 )
 
 type ServiceClients struct {
-	Auth  authpb.AuthServiceClient
-	Order orderpb.OrderServiceClient
+	Auth     authpb.AuthServiceClient
+	Order    orderpb.OrderServiceClient
+	Shipping shippingpb.ShippingServiceClient
+
+	authConn     *grpc.ClientConn
+	orderConn    *grpc.ClientConn
+	shippingConn *grpc.ClientConn
+}
+
+// authMethods are the Auth Service methods with settings of their own. Reads are
+// retried. Login and Register spend time in bcrypt, and the OIDC callback waits
+// on the identity provider, so they get longer deadlines.
+var authMethods = map[string]grpcclient.Method{
+	"Validate":          {Timeout: 2 * time.Second, Retry: true},
+	"GetProfile":        {Retry: true},
+	"ListAddresses":     {Retry: true},
+	"GetAddress":        {Retry: true},
+	"ListSessions":      {Retry: true},
+	"Login":             {Timeout: 5 * time.Second},
+	"Register":          {Timeout: 5 * time.Second},
+	"CompleteOidcLogin": {Timeout: 10 * time.Second},
+}
+
+// orderMethods are the Order Service methods with settings of their own.
+var orderMethods = map[string]grpcclient.Method{
+	"GetOrder":    {Retry: true},
+	"CreateOrder": {Timeout: 5 * time.Second},
+}
+
+// shippingMethods are the Shipping Service methods with settings of their own.
+var shippingMethods = map[string]grpcclient.Method{
+	"GetShipmentStatus":   {Retry: true},
+	"GetShipmentTracking": {Retry: true},
+	"GetShippingQuotes":   {Retry: true},
+	"GetShipmentLabel":    {Retry: true},
+}
+
+// HealthChecks returns a readiness check per downstream service, asking each
+// for its overall grpc.health.v1 status (which covers its own dependencies).
+func (c *ServiceClients) HealthChecks() map[string]health.Check {
+	return map[string]health.Check{
+		"auth-service":     health.GRPC(c.authConn, ""),
+		"order-service":    health.GRPC(c.orderConn, ""),
+		"shipping-service": health.GRPC(c.shippingConn, ""),
+	}
 }
 
 func InitClients() (*ServiceClients, error) {
+	creds, err := transportCredentials()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := identity.SignerFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Client spans propagate the request's trace context to the services, and the
+	// interceptors its request ID and the signed identity of the user it's for.
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(logging.UnaryClientInterceptor, identity.UnaryClientInterceptor(signer)),
+		grpc.WithChainStreamInterceptor(logging.StreamClientInterceptor),
+	}
+
 	// Connect to Auth Service
-	authAddr := os.Getenv("AUTH_SERVICE_ADDR")
-	if authAddr == "" {
-		authAddr = "localhost:50051"
+	authCfg, err := clientConfig("AUTH_SERVICE", "localhost:50051", authMethods)
+	if err != nil {
+		return nil, err
 	}
-	authConn, err := grpc.NewClient(authAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	authConn, err := grpcclient.Dial(authpb.AuthService_ServiceDesc.ServiceName, authCfg, opts...)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Connected to Auth Service at %s", authAddr)
+	slog.Info("Connected to Auth Service", "addr", authCfg.Target)
 
 	// Connect to Order Service
-	orderAddr := os.Getenv("ORDER_SERVICE_ADDR")
-	if orderAddr == "" {
-		orderAddr = "localhost:50052"
+	orderCfg, err := clientConfig("ORDER_SERVICE", "localhost:50052", orderMethods)
+	if err != nil {
+		return nil, err
 	}
-	orderConn, err := grpc.NewClient(orderAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	orderConn, err := grpcclient.Dial(orderpb.OrderService_ServiceDesc.ServiceName, orderCfg, opts...)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Connected to Order Service at %s", orderAddr)
+	slog.Info("Connected to Order Service", "addr", orderCfg.Target)
+
+	// Connect to Shipping Service
+	shippingCfg, err := clientConfig("SHIPPING_SERVICE", "localhost:50053", shippingMethods)
+	if err != nil {
+		return nil, err
+	}
+	shippingConn, err := grpcclient.Dial(shippingpb.ShippingService_ServiceDesc.ServiceName, shippingCfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Connected to Shipping Service", "addr", shippingCfg.Target)
 
 	return &ServiceClients{
-		Auth:  authpb.NewAuthServiceClient(authConn),
-		Order: orderpb.NewOrderServiceClient(orderConn),
+		Auth:         authpb.NewAuthServiceClient(authConn),
+		Order:        orderpb.NewOrderServiceClient(orderConn),
+		Shipping:     shippingpb.NewShippingServiceClient(shippingConn),
+		authConn:     authConn,
+		orderConn:    orderConn,
+		shippingConn: shippingConn,
 	}, nil
 }
 
+// transportCredentials returns TLS credentials when TLS_CA_FILE is set, presenting
+// the certificate in TLS_CERT_FILE and TLS_KEY_FILE to services requiring mutual
+// TLS. Without TLS settings, connections are plaintext.
+func transportCredentials() (credentials.TransportCredentials, error) {
+	files := mtls.Files{
+		Cert: os.Getenv("TLS_CERT_FILE"),
+		Key:  os.Getenv("TLS_KEY_FILE"),
+		CA:   os.Getenv("TLS_CA_FILE"),
+	}
+	if !files.Enabled() {
+		return insecure.NewCredentials(), nil
+	}
+	source, err := mtls.NewSource(files)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Using TLS for downstream calls", "mutual", files.Cert != "")
+	return source.ClientCredentials(), nil
+}
+
+// clientConfig reads the settings of a downstream service from variables named
+// after prefix: <prefix>_ADDR, <prefix>_TIMEOUT, <prefix>_MAX_ATTEMPTS,
+// <prefix>_BREAKER_FAILURES and <prefix>_BREAKER_COOLDOWN. GRPC_KEEPALIVE_TIME
+// applies to every service.
+func clientConfig(prefix, defaultAddr string, methods map[string]grpcclient.Method) (grpcclient.Config, error) {
+	cfg := grpcclient.DefaultConfig(defaultAddr)
+	cfg.Methods = methods
+	if addr := os.Getenv(prefix + "_ADDR"); addr != "" {
+		cfg.Target = addr
+	}
+
+	var err error
+	if cfg.Timeout, err = envDuration(prefix+"_TIMEOUT", cfg.Timeout); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxAttempts, err = envInt(prefix+"_MAX_ATTEMPTS", cfg.MaxAttempts); err != nil {
+		return cfg, err
+	}
+	if cfg.Breaker.Failures, err = envInt(prefix+"_BREAKER_FAILURES", cfg.Breaker.Failures); err != nil {
+		return cfg, err
+	}
+	if cfg.Breaker.Cooldown, err = envDuration(prefix+"_BREAKER_COOLDOWN", cfg.Breaker.Cooldown); err != nil {
+		return cfg, err
+	}
+	if cfg.KeepaliveTime, err = envDuration("GRPC_KEEPALIVE_TIME", cfg.KeepaliveTime); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func envInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return n, nil
+}
+
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return d, nil
+}
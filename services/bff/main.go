@@ -1,55 +1,144 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/my-store/pkg/health"
+	"github.com/my-store/pkg/logging"
+	"github.com/my-store/pkg/metrics"
+	"github.com/my-store/pkg/telemetry"
 	"github.com/rs/cors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Server struct {
 	clients *ServiceClients
+	proxies trustedProxies
 }
 
 func main() {
+	logging.Setup("bff")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := telemetry.Setup(ctx, "bff")
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
+
 	// 1. Initialize gRPC Clients
 	clients, err := InitClients()
 	if err != nil {
-		log.Fatalf("Failed to initialize clients: %v", err)
+		logging.Fatal("Failed to initialize clients", "error", err)
 	}
 
-	server := &Server{clients: clients}
+	// Only the proxies listed in TRUSTED_PROXIES may name the client address, which
+	// keys the per-IP rate limits and is recorded on sessions.
+	proxies, err := loadTrustedProxies()
+	if err != nil {
+		logging.Fatal("Invalid trusted proxies", "error", err)
+	}
+	server := &Server{clients: clients, proxies: proxies}
+	limiter, err := newRateLimiter(proxies)
+	if err != nil {
+		logging.Fatal("Invalid rate limit configuration", "error", err)
+	}
 
 	// 2. Setup Router
 	mux := http.NewServeMux()
 
+	// Probes: /livez and /readyz. A downstream outage only degrades the BFF: routes
+	// that don't need the failing service keep working, so it stays ready.
+	checker := health.NewChecker(2 * time.Second)
+	for name, check := range clients.HealthChecks() {
+		checker.AddOptional(name, check)
+	}
+	checker.Register(mux)
+	mux.Handle("/health", checker.LivenessHandler())
+	mux.Handle("GET /metrics", metrics.Handler())
+
 	// Public Endpoints
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("BFF is running"))
-	})
 
 	// Auth Endpoints
-	mux.HandleFunc("/api/auth/register", server.handleRegister)
-	mux.HandleFunc("/api/auth/login", server.handleLogin)
+	mux.HandleFunc("/api/auth/register", limiter.limit("register", server.handleRegister))
+	mux.HandleFunc("/api/auth/login", limiter.limit("login", server.handleLogin))
+	mux.HandleFunc("POST /api/auth/mfa/verify", limiter.limit("mfa_verify", server.handleVerifyMfa))
+	mux.HandleFunc("GET /api/auth/oidc/{provider}/login", server.handleOidcLogin)
+	mux.HandleFunc("GET /api/auth/oidc/{provider}/callback", server.handleOidcCallback)
+	mux.HandleFunc("GET /api/shipments/{tracking_id}", server.handleGetShipment)
 
 	// Protected Endpoints
-	mux.HandleFunc("/api/orders", server.withAuth(server.handleCreateOrder))
+	mux.HandleFunc("/api/orders", server.withAuth(limiter.limit("orders", server.handleCreateOrder)))
+	mux.HandleFunc("GET /api/orders/{id}", server.withAuth(server.handleGetOrder))
+	mux.HandleFunc("POST /api/orders/{id}/shipment", server.withAuth(server.handleCreateShipment))
+	mux.HandleFunc("POST /api/orders/{id}/returns", server.withAuth(server.handleRequestReturn))
+	mux.HandleFunc("GET /api/returns/{id}", server.withAuth(server.handleGetReturn))
+	mux.HandleFunc("GET /api/returns/{id}/label", server.withAuth(server.handleGetReturnLabel))
+	mux.HandleFunc("POST /api/returns/{id}/approve", server.withAuth(server.handleApproveReturn))
+	mux.HandleFunc("POST /api/returns/{id}/reject", server.withAuth(server.handleRejectReturn))
+	mux.HandleFunc("POST /api/returns/{id}/receive", server.withAuth(server.handleReceiveReturn))
+	mux.HandleFunc("POST /api/shipping/quotes", server.withAuth(server.handleGetShippingQuotes))
+	mux.HandleFunc("GET /api/shipments/{tracking_id}/label", server.withAuth(server.handleGetShipmentLabel))
+	mux.HandleFunc("POST /api/auth/mfa/enroll", server.withAuth(server.handleEnrollMfa))
+	mux.HandleFunc("POST /api/auth/mfa/confirm", server.withAuth(server.handleConfirmMfa))
+	mux.HandleFunc("POST /api/auth/mfa/disable", server.withAuth(server.handleDisableMfa))
+	mux.HandleFunc("GET /api/auth/sessions", server.withAuth(server.handleListSessions))
+	mux.HandleFunc("DELETE /api/auth/sessions/{id}", server.withAuth(server.handleRevokeSession))
+	mux.HandleFunc("POST /api/auth/sessions/revoke-others", server.withAuth(server.handleRevokeOtherSessions))
+	mux.HandleFunc("GET /api/users/me", server.withAuth(server.handleGetProfile))
+	mux.HandleFunc("PUT /api/users/me", server.withAuth(server.handleUpdateProfile))
+	mux.HandleFunc("GET /api/users/me/addresses", server.withAuth(server.handleListAddresses))
+	mux.HandleFunc("POST /api/users/me/addresses", server.withAuth(server.handleCreateAddress))
+	mux.HandleFunc("PUT /api/users/me/addresses/{id}", server.withAuth(server.handleUpdateAddress))
+	mux.HandleFunc("DELETE /api/users/me/addresses/{id}", server.withAuth(server.handleDeleteAddress))
+	mux.HandleFunc("POST /api/users/me/addresses/{id}/default", server.withAuth(server.handleSetDefaultAddress))
 
 	// 3. Setup CORS
 	// Allow requests from frontend (localhost:3000)
 	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:5173"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedOrigins: []string{"http://localhost:3000", "http://localhost:5173"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Authorization", "Content-Type", "X-Device-Name", logging.RequestIDHeader},
+		ExposedHeaders: []string{
+			logging.RequestIDHeader, "Retry-After",
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+		},
 		AllowCredentials: true,
 	})
 
-	handler := c.Handler(mux)
+	// Every request gets a server span and RED metrics, labelled with its route once
+	// the mux has matched it. The request ID is assigned outermost because it copies
+	// the request, and the layers reading the route need the copy the mux records it on.
+	handler := withRequestID(otelhttp.NewHandler(routeSpans(metrics.InstrumentHandler(c.Handler(mux))), "bff",
+		otelhttp.WithSpanNameFormatter(spanName),
+		otelhttp.WithFilter(func(r *http.Request) bool { return !isOperational(r.URL.Path) }),
+	))
 
 	// 4. Start Server
 	port := "8080"
-	log.Printf("BFF Service listening on port %s", port)
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	srv := &http.Server{Addr: ":" + port, Handler: handler}
+	go func() {
+		slog.Info("BFF Service listening", "port", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Failed to serve", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Shutdown failed", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
 	}
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	authpb "github.com/my-store/pkg/api/auth"
+)
+
+// bearerToken returns the raw access token from the Authorization header.
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func (s *Server) handleVerifyMfa(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.VerifyMfa(ctx, &authpb.VerifyMfaRequest{
+		ChallengeToken: req.ChallengeToken,
+		Code:           req.Code,
+		Client:         s.clientInfo(r),
+	})
+
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"token": resp.Token,
+	})
+}
+
+func (s *Server) handleEnrollMfa(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.EnrollMfa(ctx, &authpb.EnrollMfaRequest{
+		Token: bearerToken(r),
+	})
+
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":      resp.Secret,
+		"otpauth_uri": resp.OtpauthUri,
+		"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(resp.QrPng),
+	})
+}
+
+func (s *Server) handleConfirmMfa(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.ConfirmMfa(ctx, &authpb.ConfirmMfaRequest{
+		Token: bearerToken(r),
+		Code:  req.Code,
+	})
+
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]string{
+		"recovery_codes": resp.RecoveryCodes,
+	})
+}
+
+func (s *Server) handleDisableMfa(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+
+	_, err := s.clients.Auth.DisableMfa(ctx, &authpb.DisableMfaRequest{
+		Token: bearerToken(r),
+		Code:  req.Code,
+	})
+
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "MFA disabled"})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	authpb "github.com/my-store/pkg/api/auth"
+	"github.com/my-store/pkg/apierr"
+	"google.golang.org/grpc/status"
+)
+
+// oidcStateCookie binds an in-flight OIDC login to the browser that started it,
+// so a callback URL can't be replayed in someone else's session.
+const oidcStateCookie = "oidc_state"
+
+// frontendCallbackURL is where the browser lands after an OIDC login. The outcome
+// is passed in the URL fragment so tokens never reach server logs.
+func frontendCallbackURL() string {
+	if u := os.Getenv("OIDC_FRONTEND_CALLBACK_URL"); u != "" {
+		return u
+	}
+	return "http://localhost:3000/auth/callback"
+}
+
+func (s *Server) handleOidcLogin(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.StartOidcLogin(ctx, &authpb.StartOidcLoginRequest{
+		Provider: provider,
+	})
+
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    resp.State,
+		Path:     "/api/auth/oidc/",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode, // must survive the top-level redirect back from the provider
+	})
+	http.Redirect(w, r, resp.AuthorizationUrl, http.StatusFound)
+}
+
+func (s *Server) handleOidcCallback(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+	query := r.URL.Query()
+
+	// Always clear the state cookie, whatever the outcome.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/api/auth/oidc/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	if providerErr := query.Get("error"); providerErr != "" {
+		redirectToFrontend(w, r, url.Values{"error": {providerErr}})
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || cookie.Value == "" || cookie.Value != query.Get("state") {
+		redirectToFrontend(w, r, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.CompleteOidcLogin(ctx, &authpb.CompleteOidcLoginRequest{
+		Provider: provider,
+		State:    query.Get("state"),
+		Code:     query.Get("code"),
+		Client:   s.clientInfo(r),
+	})
+
+	if err != nil {
+		// Errors raised on purpose by the auth service mean the login was refused;
+		// anything else is a server failure whose details stay out of the browser.
+		if apierr.Info(err) == nil {
+			slog.ErrorContext(ctx, "OIDC callback failed", "provider", provider, "error", err)
+			redirectToFrontend(w, r, url.Values{"error": {"server_error"}})
+			return
+		}
+		redirectToFrontend(w, r, url.Values{"error": {"access_denied"}, "error_description": {status.Convert(err).Message()}})
+		return
+	}
+
+	if resp.MfaRequired {
+		redirectToFrontend(w, r, url.Values{"mfa_required": {"true"}, "challenge_token": {resp.ChallengeToken}})
+		return
+	}
+	redirectToFrontend(w, r, url.Values{"token": {resp.Token}})
+}
+
+func redirectToFrontend(w http.ResponseWriter, r *http.Request, fragment url.Values) {
+	http.Redirect(w, r, frontendCallbackURL()+"#"+fragment.Encode(), http.StatusFound)
+}
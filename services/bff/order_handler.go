@@ -4,11 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 
 	authpb "github.com/my-store/pkg/api/auth"
 	orderpb "github.com/my-store/pkg/api/order"
+	"github.com/my-store/pkg/identity"
 )
 
 // Middleware to validate JWT token
@@ -16,24 +17,27 @@ func (s *Server) withAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+			writeProblem(w, r, http.StatusUnauthorized, "Missing Authorization header")
 			return
 		}
 
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		ctx := r.Context()
 
 		// Validate token with Auth Service
-		resp, err := s.clients.Auth.Validate(ctx, &authpb.ValidateRequest{Token: token})
-		if err != nil || resp.Status != 0 {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		resp, err := s.clients.Auth.Validate(ctx, &authpb.ValidateRequest{
+			Token:  token,
+			Client: s.clientInfo(r),
+		})
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		// Store user ID in context
+		// Store user ID in context, and the identity forwarded to the services
 		ctx = context.WithValue(r.Context(), "userID", resp.UserId)
+		ctx = identity.NewContext(ctx, identity.Identity{UserID: resp.UserId, SessionID: resp.SessionId, Admin: resp.IsAdmin})
 		next(w, r.WithContext(ctx))
 	}
 }
@@ -41,7 +45,7 @@ func (s *Server) withAuth(next http.HandlerFunc) http.HandlerFunc {
 func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("userID").(int64)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -51,10 +55,13 @@ func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 			Quantity  int32   `json:"quantity"`
 			Price     float64 `json:"price"`
 		} `json:"items"`
+		// Either a saved address from the user's address book or an inline one.
+		AddressID       int64        `json:"address_id"`
+		ShippingAddress *addressJSON `json:"shipping_address"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -68,16 +75,29 @@ func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	ctx := r.Context()
+
+	shippingAddress := req.ShippingAddress.proto()
+	if req.AddressID != 0 {
+		addrResp, err := s.clients.Auth.GetAddress(ctx, &authpb.GetAddressRequest{
+			Token:     bearerToken(r),
+			AddressId: req.AddressID,
+		})
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		shippingAddress = addrResp.Address.Address
+	}
 
 	resp, err := s.clients.Order.CreateOrder(ctx, &orderpb.CreateOrderRequest{
-		UserId: userID,
-		Items:  orderItems,
+		UserId:          userID,
+		Items:           orderItems,
+		ShippingAddress: shippingAddress,
 	})
 
 	if err != nil {
-		http.Error(w, "Failed to create order: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -85,3 +105,87 @@ func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]int64{"order_id": resp.OrderId})
 }
 
+type orderItemJSON struct {
+	ProductID int64   `json:"product_id"`
+	Quantity  int32   `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+type itemFulfillmentJSON struct {
+	ProductID         int64  `json:"product_id"`
+	Quantity          int32  `json:"quantity"`
+	QuantityShipped   int32  `json:"quantity_shipped"`
+	QuantityDelivered int32  `json:"quantity_delivered"`
+	QuantityReturned  int32  `json:"quantity_returned"`
+	Status            string `json:"status"`
+}
+
+type orderShipmentJSON struct {
+	TrackingID string `json:"tracking_id"`
+	Status     string `json:"status"`
+}
+
+// handleGetOrder returns an order with the fulfillment of each product, its
+// shipments and its returns. Only the user who placed the order, or an admin, may see it.
+func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	caller, ok := identity.FromContext(r.Context())
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	orderID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	ctx := r.Context()
+
+	order, err := s.clients.Order.GetOrder(ctx, &orderpb.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// Someone else's order is reported as missing rather than forbidden, so order
+	// IDs can't be probed.
+	if order.UserId != caller.UserID && !caller.Admin {
+		writeProblem(w, r, http.StatusNotFound, "Order not found")
+		return
+	}
+
+	items := make([]orderItemJSON, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, orderItemJSON{ProductID: item.ProductId, Quantity: item.Quantity, Price: item.Price})
+	}
+	fulfillment := make([]itemFulfillmentJSON, 0, len(order.Fulfillment))
+	for _, f := range order.Fulfillment {
+		fulfillment = append(fulfillment, itemFulfillmentJSON{
+			ProductID:         f.ProductId,
+			Quantity:          f.Quantity,
+			QuantityShipped:   f.QuantityShipped,
+			QuantityDelivered: f.QuantityDelivered,
+			QuantityReturned:  f.QuantityReturned,
+			Status:            f.Status,
+		})
+	}
+	shipments := make([]orderShipmentJSON, 0, len(order.Shipments))
+	for _, shipment := range order.Shipments {
+		shipments = append(shipments, orderShipmentJSON{TrackingID: shipment.TrackingId, Status: shipment.Status})
+	}
+	returns := make([]returnJSON, 0, len(order.Returns))
+	for _, ret := range order.Returns {
+		returns = append(returns, returnFromProto(ret))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"order_id":         order.OrderId,
+		"status":           order.Status,
+		"items":            items,
+		"shipping_address": addressFromProto(order.ShippingAddress),
+		"fulfillment":      fulfillment,
+		"shipments":        shipments,
+		"returns":          returns,
+	})
+}
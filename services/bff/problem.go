@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/my-store/pkg/apierr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// problem is an RFC 7807 problem details body. Code and Reason are extension
+// members carrying the gRPC status code and the ErrorInfo reason, so clients can
+// branch on them instead of on the human-readable detail.
+type problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	Code          string         `json:"code,omitempty"`
+	Reason        string         `json:"reason,omitempty"`
+	InvalidParams []invalidParam `json:"invalid_params,omitempty"`
+}
+
+// invalidParam describes one rejected request field.
+type invalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+	Code   string `json:"code"`
+}
+
+// writeError translates an error returned by a downstream gRPC call into a problem response.
+// Only errors the services raised on purpose (those carrying an ErrorInfo) expose their
+// message; anything else is logged and reported generically.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	info := apierr.Info(err)
+
+	p := problem{
+		Status:   httpStatusFor(st.Code()),
+		Instance: r.URL.Path,
+		Code:     st.Code().String(),
+	}
+	if info != nil {
+		p.Type = problemType(info.Reason)
+		p.Detail = st.Message()
+		p.Reason = info.Reason
+	} else {
+		slog.ErrorContext(r.Context(), "Request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		p.Type = "about:blank"
+	}
+
+	for _, v := range apierr.FieldViolations(err) {
+		p.InvalidParams = append(p.InvalidParams, invalidParam{
+			Name:   v.Field,
+			Reason: v.Description,
+			Code:   v.Reason,
+		})
+	}
+	if len(p.InvalidParams) > 0 {
+		p.Status = http.StatusUnprocessableEntity
+	}
+
+	// Set when a service (or the circuit breaker in front of it) is unavailable.
+	if delay, ok := apierr.RetryDelay(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(delay), 1)))
+	}
+
+	p.Title = http.StatusText(p.Status)
+	if p.Title == "" {
+		p.Title = st.Code().String()
+	}
+	writeProblemBody(w, p)
+}
+
+// writeProblem responds with a problem for errors detected by the BFF itself,
+// such as a malformed request body.
+func writeProblem(w http.ResponseWriter, r *http.Request, statusCode int, detail string) {
+	writeProblemBody(w, problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(statusCode),
+		Status:   statusCode,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+}
+
+func writeProblemBody(w http.ResponseWriter, p problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// problemType builds the problem type URI for an ErrorInfo reason, e.g.
+// "INVALID_CREDENTIALS" becomes "urn:my-store:problem:invalid-credentials".
+func problemType(reason string) string {
+	return "urn:my-store:problem:" + strings.ToLower(strings.ReplaceAll(reason, "_", "-"))
+}
+
+// httpStatusFor maps a gRPC status code to the closest HTTP status.
+func httpStatusFor(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.FailedPrecondition, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499 // client closed request
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
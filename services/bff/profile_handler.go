@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	authpb "github.com/my-store/pkg/api/auth"
+	commonpb "github.com/my-store/pkg/api/common"
+)
+
+// addressJSON is the REST representation of a postal address.
+type addressJSON struct {
+	RecipientName string `json:"recipient_name"`
+	Line1         string `json:"line1"`
+	Line2         string `json:"line2,omitempty"`
+	City          string `json:"city"`
+	Region        string `json:"region,omitempty"`
+	PostalCode    string `json:"postal_code"`
+	CountryCode   string `json:"country_code"`
+	Phone         string `json:"phone,omitempty"`
+}
+
+func (a *addressJSON) proto() *commonpb.Address {
+	if a == nil {
+		return nil
+	}
+	return &commonpb.Address{
+		RecipientName: a.RecipientName,
+		Line1:         a.Line1,
+		Line2:         a.Line2,
+		City:          a.City,
+		Region:        a.Region,
+		PostalCode:    a.PostalCode,
+		CountryCode:   a.CountryCode,
+		Phone:         a.Phone,
+	}
+}
+
+func addressFromProto(a *commonpb.Address) *addressJSON {
+	if a == nil {
+		return nil
+	}
+	return &addressJSON{
+		RecipientName: a.RecipientName,
+		Line1:         a.Line1,
+		Line2:         a.Line2,
+		City:          a.City,
+		Region:        a.Region,
+		PostalCode:    a.PostalCode,
+		CountryCode:   a.CountryCode,
+		Phone:         a.Phone,
+	}
+}
+
+type savedAddressJSON struct {
+	ID        int64        `json:"id"`
+	Label     string       `json:"label"`
+	IsDefault bool         `json:"is_default"`
+	Address   *addressJSON `json:"address"`
+}
+
+func savedAddressFromProto(a *authpb.SavedAddress) savedAddressJSON {
+	return savedAddressJSON{
+		ID:        a.Id,
+		Label:     a.Label,
+		IsDefault: a.IsDefault,
+		Address:   addressFromProto(a.Address),
+	}
+}
+
+func addressIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid address ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.GetProfile(ctx, &authpb.GetProfileRequest{Token: bearerToken(r)})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeProfile(w, resp)
+}
+
+func (s *Server) handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		Phone string `json:"phone"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.UpdateProfile(ctx, &authpb.UpdateProfileRequest{
+		Token: bearerToken(r),
+		Name:  req.Name,
+		Phone: req.Phone,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeProfile(w, resp)
+}
+
+func writeProfile(w http.ResponseWriter, resp *authpb.ProfileResponse) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"user_id": resp.Profile.UserId,
+		"email":   resp.Profile.Email,
+		"name":    resp.Profile.Name,
+		"phone":   resp.Profile.Phone,
+	})
+}
+
+func (s *Server) handleListAddresses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.ListAddresses(ctx, &authpb.ListAddressesRequest{Token: bearerToken(r)})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	addresses := make([]savedAddressJSON, 0, len(resp.Addresses))
+	for _, a := range resp.Addresses {
+		addresses = append(addresses, savedAddressFromProto(a))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"addresses": addresses})
+}
+
+func (s *Server) handleCreateAddress(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Label       string       `json:"label"`
+		Address     *addressJSON `json:"address"`
+		MakeDefault bool         `json:"make_default"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.CreateAddress(ctx, &authpb.CreateAddressRequest{
+		Token:       bearerToken(r),
+		Label:       req.Label,
+		Address:     req.Address.proto(),
+		MakeDefault: req.MakeDefault,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeAddress(w, resp, http.StatusCreated)
+}
+
+func (s *Server) handleUpdateAddress(w http.ResponseWriter, r *http.Request) {
+	id, ok := addressIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Label   string       `json:"label"`
+		Address *addressJSON `json:"address"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.UpdateAddress(ctx, &authpb.UpdateAddressRequest{
+		Token:     bearerToken(r),
+		AddressId: id,
+		Label:     req.Label,
+		Address:   req.Address.proto(),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeAddress(w, resp, http.StatusOK)
+}
+
+func (s *Server) handleDeleteAddress(w http.ResponseWriter, r *http.Request) {
+	id, ok := addressIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	_, err := s.clients.Auth.DeleteAddress(ctx, &authpb.DeleteAddressRequest{
+		Token:     bearerToken(r),
+		AddressId: id,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSetDefaultAddress(w http.ResponseWriter, r *http.Request) {
+	id, ok := addressIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.SetDefaultAddress(ctx, &authpb.SetDefaultAddressRequest{
+		Token:     bearerToken(r),
+		AddressId: id,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeAddress(w, resp, http.StatusOK)
+}
+
+func writeAddress(w http.ResponseWriter, resp *authpb.AddressResponse, okStatus int) {
+	w.WriteHeader(okStatus)
+	json.NewEncoder(w).Encode(savedAddressFromProto(resp.Address))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/my-store/pkg/ratelimit"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+var rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "bff_rate_limited_total",
+	Help: "Requests rejected with 429, by rate limit policy.",
+}, []string{"policy"})
+
+// limitKey says what a route's bucket is counted against.
+type limitKey int
+
+const (
+	perIP   limitKey = iota
+	perUser          // the authenticated user; the route must be behind withAuth
+)
+
+type routeLimit struct {
+	key    limitKey
+	policy ratelimit.Policy
+}
+
+// defaultRouteLimits are the built-in policies by name. Each can be overridden
+// with RATE_LIMIT_<NAME>=limit/period[/burst], e.g. RATE_LIMIT_LOGIN=20/1m.
+var defaultRouteLimits = map[string]routeLimit{
+	// Slow down credential stuffing and MFA code guessing from one address.
+	"login":      {perIP, ratelimit.Policy{Limit: 10, Period: time.Minute}},
+	"mfa_verify": {perIP, ratelimit.Policy{Limit: 10, Period: time.Minute}},
+	"register":   {perIP, ratelimit.Policy{Limit: 5, Period: 10 * time.Minute}},
+	// Allow a short burst of checkouts, then one every few seconds.
+	"orders": {perUser, ratelimit.Policy{Limit: 20, Period: time.Minute, Burst: 5}},
+}
+
+// rateLimiter applies the route policies using a shared backend.
+type rateLimiter struct {
+	backend ratelimit.Limiter
+	limits  map[string]routeLimit
+	proxies trustedProxies
+}
+
+// newRateLimiter configures the limiter from the environment. RATE_LIMIT_BACKEND
+// selects "memory" (the default; limits are per replica) or "redis", which shares
+// them between replicas through the server at RATE_LIMIT_REDIS_ADDR. Per-IP
+// buckets are keyed by the client address proxies vouch for.
+func newRateLimiter(proxies trustedProxies) (*rateLimiter, error) {
+	rl := &rateLimiter{limits: make(map[string]routeLimit, len(defaultRouteLimits)), proxies: proxies}
+	for name, limit := range defaultRouteLimits {
+		if v := os.Getenv("RATE_LIMIT_" + strings.ToUpper(name)); v != "" {
+			p, err := ratelimit.ParsePolicy(v)
+			if err != nil {
+				return nil, fmt.Errorf("RATE_LIMIT_%s: %w", strings.ToUpper(name), err)
+			}
+			limit.policy = p
+		}
+		rl.limits[name] = limit
+	}
+
+	switch backend := os.Getenv("RATE_LIMIT_BACKEND"); backend {
+	case "", "memory":
+		rl.backend = ratelimit.NewMemory()
+	case "redis":
+		addr := os.Getenv("RATE_LIMIT_REDIS_ADDR")
+		if addr == "" {
+			return nil, fmt.Errorf("RATE_LIMIT_REDIS_ADDR is required for the redis backend")
+		}
+		rl.backend = ratelimit.NewRedis(redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: os.Getenv("RATE_LIMIT_REDIS_PASSWORD"),
+		}), "ratelimit:")
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q", backend)
+	}
+	return rl, nil
+}
+
+// limit wraps a handler with the named policy. Allowed requests get RateLimit-*
+// headers describing the bucket; rejected ones get 429 with Retry-After.
+func (rl *rateLimiter) limit(name string, next http.HandlerFunc) http.HandlerFunc {
+	limit, ok := rl.limits[name]
+	if !ok {
+		panic("unknown rate limit policy " + name)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := name + ":ip:" + rl.proxies.clientIP(r)
+		if limit.key == perUser {
+			if userID, ok := r.Context().Value("userID").(int64); ok {
+				key = name + ":user:" + strconv.FormatInt(userID, 10)
+			}
+		}
+
+		res, err := rl.backend.Allow(r.Context(), key, limit.policy)
+		if err != nil {
+			// Fail open: an unavailable limiter shouldn't take the API down with it.
+			slog.WarnContext(r.Context(), "Rate limiter unavailable", "policy", name, "error", err)
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		window := limit.policy.Period * time.Duration(limit.policy.Capacity()) / time.Duration(limit.policy.Limit)
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit, ceilSeconds(window)))
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			retry := max(ceilSeconds(res.RetryAfter), 1)
+			h.Set("Retry-After", strconv.Itoa(retry))
+			rateLimited.WithLabelValues(name).Inc()
+			writeProblem(w, r, http.StatusTooManyRequests, fmt.Sprintf("Too many requests, retry in %d seconds", retry))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
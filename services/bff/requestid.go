@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/my-store/pkg/logging"
+)
+
+// withRequestID adopts the caller's X-Request-ID, or generates one, and stores it
+// in the request context, from where it is logged and forwarded to the services.
+// The ID is echoed on the response so clients can quote it when reporting a problem.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(logging.RequestIDHeader)
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set(logging.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
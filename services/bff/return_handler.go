@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	orderpb "github.com/my-store/pkg/api/order"
+	shippingpb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/identity"
+)
+
+type returnItemJSON struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int32 `json:"quantity"`
+}
+
+type returnEventJSON struct {
+	Status     string    `json:"status"`
+	Note       string    `json:"note,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type returnJSON struct {
+	ReturnID    int64             `json:"return_id"`
+	OrderID     int64             `json:"order_id"`
+	Status      string            `json:"status"`
+	Reason      string            `json:"reason"`
+	Comment     string            `json:"comment,omitempty"`
+	Items       []returnItemJSON  `json:"items"`
+	TrackingID  string            `json:"tracking_id,omitempty"`
+	RefundCents int64             `json:"refund_cents"`
+	Currency    string            `json:"currency"`
+	RefundID    string            `json:"refund_id,omitempty"`
+	History     []returnEventJSON `json:"history"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+func returnFromProto(ret *orderpb.Return) returnJSON {
+	items := make([]returnItemJSON, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		items = append(items, returnItemJSON{ProductID: item.ProductId, Quantity: item.Quantity})
+	}
+	history := make([]returnEventJSON, 0, len(ret.History))
+	for _, e := range ret.History {
+		history = append(history, returnEventJSON{Status: e.Status, Note: e.Note, OccurredAt: e.OccurredAt.AsTime()})
+	}
+	return returnJSON{
+		ReturnID:    ret.ReturnId,
+		OrderID:     ret.OrderId,
+		Status:      ret.Status,
+		Reason:      ret.Reason,
+		Comment:     ret.Comment,
+		Items:       items,
+		TrackingID:  ret.TrackingId,
+		RefundCents: ret.RefundCents,
+		Currency:    ret.Currency,
+		RefundID:    ret.RefundId,
+		History:     history,
+		CreatedAt:   ret.CreatedAt.AsTime(),
+	}
+}
+
+// handleRequestReturn opens a return of delivered items of the caller's order.
+// The reason is one of damaged, wrong_item, not_as_described, no_longer_needed
+// or other.
+func (s *Server) handleRequestReturn(w http.ResponseWriter, r *http.Request) {
+	caller, ok := identity.FromContext(r.Context())
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	orderID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	var req struct {
+		Items   []returnItemJSON `json:"items"`
+		Reason  string           `json:"reason"`
+		Comment string           `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	items := make([]*orderpb.ReturnItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, &orderpb.ReturnItem{ProductId: item.ProductID, Quantity: item.Quantity})
+	}
+
+	ctx := r.Context()
+
+	// The order service only opens returns of the caller's own orders.
+	ret, err := s.clients.Order.RequestReturn(ctx, &orderpb.RequestReturnRequest{
+		UserId:  caller.UserID,
+		OrderId: orderID,
+		Items:   items,
+		Reason:  strings.ToUpper(req.Reason),
+		Comment: req.Comment,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(returnFromProto(ret))
+}
+
+// handleGetReturn returns a return and its history. Only the customer who
+// requested it, or an admin, may see it.
+func (s *Server) handleGetReturn(w http.ResponseWriter, r *http.Request) {
+	ret, ok := s.loadReturn(w, r)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(returnFromProto(ret))
+}
+
+// handleGetReturnLabel returns the label the customer prints for a return's
+// parcel once support has approved it.
+func (s *Server) handleGetReturnLabel(w http.ResponseWriter, r *http.Request) {
+	ret, ok := s.loadReturn(w, r)
+	if !ok {
+		return
+	}
+	if ret.TrackingId == "" {
+		writeProblem(w, r, http.StatusConflict, "The return has no label until it is approved")
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Shipping.GetShipmentLabel(ctx, &shippingpb.GetShipmentLabelRequest{
+		TrackingId: ret.TrackingId,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", resp.ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp.Content)
+}
+
+// loadReturn fetches the return named by the path for its customer or an admin,
+// writing the error response if it can't.
+func (s *Server) loadReturn(w http.ResponseWriter, r *http.Request) (*orderpb.Return, bool) {
+	caller, ok := identity.FromContext(r.Context())
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	returnID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid return ID")
+		return nil, false
+	}
+
+	ret, err := s.clients.Order.GetReturn(r.Context(), &orderpb.GetReturnRequest{ReturnId: returnID})
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	// Like orders, someone else's return is reported as missing.
+	if ret.UserId != caller.UserID && !caller.Admin {
+		writeProblem(w, r, http.StatusNotFound, "Return not found")
+		return nil, false
+	}
+	return ret, true
+}
+
+// handleApproveReturn books the return shipment from the order's shipping
+// address to the returns center and approves the return. Retrying after a
+// failure reuses the shipment already booked. Only admins may approve returns.
+func (s *Server) handleApproveReturn(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Note        string `json:"note"`
+		WeightGrams int32  `json:"weight_grams"`
+	}
+	returnID, ok := s.supportAction(w, r, &req)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	ret, err := s.clients.Order.GetReturn(ctx, &orderpb.GetReturnRequest{ReturnId: returnID})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if ret.Status != "REQUESTED" {
+		writeProblem(w, r, http.StatusConflict, "The return is "+ret.Status)
+		return
+	}
+	order, err := s.clients.Order.GetOrder(ctx, &orderpb.GetOrderRequest{OrderId: ret.OrderId})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if order.ShippingAddress == nil {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Order has no shipping address")
+		return
+	}
+
+	items := make([]*shippingpb.ShipmentItem, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		items = append(items, &shippingpb.ShipmentItem{ProductId: item.ProductId, Quantity: item.Quantity})
+	}
+	shipment, err := s.clients.Shipping.CreateReturnShipment(ctx, &shippingpb.CreateReturnShipmentRequest{
+		OrderId:     ret.OrderId,
+		ReturnId:    ret.ReturnId,
+		Address:     order.ShippingAddress,
+		WeightGrams: req.WeightGrams,
+		Items:       items,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ret, err = s.clients.Order.ApproveReturn(ctx, &orderpb.ApproveReturnRequest{
+		ReturnId:   returnID,
+		TrackingId: shipment.TrackingId,
+		Note:       req.Note,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(returnFromProto(ret))
+}
+
+// handleRejectReturn turns down a requested return. Only admins may reject
+// returns.
+func (s *Server) handleRejectReturn(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Note string `json:"note"`
+	}
+	returnID, ok := s.supportAction(w, r, &req)
+	if !ok {
+		return
+	}
+
+	ret, err := s.clients.Order.RejectReturn(r.Context(), &orderpb.RejectReturnRequest{
+		ReturnId: returnID,
+		Note:     req.Note,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(returnFromProto(ret))
+}
+
+// handleReceiveReturn records that a return's items arrived at the returns
+// center, which emits a restock event and refunds the customer. Returns are received
+// automatically when their shipment is delivered; this is for parcels the
+// carrier didn't report, and to retry a failed refund. Only admins may receive
+// returns.
+func (s *Server) handleReceiveReturn(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Note string `json:"note"`
+	}
+	returnID, ok := s.supportAction(w, r, &req)
+	if !ok {
+		return
+	}
+
+	ret, err := s.clients.Order.ReceiveReturn(r.Context(), &orderpb.ReceiveReturnRequest{
+		ReturnId: returnID,
+		Note:     req.Note,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(returnFromProto(ret))
+}
+
+// supportAction checks that the caller is an admin and decodes the optional
+// body into req, returning the return ID from the path. It writes the error
+// response if it can't.
+func (s *Server) supportAction(w http.ResponseWriter, r *http.Request, req any) (int64, bool) {
+	caller, ok := identity.FromContext(r.Context())
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+		return 0, false
+	}
+	if !caller.Admin {
+		writeProblem(w, r, http.StatusForbidden, "Only support can process returns")
+		return 0, false
+	}
+
+	returnID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid return ID")
+		return 0, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return 0, false
+	}
+	return returnID, true
+}
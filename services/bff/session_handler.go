@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	authpb "github.com/my-store/pkg/api/auth"
+)
+
+// clientInfo describes the caller's device for session tracking.
+// Clients may name themselves with X-Device-Name; otherwise the auth service
+// derives a label from the user agent. The address is the one the trusted
+// proxies vouch for, as for rate limiting.
+func (s *Server) clientInfo(r *http.Request) *authpb.ClientInfo {
+	return &authpb.ClientInfo{
+		UserAgent: r.UserAgent(),
+		Ip:        s.proxies.clientIP(r),
+		Device:    r.Header.Get("X-Device-Name"),
+	}
+}
+
+type sessionJSON struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.ListSessions(ctx, &authpb.ListSessionsRequest{Token: bearerToken(r)})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	sessions := make([]sessionJSON, 0, len(resp.Sessions))
+	for _, sess := range resp.Sessions {
+		sessions = append(sessions, sessionJSON{
+			ID:         sess.Id,
+			Device:     sess.Device,
+			UserAgent:  sess.UserAgent,
+			IP:         sess.Ip,
+			CreatedAt:  sess.CreatedAt.AsTime(),
+			LastSeenAt: sess.LastSeenAt.AsTime(),
+			Current:    sess.Current,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"sessions": sessions})
+}
+
+func (s *Server) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	_, err := s.clients.Auth.RevokeSession(ctx, &authpb.RevokeSessionRequest{
+		Token:     bearerToken(r),
+		SessionId: r.PathValue("id"),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	resp, err := s.clients.Auth.RevokeAllOtherSessions(ctx, &authpb.RevokeAllOtherSessionsRequest{
+		Token: bearerToken(r),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int32{"revoked_count": resp.RevokedCount})
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	orderpb "github.com/my-store/pkg/api/order"
+	shippingpb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/identity"
+)
+
+type shipmentEventJSON struct {
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	Location    string    `json:"location,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+type shipmentItemJSON struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int32 `json:"quantity"`
+}
+
+type shipmentJSON struct {
+	TrackingID            string              `json:"tracking_id"`
+	OrderID               int64               `json:"order_id"`
+	Status                string              `json:"status"`
+	StatusText            string              `json:"status_text"`
+	Carrier               string              `json:"carrier,omitempty"`
+	Service               string              `json:"service,omitempty"`
+	CarrierTrackingNumber string              `json:"carrier_tracking_number,omitempty"`
+	Items                 []shipmentItemJSON  `json:"items"`
+	History               []shipmentEventJSON `json:"history"`
+}
+
+type quoteJSON struct {
+	Carrier       string `json:"carrier"`
+	Service       string `json:"service"`
+	ServiceName   string `json:"service_name"`
+	PriceCents    int64  `json:"price_cents"`
+	Currency      string `json:"currency"`
+	EstimatedDays int32  `json:"estimated_days"`
+}
+
+// quotePolicies maps the REST policy names to the proto enum.
+var quotePolicies = map[string]shippingpb.QuotePolicy{
+	"":         shippingpb.QuotePolicy_QUOTE_POLICY_UNSPECIFIED,
+	"cheapest": shippingpb.QuotePolicy_QUOTE_POLICY_CHEAPEST,
+	"fastest":  shippingpb.QuotePolicy_QUOTE_POLICY_FASTEST,
+}
+
+// handleGetShippingQuotes prices shipping to an address, best option first, so
+// checkout can offer the choice.
+func (s *Server) handleGetShippingQuotes(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Address     *addressJSON `json:"address"`
+		WeightGrams int32        `json:"weight_grams"`
+		Policy      string       `json:"policy"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	policy, ok := quotePolicies[req.Policy]
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, "Policy must be cheapest or fastest")
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Shipping.GetShippingQuotes(ctx, &shippingpb.GetShippingQuotesRequest{
+		Address:     req.Address.proto(),
+		WeightGrams: req.WeightGrams,
+		Policy:      policy,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	quotes := make([]quoteJSON, 0, len(resp.Quotes))
+	for _, q := range resp.Quotes {
+		quotes = append(quotes, quoteJSON{
+			Carrier:       q.Carrier,
+			Service:       q.Service,
+			ServiceName:   q.ServiceName,
+			PriceCents:    q.PriceCents,
+			Currency:      q.Currency,
+			EstimatedDays: q.EstimatedDays,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"quotes": quotes})
+}
+
+// shipmentStatus turns SHIPMENT_STATUS_IN_TRANSIT into "IN_TRANSIT".
+func shipmentStatus(s shippingpb.ShipmentStatus) string {
+	return strings.TrimPrefix(s.String(), "SHIPMENT_STATUS_")
+}
+
+// handleCreateShipment ships some or all of an order's items to its shipping
+// address with the carrier service chosen from the quotes or, without a choice,
+// the best one under the policy. Only the user who placed the order, or an admin,
+// may ship it.
+func (s *Server) handleCreateShipment(w http.ResponseWriter, r *http.Request) {
+	caller, ok := identity.FromContext(r.Context())
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	orderID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	// The body is optional. Without items, everything not yet shipped goes.
+	var req struct {
+		Items       []shipmentItemJSON `json:"items"`
+		Carrier     string             `json:"carrier"`
+		Service     string             `json:"service"`
+		Policy      string             `json:"policy"`
+		WeightGrams int32              `json:"weight_grams"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	policy, ok := quotePolicies[req.Policy]
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, "Policy must be cheapest or fastest")
+		return
+	}
+
+	ctx := r.Context()
+
+	order, err := s.clients.Order.GetOrder(ctx, &orderpb.GetOrderRequest{OrderId: orderID})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if order.UserId != caller.UserID && !caller.Admin {
+		writeProblem(w, r, http.StatusForbidden, "Only the customer who placed the order can ship it")
+		return
+	}
+	if order.ShippingAddress == nil {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Order has no shipping address")
+		return
+	}
+
+	items := make([]*shippingpb.ShipmentItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, &shippingpb.ShipmentItem{ProductId: item.ProductID, Quantity: item.Quantity})
+	}
+	ordered := make([]*shippingpb.ShipmentItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		ordered = append(ordered, &shippingpb.ShipmentItem{ProductId: item.ProductId, Quantity: item.Quantity})
+	}
+
+	created, err := s.clients.Shipping.CreateShipment(ctx, &shippingpb.CreateShipmentRequest{
+		OrderId:     orderID,
+		Items:       items,
+		Ordered:     ordered,
+		Address:     order.ShippingAddress,
+		WeightGrams: req.WeightGrams,
+		Carrier:     req.Carrier,
+		Service:     req.Service,
+		Policy:      policy,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	s.writeShipment(w, r, http.StatusCreated, created.TrackingId)
+}
+
+// handleGetShipment returns a shipment's status and tracking timeline. Tracking IDs
+// are unguessable, so like a carrier's tracking page this needs no login.
+func (s *Server) handleGetShipment(w http.ResponseWriter, r *http.Request) {
+	s.writeShipment(w, r, http.StatusOK, r.PathValue("tracking_id"))
+}
+
+// handleGetShipmentLabel returns a shipment's label for printing. Labels show the
+// customer's address, so only admins (the warehouse) may fetch them.
+func (s *Server) handleGetShipmentLabel(w http.ResponseWriter, r *http.Request) {
+	caller, ok := identity.FromContext(r.Context())
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if !caller.Admin {
+		writeProblem(w, r, http.StatusForbidden, "Only admins can print labels")
+		return
+	}
+
+	ctx := r.Context()
+
+	resp, err := s.clients.Shipping.GetShipmentLabel(ctx, &shippingpb.GetShipmentLabelRequest{
+		TrackingId: r.PathValue("tracking_id"),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", resp.ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp.Content)
+}
+
+func (s *Server) writeShipment(w http.ResponseWriter, r *http.Request, statusCode int, trackingID string) {
+	resp, err := s.clients.Shipping.GetShipmentTracking(r.Context(), &shippingpb.GetShipmentTrackingRequest{
+		TrackingId: trackingID,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	history := make([]shipmentEventJSON, 0, len(resp.Events))
+	for _, e := range resp.Events {
+		history = append(history, shipmentEventJSON{
+			Status:      shipmentStatus(e.Status),
+			Description: e.Description,
+			Location:    e.Location,
+			OccurredAt:  e.OccurredAt.AsTime(),
+		})
+	}
+
+	items := make([]shipmentItemJSON, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		items = append(items, shipmentItemJSON{ProductID: item.ProductId, Quantity: item.Quantity})
+	}
+
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(shipmentJSON{
+		TrackingID:            trackingID,
+		OrderID:               resp.OrderId,
+		Status:                shipmentStatus(resp.Status),
+		StatusText:            resp.StatusText,
+		Carrier:               resp.Carrier,
+		Service:               resp.Service,
+		CarrierTrackingNumber: resp.CarrierTrackingNumber,
+		Items:                 items,
+		History:               history,
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanName names request spans after the ServeMux pattern that matched, e.g.
+// "DELETE /api/users/me/addresses/{id}", so traces group by route rather than by
+// raw path. otelhttp calls it again once the handler has run and r.Pattern is set.
+func spanName(_ string, r *http.Request) string {
+	if route := routeOf(r); route != "" {
+		return r.Method + " " + route
+	}
+	return r.Method
+}
+
+// routeSpans records the matched route on the request span. The mux sets
+// r.Pattern on the request it is handed, so this runs after next returns.
+func routeSpans(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if route := routeOf(r); route != "" {
+			trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPRoute(route))
+		}
+	})
+}
+
+// routeOf returns the path part of the matched pattern; patterns like
+// "GET /api/users/me" carry the method as well.
+func routeOf(r *http.Request) string {
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return path
+	}
+	return r.Pattern
+}
+
+// isOperational reports whether the path is a health probe or the metrics
+// endpoint, neither of which is worth tracing.
+func isOperational(path string) bool {
+	switch path {
+	case "/livez", "/readyz", "/health", "/metrics":
+		return true
+	}
+	return false
+}
@@ -1,26 +1,62 @@
 package main
 
 import (
-	"fmt"
-	"log"
-	"net"
-	"os"
-	"os/signal"
-	"syscall"
+	"context"
+	"log/slog"
+
+	"github.com/my-store/pkg/events"
+	"github.com/my-store/pkg/events/consumer"
+	"github.com/my-store/pkg/kafka"
+	"github.com/my-store/pkg/logging"
+	"github.com/my-store/pkg/server"
 )
 
+// consumerGroup is the service's Kafka consumer group, which also names its
+// retry and dead-letter topics.
+const consumerGroup = "notification-service"
+
+// topicShipmentStatusChanged carries the shipping service's ShipmentStatusChanged
+// events, keyed by order ID.
+const topicShipmentStatusChanged = "shipment-status-changed"
+
 func main() {
-	port := 50054
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	logging.Setup("notification")
+
+	cfg, err := server.LoadConfig("notification", 50054)
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	retryDelays, err := consumer.RetryDelaysFromEnv()
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logging.Fatal("Invalid consumer configuration", "error", err)
+	}
+	srv := server.New(cfg)
+
+	// Customers hear about their parcels as the shipping service reports them.
+	// Notifications are only logged until a real sender is configured.
+	if len(cfg.KafkaBrokers) > 0 {
+		producer, err := kafka.NewProducer(cfg.KafkaBrokers)
+		if err != nil {
+			logging.Fatal("Failed to create Kafka producer", "error", err)
+		}
+		srv.AddCloser(producer)
+
+		shipments, err := consumer.New(consumer.Config{
+			Brokers:     cfg.KafkaBrokers,
+			Group:       consumerGroup,
+			Topics:      []string{topicShipmentStatusChanged},
+			RetryDelays: retryDelays,
+		}, producer)
+		if err != nil {
+			logging.Fatal("Failed to create Kafka consumer", "error", err)
+		}
+		handle := shipmentHandler(events.Catalog(), LogSender{})
+		srv.AddWorker(func(ctx context.Context) { shipments.Run(ctx, handle) })
+	} else {
+		slog.Warn("KAFKA_BROKERS is not set; no notifications will be sent")
+	}
+
+	if err := srv.Run(); err != nil {
+		logging.Fatal("Notification service failed", "error", err)
 	}
-	defer lis.Close()
-	log.Printf("Notification Service listening on port %d", port)
-
-	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	shippingpb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/events"
+	"github.com/my-store/pkg/kafka"
+)
+
+// Notification is a message to the customer who placed an order.
+type Notification struct {
+	OrderID    int64
+	TrackingID string
+	Subject    string
+	Body       string
+}
+
+// Sender delivers notifications, by email, push or whatever a deployment uses.
+type Sender interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// LogSender logs notifications instead of delivering them, for local runs.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, n Notification) error {
+	slog.InfoContext(ctx, "Notification sent", "order_id", n.OrderID, "tracking_id", n.TrackingID, "subject", n.Subject)
+	return nil
+}
+
+// shipmentHandler returns a Kafka handler that notifies customers when an
+// order's parcel changes status. Return shipments, and scans that don't change
+// the status, notify no one. Notifications are sent at least once: an event
+// redelivered after a failure sends its notification again.
+func shipmentHandler(reg *events.Registry, sender Sender) kafka.Handler {
+	return events.Handle(reg, func(ctx context.Context, _ events.Metadata, event *shippingpb.ShipmentStatusChanged) error {
+		n, ok := shipmentNotification(event)
+		if !ok {
+			return nil
+		}
+		if err := sender.Send(ctx, n); err != nil {
+			return fmt.Errorf("failed to notify order %d: %w", event.OrderId, err)
+		}
+		return nil
+	})
+}
+
+// shipmentNotification returns the notification of a shipment status change,
+// if the customer gets one.
+func shipmentNotification(event *shippingpb.ShipmentStatusChanged) (Notification, bool) {
+	if event.ReturnId != 0 || event.Status == event.PreviousStatus {
+		return Notification{}, false
+	}
+	var subject, body string
+	switch event.Status {
+	case shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT:
+		subject, body = "Your order is on its way", "The carrier has picked up parcel %s."
+	case shippingpb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY:
+		subject, body = "Your order is out for delivery", "Parcel %s will be delivered today."
+	case shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED:
+		subject, body = "Your order has been delivered", "Parcel %s has been delivered."
+	case shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERY_FAILED:
+		subject, body = "We couldn't deliver your order", "The carrier couldn't deliver parcel %s."
+	case shippingpb.ShipmentStatus_SHIPMENT_STATUS_LOST:
+		subject, body = "Your order was lost in transit", "The carrier has lost parcel %s. Support will be in touch."
+	default:
+		return Notification{}, false
+	}
+	return Notification{
+		OrderID:    event.OrderId,
+		TrackingID: event.TrackingId,
+		Subject:    subject,
+		Body:       fmt.Sprintf(body, event.TrackingId),
+	}, true
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	shippingpb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/events"
+	"github.com/my-store/pkg/events/memory"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestShipmentNotification(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      shippingpb.ShipmentStatus
+		previous    shippingpb.ShipmentStatus
+		returnID    int64
+		wantSubject string // "" if no one is notified
+	}{
+		{"booked", shippingpb.ShipmentStatus_SHIPMENT_STATUS_CREATED, shippingpb.ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED, 0, ""},
+		{"picked up", shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, shippingpb.ShipmentStatus_SHIPMENT_STATUS_CREATED, 0, "Your order is on its way"},
+		{"out for delivery", shippingpb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY, shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, 0, "Your order is out for delivery"},
+		{"delivered", shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED, shippingpb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY, 0, "Your order has been delivered"},
+		{"delivery failed", shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERY_FAILED, shippingpb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY, 0, "We couldn't deliver your order"},
+		{"lost", shippingpb.ShipmentStatus_SHIPMENT_STATUS_LOST, shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, 0, "Your order was lost in transit"},
+		{"another scan in transit", shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, 0, ""},
+		{"return picked up", shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, shippingpb.ShipmentStatus_SHIPMENT_STATUS_CREATED, 9, ""},
+		{"return delivered", shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED, shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, 9, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, ok := shipmentNotification(&shippingpb.ShipmentStatusChanged{
+				TrackingId:     "SIM0001",
+				OrderId:        42,
+				Status:         tt.status,
+				PreviousStatus: tt.previous,
+				ReturnId:       tt.returnID,
+			})
+			if ok != (tt.wantSubject != "") || n.Subject != tt.wantSubject {
+				t.Fatalf("got %q (notified: %v), want %q", n.Subject, ok, tt.wantSubject)
+			}
+			if ok && (n.OrderID != 42 || n.TrackingID != "SIM0001") {
+				t.Errorf("notification %+v isn't about order 42's parcel SIM0001", n)
+			}
+		})
+	}
+}
+
+// recordingSender records what it sends. Its first failures sends fail.
+type recordingSender struct {
+	mu       sync.Mutex
+	failures int
+	sent     []Notification
+}
+
+func (s *recordingSender) Send(_ context.Context, n Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("mail server unavailable")
+	}
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+func (s *recordingSender) subjects(orderID int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var subjects []string
+	for _, n := range s.sent {
+		if n.OrderID == orderID {
+			subjects = append(subjects, n.Subject)
+		}
+	}
+	return subjects
+}
+
+// TestShipmentNotificationsFromBroker runs the consumer on the in-memory broker
+// and publishes the shipping service's events for two orders' parcels and a
+// return, as its outbox relay would.
+func TestShipmentNotificationsFromBroker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reg := events.Catalog()
+	broker := memory.NewBroker(3)
+	sender := &recordingSender{failures: 1} // the first send is retried
+	consumer := broker.Consumer(consumerGroup, topicShipmentStatusChanged)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx, shipmentHandler(reg, sender))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	publish := func(orderID int64, trackingID string, returnID int64, statuses ...shippingpb.ShipmentStatus) {
+		t.Helper()
+		previous := shippingpb.ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED
+		for _, status := range statuses {
+			msg, err := reg.Message(ctx, "shipping", topicShipmentStatusChanged, []byte(strconv.FormatInt(orderID, 10)),
+				&shippingpb.ShipmentStatusChanged{
+					TrackingId:     trackingID,
+					OrderId:        orderID,
+					Status:         status,
+					PreviousStatus: previous,
+					OccurredAt:     timestamppb.Now(),
+					ReturnId:       returnID,
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := broker.Publish(ctx, msg); err != nil {
+				t.Fatal(err)
+			}
+			previous = status
+		}
+	}
+	publish(1, "SIM0001", 0,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_CREATED,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED)
+	publish(2, "SIM0002", 0,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_CREATED,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_LOST)
+	publish(1, "SIM0003", 7, // the customer sends something back
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_CREATED,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED)
+
+	// The consumer may join after the events are published; it starts from the
+	// beginning either way.
+	for {
+		var committed int64
+		for p := range broker.Records(topicShipmentStatusChanged) {
+			committed += broker.Committed(consumerGroup, topicShipmentStatusChanged, int32(p))
+		}
+		if committed == 10 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("%d of 10 events handled: %v", committed, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if got, want := sender.subjects(1), []string{"Your order is on its way", "Your order is out for delivery", "Your order has been delivered"}; !slices.Equal(got, want) {
+		t.Errorf("order 1: sent %q, want %q", got, want)
+	}
+	if got, want := sender.subjects(2), []string{"Your order is on its way", "Your order was lost in transit"}; !slices.Equal(got, want) {
+		t.Errorf("order 2: sent %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	shippingpb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/events"
+	"github.com/my-store/pkg/events/consumer"
+	"github.com/my-store/pkg/events/projection"
+	"github.com/my-store/pkg/kafka"
+)
+
+// consumerGroup is the service's Kafka consumer group, which also names its
+// retry and dead-letter topics.
+const consumerGroup = "order-service"
+
+// projectionOrderShipments names the order_shipments projection in rebuild
+// requests and in processed_events.
+const projectionOrderShipments = "order-shipments"
+
+// topicShipmentStatusChanged carries the shipping service's ShipmentStatusChanged
+// events, keyed by order ID.
+const topicShipmentStatusChanged = "shipment-status-changed"
+
+// eventRegistry types and versions the events the service publishes and
+// consumes.
+var eventRegistry = events.Catalog()
+
+// shipmentsProjection is the orders' view of their shipments: order_shipments,
+// and the order statuses derived from it. Return shipments aren't part of it;
+// replaying them would move returns along and refund them again.
+func (s *OrderServer) shipmentsProjection(db *sql.DB) projection.Projection {
+	return projection.Projection{
+		Name:   projectionOrderShipments,
+		Topics: []string{topicShipmentStatusChanged},
+		Tables: []string{"order_shipments"},
+		Handle: events.Handle(eventRegistry, consumer.Idempotent(db, projectionOrderShipments, s.applyOrderShipment)),
+	}
+}
+
+// shipmentsHandler returns the handler of the shipment-status-changed topic: it
+// updates the shipments projection, then the return a return shipment is for.
+// Each runs once per event, so a retry after the second failed skips the first.
+func (s *OrderServer) shipmentsHandler(db *sql.DB, shipments projection.Projection) kafka.Handler {
+	returns := events.Handle(eventRegistry, consumer.Idempotent(db, consumerGroup, s.handleReturnShipment))
+	return func(ctx context.Context, rec *kafka.Record) error {
+		if err := shipments.Handle(ctx, rec); err != nil {
+			return err
+		}
+		return returns(ctx, rec)
+	}
+}
+
+// applyOrderShipment updates the order of a shipment that was created or changed
+// status. It runs once per event (see consumer.Idempotent); events may still
+// arrive late, and ApplyShipment ignores any older than what it has recorded.
+func (s *OrderServer) applyOrderShipment(ctx context.Context, tx *sql.Tx, _ events.Metadata, event *shippingpb.ShipmentStatusChanged) error {
+	if event.ReturnId != 0 {
+		return nil
+	}
+
+	status, changed, err := s.store.ApplyShipment(ctx, tx, event.OrderId, OrderShipment{
+		TrackingID: event.TrackingId,
+		Status:     shipmentStatusName(event.Status),
+		Items:      event.Items,
+	}, event.OccurredAt.AsTime())
+	if errors.Is(err, ErrOrderNotFound) {
+		slog.WarnContext(ctx, "Dropping shipment event for unknown order", "order_id", event.OrderId, "tracking_id", event.TrackingId)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply shipment to order %d: %w", event.OrderId, err)
+	}
+	if changed {
+		slog.InfoContext(ctx, "Order status changed", "order_id", event.OrderId,
+			"status", status, "tracking_id", event.TrackingId)
+	}
+	return nil
+}
+
+// handleReturnShipment moves a return along with its shipment: it is in transit
+// once the carrier has it and received and refunded once delivered to
+// the returns center. Returns are advanced in their own transactions rather than
+// tx, and never step back.
+func (s *OrderServer) handleReturnShipment(ctx context.Context, _ *sql.Tx, _ events.Metadata, event *shippingpb.ShipmentStatusChanged) error {
+	if event.ReturnId == 0 {
+		return nil
+	}
+	var err error
+	switch event.Status {
+	case shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, shippingpb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY:
+		_, _, err = s.store.MarkReturnInTransit(ctx, event.ReturnId)
+	case shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED:
+		_, err = s.receiveReturn(ctx, event.ReturnId, "Delivered to the returns center")
+	case shippingpb.ShipmentStatus_SHIPMENT_STATUS_LOST, shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERY_FAILED:
+		// Support receives the return by hand if the parcel turns up.
+		slog.WarnContext(ctx, "Return shipment did not arrive", "return_id", event.ReturnId,
+			"tracking_id", event.TrackingId, "status", shipmentStatusName(event.Status))
+	}
+
+	switch {
+	case errors.Is(err, ErrReturnNotFound):
+		slog.WarnContext(ctx, "Dropping shipment event for unknown return", "return_id", event.ReturnId, "tracking_id", event.TrackingId)
+		return nil
+	case errors.Is(err, ErrReturnState):
+		// e.g. the parcel was scanned before support recorded the approval.
+		slog.WarnContext(ctx, "Dropping shipment event the return can't follow", "return_id", event.ReturnId,
+			"tracking_id", event.TrackingId, "status", shipmentStatusName(event.Status), "error", err)
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to apply shipment to return %d: %w", event.ReturnId, err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import "github.com/my-store/pkg/apierr"
+
+// errDomain is the ErrorInfo domain of every error returned by this service.
+const errDomain apierr.Domain = "order.my-store"
+
+// Error reasons returned in ErrorInfo. Clients branch on them, so existing values must not change.
+const (
+	ReasonValidationFailed = "VALIDATION_FAILED"
+	ReasonOrderNotFound    = "ORDER_NOT_FOUND"
+	ReasonIdentityRequired = "IDENTITY_REQUIRED"
+	ReasonUserMismatch     = "USER_MISMATCH"
+	ReasonAdminRequired    = "ADMIN_REQUIRED"
+	ReasonReturnNotFound   = "RETURN_NOT_FOUND"
+	ReasonNotReturnable    = "NOT_RETURNABLE"
+	ReasonReturnState      = "INVALID_RETURN_STATE"
+	ReasonRefundFailed     = "REFUND_FAILED"
+	ReasonPaymentFailed    = "PAYMENT_FAILED"
+	ReasonRebuilding       = "SHIPMENTS_REBUILDING"
+)
@@ -0,0 +1,166 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	commonpb "github.com/my-store/pkg/api/common"
+	pb "github.com/my-store/pkg/api/order"
+	shippingpb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/events/memory"
+	"github.com/my-store/pkg/outbox"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// testDB returns a connection to a schema of its own in the database at
+// TEST_DATABASE_URL, dropped when the test ends.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Fatal("TEST_DATABASE_URL is not set; integration tests need Postgres")
+	}
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	cfg.RuntimeParams["search_path"] = schema
+	db := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// waitConsumed waits until every group has handled every record of the topic.
+func waitConsumed(t *testing.T, ctx context.Context, broker *memory.Broker, topic string, records int, groups ...string) {
+	t.Helper()
+	for {
+		consumed := true
+		for _, group := range groups {
+			var n int64
+			for p := range broker.Records(topic) {
+				n += broker.Committed(group, topic, int32(p))
+			}
+			consumed = consumed && n == int64(records)
+		}
+		if consumed {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("records of %s weren't all consumed: %v", topic, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// TestOrderShipmentFlow places an order, has the shipping service's events for
+// its parcel go through the outbox onto a broker, and checks that the order
+// follows the parcel. The shipping service's own test (services/shipping
+// flow_integration_test.go) checks that it publishes these events.
+func TestOrderShipmentFlow(t *testing.T) {
+	db := testDB(t)
+	store := NewOrderStore(db)
+	if err := store.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+	server := NewOrderServer(store, simulatedPayments{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	broker := memory.NewBroker(3)
+	shipments := server.shipmentsProjection(db)
+	wg.Go(func() { outbox.NewRelay(db, broker).Run(ctx) })
+	wg.Go(func() {
+		broker.Consumer(consumerGroup, topicShipmentStatusChanged).Run(ctx, server.shipmentsHandler(db, shipments))
+	})
+
+	created, err := server.CreateOrder(ctx, &pb.CreateOrderRequest{
+		UserId: 7,
+		Items:  []*pb.OrderItem{{ProductId: 1, Quantity: 2, Price: 9.99}},
+		ShippingAddress: &commonpb.Address{
+			RecipientName: "Ada Lovelace", Line1: "12 St James's Square", City: "London",
+			PostalCode: "SW1Y 4JH", CountryCode: "GB",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orderID := created.OrderId
+
+	// The shipping service publishes the parcel's progress through its outbox;
+	// the order database's outbox stands in for it.
+	statuses := []shippingpb.ShipmentStatus{
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_CREATED,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY,
+		shippingpb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED,
+	}
+	occurredAt := time.Now().Add(-time.Hour)
+	previous := shippingpb.ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED
+	for i, status := range statuses {
+		msg, err := eventRegistry.Message(ctx, "shipping", topicShipmentStatusChanged, fmt.Append(nil, orderID),
+			&shippingpb.ShipmentStatusChanged{
+				TrackingId:     "SIM0001",
+				OrderId:        orderID,
+				Status:         status,
+				PreviousStatus: previous,
+				OccurredAt:     timestamppb.New(occurredAt.Add(time.Duration(i) * time.Minute)),
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := outbox.Add(ctx, tx, msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		previous = status
+	}
+
+	waitConsumed(t, ctx, broker, topicShipmentStatusChanged, len(statuses), consumerGroup)
+	if err := broker.WaitIdle(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := store.Get(ctx, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != StatusDelivered {
+		t.Errorf("order status %s, want %s", order.Status, StatusDelivered)
+	}
+	if order.PaidCents != 1998 {
+		t.Errorf("order paid %d cents, want 1998", order.PaidCents)
+	}
+	if len(order.Shipments) != 1 || order.Shipments[0].Status != "DELIVERED" {
+		t.Errorf("order shipments %+v, want SIM0001 delivered", order.Shipments)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+
+	pb "github.com/my-store/pkg/api/order"
+	shippingpb "github.com/my-store/pkg/api/shipping"
+)
+
+// Fulfillment statuses of an order item.
+const (
+	FulfillmentUnfulfilled      = "UNFULFILLED"
+	FulfillmentPartiallyShipped = "PARTIALLY_SHIPPED"
+	FulfillmentShipped          = "SHIPPED"
+	FulfillmentDelivered        = "DELIVERED"
+)
+
+// OrderShipment is the order's copy of one of its shipments, kept up to date
+// from the shipping service's events.
+type OrderShipment struct {
+	TrackingID string
+	Status     string // the shipment status, e.g. "IN_TRANSIT"
+	Items      []*shippingpb.ShipmentItem
+}
+
+// shipmentStatusName turns SHIPMENT_STATUS_IN_TRANSIT into "IN_TRANSIT".
+func shipmentStatusName(s shippingpb.ShipmentStatus) string {
+	return strings.TrimPrefix(s.String(), "SHIPMENT_STATUS_")
+}
+
+// shipmentLeft reports whether the carrier has the parcel or has delivered it.
+// Parcels still awaiting pickup, lost or returned undelivered haven't shipped.
+func shipmentLeft(status string) bool {
+	switch status {
+	case "IN_TRANSIT", "OUT_FOR_DELIVERY", "DELIVERED":
+		return true
+	}
+	return false
+}
+
+// countReturned adds the items of received returns to each product's
+// fulfillment. Returned items stay counted as delivered.
+func countReturned(fulfillment []*pb.ItemFulfillment, returns []*Return) {
+	returned := make(map[int64]int32)
+	for _, ret := range returns {
+		if ret.Status == ReturnReceived || ret.Status == ReturnRefunded {
+			for _, item := range ret.Items {
+				returned[item.ProductId] += item.Quantity
+			}
+		}
+	}
+	for _, f := range fulfillment {
+		f.QuantityReturned = returned[f.ProductId]
+	}
+}
+
+// fulfill derives each product's fulfillment, and from them the order status,
+// from the order's shipments.
+func fulfill(items []*pb.OrderItem, shipments []OrderShipment) ([]*pb.ItemFulfillment, string) {
+	shipped := make(map[int64]int32)
+	delivered := make(map[int64]int32)
+	for _, shipment := range shipments {
+		if !shipmentLeft(shipment.Status) {
+			continue
+		}
+		for _, item := range shipment.Items {
+			shipped[item.ProductId] += item.Quantity
+			if shipment.Status == "DELIVERED" {
+				delivered[item.ProductId] += item.Quantity
+			}
+		}
+	}
+
+	// Products listed more than once are fulfilled together.
+	var fulfillment []*pb.ItemFulfillment
+	byProduct := make(map[int64]*pb.ItemFulfillment)
+	for _, item := range items {
+		f, ok := byProduct[item.ProductId]
+		if !ok {
+			f = &pb.ItemFulfillment{ProductId: item.ProductId}
+			byProduct[item.ProductId] = f
+			fulfillment = append(fulfillment, f)
+		}
+		f.Quantity += item.Quantity
+	}
+
+	allShipped, allDelivered, anyShipped := true, true, false
+	for _, f := range fulfillment {
+		f.QuantityShipped = min(shipped[f.ProductId], f.Quantity)
+		f.QuantityDelivered = min(delivered[f.ProductId], f.Quantity)
+		switch {
+		case f.QuantityDelivered == f.Quantity:
+			f.Status = FulfillmentDelivered
+		case f.QuantityShipped == f.Quantity:
+			f.Status = FulfillmentShipped
+		case f.QuantityShipped > 0:
+			f.Status = FulfillmentPartiallyShipped
+		default:
+			f.Status = FulfillmentUnfulfilled
+		}
+		allShipped = allShipped && f.QuantityShipped == f.Quantity
+		allDelivered = allDelivered && f.QuantityDelivered == f.Quantity
+		anyShipped = anyShipped || f.QuantityShipped > 0
+	}
+
+	switch {
+	case len(fulfillment) > 0 && allDelivered:
+		return fulfillment, StatusDelivered
+	case len(fulfillment) > 0 && allShipped:
+		return fulfillment, StatusShipped
+	case anyShipped:
+		return fulfillment, StatusPartiallyShipped
+	default:
+		return fulfillment, StatusPending
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	pb "github.com/my-store/pkg/api/order"
+	shippingpb "github.com/my-store/pkg/api/shipping"
+)
+
+func TestFulfill(t *testing.T) {
+	// Two of product 1, listed twice, and one of product 2.
+	items := []*pb.OrderItem{{ProductId: 1, Quantity: 1}, {ProductId: 2, Quantity: 1}, {ProductId: 1, Quantity: 1}}
+	shipment := func(status string, quantities ...int32) OrderShipment {
+		s := OrderShipment{TrackingID: "SIM", Status: status}
+		for i, q := range quantities {
+			if q > 0 {
+				s.Items = append(s.Items, &shippingpb.ShipmentItem{ProductId: int64(i + 1), Quantity: q})
+			}
+		}
+		return s
+	}
+
+	tests := []struct {
+		name       string
+		shipments  []OrderShipment
+		wantStatus string
+		want       [2]string // fulfillment status of products 1 and 2
+	}{
+		{"nothing shipped", nil, StatusPending, [2]string{FulfillmentUnfulfilled, FulfillmentUnfulfilled}},
+		{"booked, not picked up", []OrderShipment{shipment("CREATED", 2, 1)}, StatusPending,
+			[2]string{FulfillmentUnfulfilled, FulfillmentUnfulfilled}},
+		{"part of a product", []OrderShipment{shipment("IN_TRANSIT", 1)}, StatusPartiallyShipped,
+			[2]string{FulfillmentPartiallyShipped, FulfillmentUnfulfilled}},
+		{"one product", []OrderShipment{shipment("OUT_FOR_DELIVERY", 2)}, StatusPartiallyShipped,
+			[2]string{FulfillmentShipped, FulfillmentUnfulfilled}},
+		{"everything in transit", []OrderShipment{shipment("IN_TRANSIT", 2, 1)}, StatusShipped,
+			[2]string{FulfillmentShipped, FulfillmentShipped}},
+		{"one of two parcels delivered", []OrderShipment{shipment("DELIVERED", 2), shipment("IN_TRANSIT", 0, 1)}, StatusShipped,
+			[2]string{FulfillmentDelivered, FulfillmentShipped}},
+		{"both parcels delivered", []OrderShipment{shipment("DELIVERED", 2), shipment("DELIVERED", 0, 1)}, StatusDelivered,
+			[2]string{FulfillmentDelivered, FulfillmentDelivered}},
+		{"lost parcel", []OrderShipment{shipment("DELIVERED", 2), shipment("LOST", 0, 1)}, StatusPartiallyShipped,
+			[2]string{FulfillmentDelivered, FulfillmentUnfulfilled}},
+		{"more shipped than ordered", []OrderShipment{shipment("DELIVERED", 3, 1)}, StatusDelivered,
+			[2]string{FulfillmentDelivered, FulfillmentDelivered}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fulfillment, status := fulfill(items, tt.shipments)
+			if status != tt.wantStatus {
+				t.Errorf("order status %s, want %s", status, tt.wantStatus)
+			}
+			if len(fulfillment) != 2 || fulfillment[0].ProductId != 1 || fulfillment[1].ProductId != 2 {
+				t.Fatalf("fulfillment %v, want products 1 and 2 in order", fulfillment)
+			}
+			if fulfillment[0].Quantity != 2 || fulfillment[0].QuantityShipped > 2 || fulfillment[0].QuantityDelivered > 2 {
+				t.Errorf("product 1: %v, want at most the 2 ordered", fulfillment[0])
+			}
+			for i, f := range fulfillment {
+				if f.Status != tt.want[i] {
+					t.Errorf("product %d is %s, want %s", f.ProductId, f.Status, tt.want[i])
+				}
+			}
+		})
+	}
+}
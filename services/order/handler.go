@@ -2,59 +2,378 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	pb "github.com/my-store/pkg/api/order"
+	"github.com/my-store/pkg/apierr"
+	"github.com/my-store/pkg/events/projection"
+	"github.com/my-store/pkg/postal"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// maxReturnComment is the longest comment, in characters, a return may carry.
+const maxReturnComment = 1000
+
 // OrderServer implements the generated OrderServiceServer interface.
 type OrderServer struct {
 	pb.UnimplementedOrderServiceServer
-	store *OrderStore
+	store    *OrderStore
+	payments PaymentProvider
 }
 
 // NewOrderServer creates a new instance of our gRPC server.
-func NewOrderServer(store *OrderStore) *OrderServer {
+func NewOrderServer(store *OrderStore, payments PaymentProvider) *OrderServer {
 	return &OrderServer{
-		store: store,
+		store:    store,
+		payments: payments,
 	}
 }
 
 // CreateOrder handles order creation.
 func (s *OrderServer) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.CreateOrderResponse, error) {
-	if len(req.Items) == 0 {
-		return &pb.CreateOrderResponse{
-			Status: int32(codes.InvalidArgument),
-			Error:  "Order must have at least one item",
-		}, nil
+	violations := validateItems(req.Items)
+	violations = append(violations, postal.Validate("shipping_address", req.ShippingAddress)...)
+	if len(violations) > 0 {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Order is invalid", violations)
 	}
 
-	order, err := s.store.Create(req.UserId, req.Items)
+	order, err := s.store.Create(ctx, req.UserId, req.Items, req.ShippingAddress)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Failed to create order: %v", err)
+		slog.ErrorContext(ctx, "Failed to create order", "user_id", req.UserId, "error", err)
+		return nil, status.Errorf(codes.Internal, "Failed to create order")
+	}
+	if err := s.charge(ctx, order); err != nil {
+		return nil, err
 	}
+	ordersCreated.Inc()
 
 	return &pb.CreateOrderResponse{
-		Status:  int32(codes.OK),
 		OrderId: order.ID,
 	}, nil
 }
 
+// charge collects payment for a new order. An order that can't be paid for is
+// deleted, so it is never shipped. A charge that can't be recorded is paid back
+// before its order is deleted, so no one pays for an order that doesn't exist.
+func (s *OrderServer) charge(ctx context.Context, order *Order) error {
+	// One charge per order, however often this is retried, and one refund of it.
+	key := "order-" + strconv.FormatInt(order.ID, 10)
+	charge, err := s.payments.Charge(ctx, ChargeRequest{
+		IdempotencyKey: key,
+		OrderID:        order.ID,
+		AmountCents:    totalCents(order.Items),
+		Currency:       orderCurrency,
+	})
+	charges.WithLabelValues(outcome(err)).Inc()
+	if err != nil {
+		slog.ErrorContext(ctx, "Payment failed", "order_id", order.ID, "error", err)
+		s.deleteUnpaid(ctx, order.ID)
+		return errDomain.Unavailable(ReasonPaymentFailed, "Payment failed; the order was not placed", 30*time.Second)
+	}
+
+	if err := s.store.RecordPayment(ctx, order.ID, charge); err != nil {
+		slog.ErrorContext(ctx, "Failed to record payment", "order_id", order.ID, "payment_id", charge.ID, "error", err)
+		_, err := s.payments.Refund(ctx, RefundRequest{
+			IdempotencyKey: key,
+			OrderID:        order.ID,
+			AmountCents:    charge.AmountCents,
+			Currency:       orderCurrency,
+			Reason:         "Order not placed",
+		})
+		refunds.WithLabelValues(outcome(err)).Inc()
+		if err != nil {
+			// The customer has paid, so the order stays for support to reconcile.
+			slog.ErrorContext(ctx, "Failed to refund unrecorded payment; keeping the order",
+				"order_id", order.ID, "payment_id", charge.ID, "error", err)
+			return status.Errorf(codes.Internal, "Failed to create order")
+		}
+		s.deleteUnpaid(ctx, order.ID)
+		return errDomain.Unavailable(ReasonPaymentFailed, "Payment failed; the order was not placed", 30*time.Second)
+	}
+	order.PaymentID, order.PaidCents = charge.ID, charge.AmountCents
+	return nil
+}
+
+// deleteUnpaid deletes an order whose payment failed, logging any error.
+func (s *OrderServer) deleteUnpaid(ctx context.Context, orderID int64) {
+	if err := s.store.DeleteUnpaid(ctx, orderID); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete unpaid order", "order_id", orderID, "error", err)
+	}
+}
+
 // GetOrder retrieves order details.
 func (s *OrderServer) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
-	order, err := s.store.Get(req.OrderId)
+	order, err := s.store.Get(ctx, req.OrderId)
+	if errors.Is(err, ErrOrderNotFound) {
+		return nil, errDomain.Error(codes.NotFound, ReasonOrderNotFound, "Order not found")
+	}
+	if errors.Is(err, projection.ErrOffline) {
+		return nil, rebuildingError()
+	}
 	if err != nil {
-		return &pb.GetOrderResponse{
-			Status: int32(codes.NotFound),
-			Error:  "Order not found",
-		}, nil
+		return nil, status.Errorf(codes.Internal, "Failed to load order")
 	}
 
 	return &pb.GetOrderResponse{
-		Status:  int32(codes.OK),
-		OrderId: order.ID,
-		UserId:  order.UserID,
-		Items:   order.Items,
+		OrderId:         order.ID,
+		UserId:          order.UserID,
+		Items:           order.Items,
+		ShippingAddress: order.ShippingAddress,
+		Status:          order.Status,
+		Fulfillment:     order.Fulfillment,
+		Shipments:       shipmentsProto(order.Shipments),
+		Returns:         returnsProto(order.Returns),
 	}, nil
 }
+
+// RequestReturn opens a return of delivered items of the caller's order.
+func (s *OrderServer) RequestReturn(ctx context.Context, req *pb.RequestReturnRequest) (*pb.Return, error) {
+	if violations := validateReturn(req); len(violations) > 0 {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Return is invalid", violations)
+	}
+
+	ret := &Return{
+		OrderID: req.OrderId,
+		UserID:  req.UserId,
+		Reason:  req.Reason,
+		Comment: req.Comment,
+		Items:   req.Items,
+	}
+	if err := s.store.CreateReturn(ctx, ret); err != nil {
+		var notReturnable *NotReturnableError
+		switch {
+		case errors.Is(err, ErrOrderNotFound):
+			return nil, errDomain.Error(codes.NotFound, ReasonOrderNotFound, "Order not found")
+		case errors.Is(err, projection.ErrOffline):
+			return nil, rebuildingError()
+		case errors.As(err, &notReturnable):
+			return nil, errDomain.Error(codes.FailedPrecondition, ReasonNotReturnable,
+				fmt.Sprintf("Only %d of product %d can be returned", notReturnable.Returnable, notReturnable.ProductID))
+		}
+		slog.ErrorContext(ctx, "Failed to request return", "order_id", req.OrderId, "error", err)
+		return nil, status.Errorf(codes.Internal, "Failed to request return")
+	}
+	returnsRequested.Inc()
+	slog.InfoContext(ctx, "Return requested", "return_id", ret.ID, "order_id", ret.OrderID, "reason", ret.Reason)
+
+	return returnProto(ret), nil
+}
+
+// GetReturn retrieves a return and its history.
+func (s *OrderServer) GetReturn(ctx context.Context, req *pb.GetReturnRequest) (*pb.Return, error) {
+	ret, err := s.store.GetReturn(ctx, req.ReturnId)
+	if err != nil {
+		return nil, returnError(ctx, req.ReturnId, err)
+	}
+	return returnProto(ret), nil
+}
+
+// ApproveReturn approves a requested return once its shipment has been booked.
+func (s *OrderServer) ApproveReturn(ctx context.Context, req *pb.ApproveReturnRequest) (*pb.Return, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.TrackingId) == "" {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Approval is invalid", []*errdetails.BadRequest_FieldViolation{
+			apierr.FieldViolation("tracking_id", "required", "The return shipment is required"),
+		})
+	}
+
+	ret, _, err := s.store.ApproveReturn(ctx, req.ReturnId, req.TrackingId, req.Note)
+	if err != nil {
+		return nil, returnError(ctx, req.ReturnId, err)
+	}
+	return returnProto(ret), nil
+}
+
+// RejectReturn turns down a requested return.
+func (s *OrderServer) RejectReturn(ctx context.Context, req *pb.RejectReturnRequest) (*pb.Return, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	ret, _, err := s.store.RejectReturn(ctx, req.ReturnId, req.Note)
+	if err != nil {
+		return nil, returnError(ctx, req.ReturnId, err)
+	}
+	return returnProto(ret), nil
+}
+
+// ReceiveReturn records that a return's items arrived, then refunds it.
+func (s *OrderServer) ReceiveReturn(ctx context.Context, req *pb.ReceiveReturnRequest) (*pb.Return, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	ret, err := s.receiveReturn(ctx, req.ReturnId, req.Note)
+	if err != nil {
+		return nil, returnError(ctx, req.ReturnId, err)
+	}
+	return returnProto(ret), nil
+}
+
+// receiveReturn receives a return, which emits a restock event, and refunds it. A
+// return received earlier whose refund failed is refunded again.
+func (s *OrderServer) receiveReturn(ctx context.Context, returnID int64, note string) (*Return, error) {
+	ret, received, err := s.store.ReceiveReturn(ctx, returnID, note)
+	if err != nil {
+		return nil, err
+	}
+	if received {
+		slog.InfoContext(ctx, "Return received", "return_id", ret.ID, "order_id", ret.OrderID)
+	}
+	if ret.Status != ReturnReceived {
+		return ret, nil
+	}
+
+	refund, err := s.payments.Refund(ctx, RefundRequest{
+		// One refund per return, however often this is retried.
+		IdempotencyKey: "return-" + strconv.FormatInt(ret.ID, 10),
+		OrderID:        ret.OrderID,
+		AmountCents:    ret.RefundCents,
+		Currency:       ret.Currency,
+		Reason:         ret.Reason,
+	})
+	refunds.WithLabelValues(outcome(err)).Inc()
+	if err != nil {
+		slog.ErrorContext(ctx, "Refund failed", "return_id", ret.ID, "order_id", ret.OrderID, "error", err)
+		return nil, ErrRefundFailed
+	}
+	ret, _, err = s.store.MarkReturnRefunded(ctx, ret.ID, refund.ID)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Return refunded", "return_id", ret.ID, "refund_id", refund.ID, "amount_cents", ret.RefundCents)
+	return ret, nil
+}
+
+// outcome labels a payment provider call for metrics.
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// rebuildingError tells the caller to retry once the shipments projection,
+// which order statuses and deliveries come from, has been rebuilt.
+func rebuildingError() error {
+	return errDomain.Unavailable(ReasonRebuilding, "Order shipments are being rebuilt; try again shortly", 30*time.Second)
+}
+
+// returnError converts an error from loading or changing a return.
+func returnError(ctx context.Context, returnID int64, err error) error {
+	var state *ReturnStateError
+	switch {
+	case errors.Is(err, ErrReturnNotFound):
+		return errDomain.Error(codes.NotFound, ReasonReturnNotFound, "Return not found")
+	case errors.As(err, &state):
+		return errDomain.Error(codes.FailedPrecondition, ReasonReturnState, "The return is "+state.Status)
+	case errors.Is(err, ErrRefundFailed):
+		return errDomain.Error(codes.Unavailable, ReasonRefundFailed,
+			"The return was received but the refund failed, receive it again to retry")
+	}
+	slog.ErrorContext(ctx, "Failed to update return", "return_id", returnID, "error", err)
+	return status.Errorf(codes.Internal, "Failed to update return")
+}
+
+func returnsProto(returns []*Return) []*pb.Return {
+	out := make([]*pb.Return, len(returns))
+	for i, ret := range returns {
+		out[i] = returnProto(ret)
+	}
+	return out
+}
+
+func returnProto(ret *Return) *pb.Return {
+	history := make([]*pb.ReturnEvent, len(ret.History))
+	for i, e := range ret.History {
+		history[i] = &pb.ReturnEvent{Status: e.Status, Note: e.Note, OccurredAt: timestamppb.New(e.OccurredAt)}
+	}
+	return &pb.Return{
+		ReturnId:    ret.ID,
+		OrderId:     ret.OrderID,
+		UserId:      ret.UserID,
+		Status:      ret.Status,
+		Reason:      ret.Reason,
+		Comment:     ret.Comment,
+		Items:       ret.Items,
+		TrackingId:  ret.TrackingID,
+		RefundCents: ret.RefundCents,
+		Currency:    ret.Currency,
+		RefundId:    ret.RefundID,
+		History:     history,
+		CreatedAt:   timestamppb.New(ret.CreatedAt),
+	}
+}
+
+func shipmentsProto(shipments []OrderShipment) []*pb.OrderShipment {
+	out := make([]*pb.OrderShipment, len(shipments))
+	for i, shipment := range shipments {
+		out[i] = &pb.OrderShipment{TrackingId: shipment.TrackingID, Status: shipment.Status}
+	}
+	return out
+}
+
+// validateReturn checks the order, the items, the reason and the comment.
+func validateReturn(req *pb.RequestReturnRequest) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	if req.OrderId <= 0 {
+		violations = append(violations, apierr.FieldViolation("order_id", "required", "Order ID is required"))
+	}
+	if len(req.Items) == 0 {
+		violations = append(violations, apierr.FieldViolation("items", "required", "Return must have at least one item"))
+	}
+	for i, item := range req.Items {
+		if item.ProductId <= 0 {
+			violations = append(violations, apierr.FieldViolation(
+				fmt.Sprintf("items[%d].product_id", i), "required", "Product ID is required"))
+		}
+		if item.Quantity <= 0 {
+			violations = append(violations, apierr.FieldViolation(
+				fmt.Sprintf("items[%d].quantity", i), "invalid", "Quantity must be at least 1"))
+		}
+	}
+	if !slices.Contains(returnReasons, req.Reason) {
+		violations = append(violations, apierr.FieldViolation("reason", "invalid",
+			"Reason must be one of "+strings.Join(returnReasons, ", ")))
+	}
+	if utf8.RuneCountInString(req.Comment) > maxReturnComment {
+		violations = append(violations, apierr.FieldViolation("comment", "invalid",
+			fmt.Sprintf("Comment can be at most %d characters", maxReturnComment)))
+	}
+	return violations
+}
+
+// validateItems reports an empty order and any item with a non-positive quantity.
+func validateItems(items []*pb.OrderItem) []*errdetails.BadRequest_FieldViolation {
+	if len(items) == 0 {
+		return []*errdetails.BadRequest_FieldViolation{
+			apierr.FieldViolation("items", "required", "Order must have at least one item"),
+		}
+	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	for i, item := range items {
+		if item.Quantity <= 0 {
+			violations = append(violations, apierr.FieldViolation(
+				fmt.Sprintf("items[%d].quantity", i), "invalid", "Quantity must be at least 1"))
+		}
+		if item.Price < 0 || math.IsNaN(item.Price) || math.IsInf(item.Price, 0) {
+			violations = append(violations, apierr.FieldViolation(
+				fmt.Sprintf("items[%d].price", i), "invalid", "Price can't be negative"))
+		}
+	}
+	return violations
+}
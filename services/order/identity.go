@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/my-store/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// userScoped is implemented by requests made on behalf of a user, such as
+// CreateOrderRequest.
+type userScoped interface {
+	GetUserId() int64
+}
+
+// requireAdmin rejects callers that aren't admins. Customers request returns;
+// approving, rejecting and receiving them is for admins only.
+func requireAdmin(ctx context.Context) error {
+	id, ok := identity.FromContext(ctx)
+	if !ok {
+		return errDomain.Error(codes.Unauthenticated, ReasonIdentityRequired, "Caller identity is required")
+	}
+	if !id.Admin {
+		return errDomain.Error(codes.PermissionDenied, ReasonAdminRequired, "Only admins can do this")
+	}
+	return nil
+}
+
+// checkIdentity rejects user-scoped requests that don't carry a verified
+// identity (see identity.UnaryServerInterceptor) or whose user_id names a
+// different user. It must run after the identity interceptor.
+func checkIdentity(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	scoped, ok := req.(userScoped)
+	if !ok {
+		return handler(ctx, req)
+	}
+	id, ok := identity.FromContext(ctx)
+	if !ok {
+		return nil, errDomain.Error(codes.Unauthenticated, ReasonIdentityRequired, "Caller identity is required")
+	}
+	if scoped.GetUserId() != id.UserID {
+		slog.WarnContext(ctx, "Request user does not match caller identity",
+			"method", info.FullMethod, "user_id", scoped.GetUserId(), "caller_user_id", id.UserID)
+		return nil, errDomain.Error(codes.PermissionDenied, ReasonUserMismatch, "Request user does not match the caller")
+	}
+	return handler(ctx, req)
+}
@@ -1,83 +1,103 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
-	"log"
-	"net"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"context"
+	"log/slog"
 
 	pb "github.com/my-store/pkg/api/order"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
+	"github.com/my-store/pkg/events/consumer"
+	"github.com/my-store/pkg/events/projection"
+	"github.com/my-store/pkg/identity"
+	"github.com/my-store/pkg/kafka"
+	"github.com/my-store/pkg/logging"
+	"github.com/my-store/pkg/mtls"
+	"github.com/my-store/pkg/outbox"
+	"github.com/my-store/pkg/server"
 )
 
 func main() {
-	// 1. Connect to Database
-	dbHost := os.Getenv("POSTGRES_HOST")
-	dbUser := os.Getenv("POSTGRES_USER")
-	dbPass := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DB")
+	logging.Setup("order")
 
-	if dbHost == "" {
-		dbHost = "localhost"
+	cfg, err := server.LoadConfig("order", 50052)
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	signer, err := identity.SignerFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid identity configuration", "error", err)
+	}
+	payments, err := loadPaymentProvider()
+	if err != nil {
+		logging.Fatal("Invalid PAYMENT_PROVIDER", "error", err)
+	}
+	retryDelays, err := consumer.RetryDelaysFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid consumer configuration", "error", err)
 	}
 
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:5432/%s?sslmode=disable", dbUser, dbPass, dbHost, dbName)
-	
-	var db *sql.DB
-	var err error
+	// Orders are placed and returned on behalf of users the BFF has authenticated,
+	// so only it may create them, and only for the user whose identity it
+	// forwards. Support's steps of a return also need an admin identity.
+	srv := server.New(cfg,
+		server.WithAuthorization(mtls.Rules{
+			pb.OrderService_CreateOrder_FullMethodName:   {"bff"},
+			pb.OrderService_RequestReturn_FullMethodName: {"bff"},
+			pb.OrderService_ApproveReturn_FullMethodName: {"bff"},
+			pb.OrderService_RejectReturn_FullMethodName:  {"bff"},
+			pb.OrderService_ReceiveReturn_FullMethodName: {"bff"},
+		}),
+		server.WithUnaryInterceptors(identity.UnaryServerInterceptor(signer), checkIdentity),
+	)
 
-	for i := 0; i < 10; i++ {
-		db, err = sql.Open("pgx", dsn)
-		if err == nil {
-			err = db.Ping()
-			if err == nil {
-				log.Println("Connected to database")
-				break
-			}
-		}
-		log.Printf("Waiting for database... (%d/10)", i+1)
-		time.Sleep(2 * time.Second)
-	}
+	// 1. Connect to Database
+	db, err := srv.OpenDB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logging.Fatal("Failed to connect to database", "error", err)
 	}
-	defer db.Close()
 
 	// 2. Initialize Store & Schema
 	store := NewOrderStore(db)
 	if err := store.InitSchema(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
-	}
-
-	// 3. Start gRPC Server
-	port := 50052
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logging.Fatal("Failed to run migrations", "error", err)
 	}
 
-	s := grpc.NewServer()
-	orderServer := NewOrderServer(store)
-	pb.RegisterOrderServiceServer(s, orderServer)
-	reflection.Register(s)
+	// 3. Register Handlers
+	orderServer := NewOrderServer(store, payments)
+	pb.RegisterOrderServiceServer(srv, orderServer)
 
-	log.Printf("Order Service listening on port %d", port)
+	// The order follows its shipments through PARTIALLY_SHIPPED, SHIPPED and
+	// DELIVERED, and returns follow theirs until received. Restock events are
+	// written to the outbox with the return and published from there. Shipment events that
+	// keep failing are retried through retry topics, then dead-lettered.
+	if len(cfg.KafkaBrokers) > 0 {
+		producer, err := kafka.NewProducer(cfg.KafkaBrokers)
+		if err != nil {
+			logging.Fatal("Failed to create Kafka producer", "error", err)
+		}
+		srv.AddCloser(producer)
+		srv.AddWorker(outbox.NewRelay(db, producer).Run)
 
-	go func() {
-		if err := s.Serve(lis); err != nil {
-			log.Fatalf("failed to serve: %v", err)
+		shipments, err := consumer.New(consumer.Config{
+			Brokers:     cfg.KafkaBrokers,
+			Group:       consumerGroup,
+			Topics:      []string{topicShipmentStatusChanged},
+			RetryDelays: retryDelays,
+		}, producer)
+		if err != nil {
+			logging.Fatal("Failed to create Kafka consumer", "error", err)
 		}
-	}()
+		// order_shipments can be rebuilt from the topic with eventctl
+		// rebuild-projection -projection order-shipments.
+		shipmentsProjection := orderServer.shipmentsProjection(db)
+		handle := orderServer.shipmentsHandler(db, shipmentsProjection)
+		srv.AddWorker(func(ctx context.Context) { shipments.Run(ctx, handle) })
+		srv.AddWorker(projection.NewRebuilder(db, cfg.KafkaBrokers, shipmentsProjection).Run)
+	} else {
+		slog.Warn("KAFKA_BROKERS is not set; order statuses won't follow shipments and restock events stay in the outbox")
+	}
 
-	// 4. Graceful Shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
-	s.GracefulStop()
+	// 4. Serve until SIGINT/SIGTERM, then drain in-flight calls
+	if err := srv.Run(); err != nil {
+		logging.Fatal("Order service failed", "error", err)
+	}
 }
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var ordersCreated = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "orders_created_total",
+	Help: "Orders successfully placed.",
+})
+
+var returnsRequested = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "order_returns_requested_total",
+	Help: "Returns requested by customers.",
+})
+
+var charges = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_charges_total",
+	Help: "Order payments requested from the payment provider, by outcome (ok or error).",
+}, []string{"outcome"})
+
+var refunds = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_refunds_total",
+	Help: "Refunds requested from the payment provider, by outcome (ok or error).",
+}, []string{"outcome"})
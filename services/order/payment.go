@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// PaymentProvider moves money through a payment service provider.
+// Implementations wrap the provider's API; simulatedPayments stands in for one
+// in local runs.
+type PaymentProvider interface {
+	// Charge captures an amount from the customer for an order. Requests with the
+	// same IdempotencyKey charge once and return the same charge.
+	Charge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	// Refund pays an amount back to the customer who paid for an order. Requests
+	// with the same IdempotencyKey refund once and return the same refund, so a
+	// failed call can be retried safely.
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+}
+
+// ChargeRequest describes money to collect.
+type ChargeRequest struct {
+	IdempotencyKey string
+	OrderID        int64
+	AmountCents    int64
+	Currency       string
+}
+
+// Charge is a charge the provider has captured.
+type Charge struct {
+	ID          string // the provider's
+	AmountCents int64  // captured, which is what refunds are bounded by
+}
+
+// RefundRequest describes money to pay back.
+type RefundRequest struct {
+	IdempotencyKey string
+	OrderID        int64
+	AmountCents    int64
+	Currency       string
+	Reason         string
+}
+
+// Refund is a refund the provider has accepted.
+type Refund struct {
+	ID string // the provider's
+}
+
+// loadPaymentProvider returns the provider PAYMENT_PROVIDER names, by default
+// "simulated".
+func loadPaymentProvider() (PaymentProvider, error) {
+	switch name := os.Getenv("PAYMENT_PROVIDER"); name {
+	case "", "simulated":
+		return simulatedPayments{}, nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
+}
+
+// simulatedPayments captures every charge in full and accepts every refund.
+// IDs are derived from the idempotency key, so retries return the same one.
+type simulatedPayments struct{}
+
+func (simulatedPayments) Charge(_ context.Context, req ChargeRequest) (*Charge, error) {
+	if req.AmountCents < 0 {
+		return nil, fmt.Errorf("invalid charge amount %d", req.AmountCents)
+	}
+	sum := sha256.Sum256([]byte(req.IdempotencyKey))
+	return &Charge{ID: "ch_sim_" + hex.EncodeToString(sum[:8]), AmountCents: req.AmountCents}, nil
+}
+
+func (simulatedPayments) Refund(_ context.Context, req RefundRequest) (*Refund, error) {
+	if req.AmountCents < 0 {
+		return nil, fmt.Errorf("invalid refund amount %d", req.AmountCents)
+	}
+	sum := sha256.Sum256([]byte(req.IdempotencyKey))
+	return &Refund{ID: "re_sim_" + hex.EncodeToString(sum[:8])}, nil
+}
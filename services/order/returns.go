@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	pb "github.com/my-store/pkg/api/order"
+	"github.com/my-store/pkg/events/projection"
+	"github.com/my-store/pkg/outbox"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Return statuses. A return is REQUESTED by the customer, then REJECTED or
+// APPROVED by support. An approved return goes IN_TRANSIT with the carrier, is
+// RECEIVED, which emits a restock event, and finally REFUNDED.
+const (
+	ReturnRequested = "REQUESTED"
+	ReturnRejected  = "REJECTED"
+	ReturnApproved  = "APPROVED"
+	ReturnInTransit = "IN_TRANSIT"
+	ReturnReceived  = "RECEIVED"
+	ReturnRefunded  = "REFUNDED"
+)
+
+// returnStep orders the statuses of a return that goes ahead.
+var returnStep = map[string]int{
+	ReturnRequested: 1,
+	ReturnApproved:  2,
+	ReturnInTransit: 3,
+	ReturnReceived:  4,
+	ReturnRefunded:  5,
+}
+
+// returnReasons are the reasons a customer may give for a return.
+var returnReasons = []string{"DAMAGED", "WRONG_ITEM", "NOT_AS_DESCRIBED", "NO_LONGER_NEEDED", "OTHER"}
+
+// orderCurrency is the currency of order prices and so of refunds.
+const orderCurrency = "USD"
+
+// priceCents converts an item price to cents.
+func priceCents(price float64) int64 {
+	return int64(math.Round(price * 100))
+}
+
+// totalCents is what the items cost, which is what the order is charged.
+func totalCents(items []*pb.OrderItem) int64 {
+	var total int64
+	for _, item := range items {
+		total += priceCents(item.Price) * int64(item.Quantity)
+	}
+	return total
+}
+
+// topicItemsRestocked carries pb.ItemsRestocked events, keyed by order ID, for
+// an inventory service to consume.
+const topicItemsRestocked = "items-restocked"
+
+var (
+	// ErrReturnNotFound is returned when no return has the requested ID.
+	ErrReturnNotFound = errors.New("return not found")
+	// ErrNotReturnable is matched by the error returned when a return asks for
+	// more of a product than was delivered and not yet returned.
+	ErrNotReturnable = errors.New("items not returnable")
+	// ErrReturnState is matched by the error returned when a return's status
+	// doesn't allow the requested step.
+	ErrReturnState = errors.New("return can't take that step")
+	// ErrRefundFailed is returned when the payment provider didn't refund a
+	// received return. Receiving it again retries.
+	ErrRefundFailed = errors.New("refund failed")
+)
+
+// NotReturnableError reports the product a return has too much of.
+type NotReturnableError struct {
+	ProductID  int64
+	Returnable int32 // how many can still be returned
+}
+
+func (e *NotReturnableError) Error() string {
+	return fmt.Sprintf("items not returnable: %d of product %d can be returned", e.Returnable, e.ProductID)
+}
+
+// Is makes errors.Is(err, ErrNotReturnable) match.
+func (e *NotReturnableError) Is(target error) bool { return target == ErrNotReturnable }
+
+// ReturnStateError reports the status that kept a return from taking a step.
+type ReturnStateError struct {
+	Status string
+}
+
+func (e *ReturnStateError) Error() string {
+	return "return can't take that step: it is " + e.Status
+}
+
+// Is makes errors.Is(err, ErrReturnState) match.
+func (e *ReturnStateError) Is(target error) bool { return target == ErrReturnState }
+
+// Return is a customer's request (RMA) to send items of an order back for a
+// refund.
+type Return struct {
+	ID          int64
+	OrderID     int64
+	UserID      int64
+	Status      string
+	Reason      string
+	Comment     string
+	Items       []*pb.ReturnItem // one per product
+	TrackingID  string           // the return shipment, once approved
+	RefundCents int64
+	Currency    string
+	RefundID    string // the payment provider's, once refunded
+	CreatedAt   time.Time
+	History     []ReturnEvent // oldest first
+}
+
+// ReturnEvent is a step in a return's history.
+type ReturnEvent struct {
+	Status     string
+	Note       string
+	OccurredAt time.Time
+}
+
+// CreateReturn records a requested return of ret.Items from the user's order and
+// sets its ID, Status, refund amount, CreatedAt and History. Only delivered items
+// that aren't in another return can be returned; asking for more fails with
+// ErrNotReturnable. Another user's order is reported as ErrOrderNotFound, and
+// projection.ErrOffline is returned while the shipments projection is rebuilt.
+//
+// The refund is the items' share of what the payment provider captured for the
+// order, and never more than the capture less the order's other refunds. Item
+// prices come from the order request, so the capture, not the price, bounds what
+// is paid back.
+func (s *OrderStore) CreateReturn(ctx context.Context, ret *Return) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// What was delivered comes from the shipments projection.
+	if err := projection.ReadLock(ctx, tx, projectionOrderShipments); err != nil {
+		return err
+	}
+
+	// Locking the order serializes its returns so two can't claim the same items.
+	var userID, paidCents int64
+	var itemsJSON []byte
+	query := `SELECT user_id, items, paid_cents FROM orders WHERE id = $1 FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, ret.OrderID).Scan(&userID, &itemsJSON, &paidCents)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if userID != ret.UserID {
+		return ErrOrderNotFound
+	}
+	var items []*pb.OrderItem
+	if err := json.Unmarshal(itemsJSON, &items); err != nil {
+		return fmt.Errorf("failed to unmarshal items: %w", err)
+	}
+	shipments, err := orderShipments(ctx, tx, ret.OrderID)
+	if err != nil {
+		return err
+	}
+	fulfillment, _ := fulfill(items, shipments)
+	returns, err := loadReturns(ctx, tx, "order_id = $1", ret.OrderID)
+	if err != nil {
+		return err
+	}
+
+	delivered := make(map[int64]int32, len(fulfillment))
+	for _, f := range fulfillment {
+		delivered[f.ProductId] = f.QuantityDelivered
+	}
+	claimed := make(map[int64]int32)
+	refundable := paidCents
+	for _, other := range returns {
+		if other.Status != ReturnRejected {
+			for _, item := range other.Items {
+				claimed[item.ProductId] += item.Quantity
+			}
+			refundable -= other.RefundCents
+		}
+	}
+	prices := make(map[int64]float64, len(items))
+	for _, item := range items {
+		if _, ok := prices[item.ProductId]; !ok {
+			prices[item.ProductId] = item.Price
+		}
+	}
+
+	ret.Items = mergeReturnItems(ret.Items)
+	var itemsCents int64
+	for _, item := range ret.Items {
+		returnable := max(delivered[item.ProductId]-claimed[item.ProductId], 0)
+		if item.Quantity > returnable {
+			return &NotReturnableError{ProductID: item.ProductId, Returnable: returnable}
+		}
+		itemsCents += priceCents(prices[item.ProductId]) * int64(item.Quantity)
+	}
+	ret.RefundCents = itemsCents
+	if total := totalCents(items); paidCents < total {
+		ret.RefundCents = int64(float64(itemsCents) * float64(paidCents) / float64(total))
+	}
+	ret.RefundCents = min(ret.RefundCents, max(refundable, 0))
+	ret.Status = ReturnRequested
+	ret.Currency = orderCurrency
+
+	returnItemsJSON, err := json.Marshal(ret.Items)
+	if err != nil {
+		return fmt.Errorf("failed to marshal return items: %w", err)
+	}
+	query = `
+		INSERT INTO returns (order_id, user_id, status, reason, comment, items, refund_cents, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query, ret.OrderID, ret.UserID, ret.Status, ret.Reason, ret.Comment,
+		returnItemsJSON, ret.RefundCents, ret.Currency).Scan(&ret.ID, &ret.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert return: %w", err)
+	}
+	event := ReturnEvent{Status: ret.Status, OccurredAt: ret.CreatedAt}
+	if err := addReturnEvent(ctx, tx, ret.ID, event); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit return: %w", err)
+	}
+
+	ret.History = []ReturnEvent{event}
+	return nil
+}
+
+// mergeReturnItems returns items with one entry per product, ordered by product.
+func mergeReturnItems(items []*pb.ReturnItem) []*pb.ReturnItem {
+	sums := make(map[int64]int32, len(items))
+	for _, item := range items {
+		sums[item.ProductId] += item.Quantity
+	}
+	merged := make([]*pb.ReturnItem, 0, len(sums))
+	for _, productID := range slices.Sorted(maps.Keys(sums)) {
+		merged = append(merged, &pb.ReturnItem{ProductId: productID, Quantity: sums[productID]})
+	}
+	return merged
+}
+
+// GetReturn retrieves a return and its history.
+func (s *OrderStore) GetReturn(ctx context.Context, returnID int64) (*Return, error) {
+	returns, err := loadReturns(ctx, s.db, "id = $1", returnID)
+	if err != nil {
+		return nil, err
+	}
+	if len(returns) == 0 {
+		return nil, ErrReturnNotFound
+	}
+	return returns[0], nil
+}
+
+// loadReturns returns the returns matching where, which compares a column to
+// arg, oldest first and with their history.
+func loadReturns(ctx context.Context, q queryer, where string, arg any) ([]*Return, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, order_id, user_id, status, reason, comment, items, tracking_id,
+			refund_cents, currency, refund_id, created_at
+		FROM returns WHERE `+where+` ORDER BY id`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query returns: %w", err)
+	}
+	defer rows.Close()
+
+	var returns []*Return
+	byID := make(map[int64]*Return)
+	for rows.Next() {
+		var ret Return
+		var itemsJSON []byte
+		if err := rows.Scan(&ret.ID, &ret.OrderID, &ret.UserID, &ret.Status, &ret.Reason, &ret.Comment, &itemsJSON,
+			&ret.TrackingID, &ret.RefundCents, &ret.Currency, &ret.RefundID, &ret.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(itemsJSON, &ret.Items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal return items: %w", err)
+		}
+		returns = append(returns, &ret)
+		byID[ret.ID] = &ret
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(returns) == 0 {
+		return nil, nil
+	}
+
+	rows, err = q.QueryContext(ctx, `
+		SELECT return_id, status, note, occurred_at FROM return_events
+		WHERE return_id = ANY($1) ORDER BY occurred_at, id`, slices.Collect(maps.Keys(byID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query return events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var returnID int64
+		var event ReturnEvent
+		if err := rows.Scan(&returnID, &event.Status, &event.Note, &event.OccurredAt); err != nil {
+			return nil, err
+		}
+		byID[returnID].History = append(byID[returnID].History, event)
+	}
+	return returns, rows.Err()
+}
+
+func addReturnEvent(ctx context.Context, tx *sql.Tx, returnID int64, event ReturnEvent) error {
+	query := `INSERT INTO return_events (return_id, status, note, occurred_at) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, query, returnID, event.Status, event.Note, event.OccurredAt); err != nil {
+		return fmt.Errorf("failed to insert return event: %w", err)
+	}
+	return nil
+}
+
+// ApproveReturn approves a requested return whose shipment has been booked.
+func (s *OrderStore) ApproveReturn(ctx context.Context, returnID int64, trackingID, note string) (*Return, bool, error) {
+	return s.advanceReturn(ctx, returnID, []string{ReturnRequested}, ReturnApproved, note, func(tx *sql.Tx, ret *Return) error {
+		ret.TrackingID = trackingID
+		_, err := tx.ExecContext(ctx, `UPDATE returns SET tracking_id = $2 WHERE id = $1`, returnID, trackingID)
+		return err
+	})
+}
+
+// RejectReturn turns down a requested return.
+func (s *OrderStore) RejectReturn(ctx context.Context, returnID int64, note string) (*Return, bool, error) {
+	return s.advanceReturn(ctx, returnID, []string{ReturnRequested}, ReturnRejected, note, nil)
+}
+
+// MarkReturnInTransit records that the carrier picked up an approved return.
+func (s *OrderStore) MarkReturnInTransit(ctx context.Context, returnID int64) (*Return, bool, error) {
+	return s.advanceReturn(ctx, returnID, []string{ReturnApproved}, ReturnInTransit, "Picked up by the carrier", nil)
+}
+
+// ReceiveReturn records that an approved return arrived and emits a restock
+// event: ItemsRestocked is added to the outbox in the same transaction. Nothing
+// in this repository consumes it yet; stock levels are up to its consumers.
+func (s *OrderStore) ReceiveReturn(ctx context.Context, returnID int64, note string) (*Return, bool, error) {
+	return s.advanceReturn(ctx, returnID, []string{ReturnApproved, ReturnInTransit}, ReturnReceived, note, func(tx *sql.Tx, ret *Return) error {
+		msg, err := eventRegistry.Message(ctx, "order", topicItemsRestocked, []byte(strconv.FormatInt(ret.OrderID, 10)), &pb.ItemsRestocked{
+			ReturnId:   ret.ID,
+			OrderId:    ret.OrderID,
+			Items:      ret.Items,
+			OccurredAt: timestamppb.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		return outbox.Add(ctx, tx, msg)
+	})
+}
+
+// MarkReturnRefunded records the payment provider's refund of a received return.
+func (s *OrderStore) MarkReturnRefunded(ctx context.Context, returnID int64, refundID string) (*Return, bool, error) {
+	return s.advanceReturn(ctx, returnID, []string{ReturnReceived}, ReturnRefunded, "Refund "+refundID, func(tx *sql.Tx, ret *Return) error {
+		ret.RefundID = refundID
+		_, err := tx.ExecContext(ctx, `UPDATE returns SET refund_id = $2 WHERE id = $1`, returnID, refundID)
+		return err
+	})
+}
+
+// advanceReturn moves a return in one of the from statuses to status to,
+// recording note in its history, and runs update, if not nil, in the same
+// transaction. A return already at or past to is returned unchanged, so retries
+// and redelivered events are harmless; one in another status fails with a
+// *ReturnStateError. It reports whether the return changed.
+func (s *OrderStore) advanceReturn(ctx context.Context, returnID int64, from []string, to, note string, update func(tx *sql.Tx, ret *Return) error) (*Return, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM returns WHERE id = $1 FOR UPDATE`, returnID).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, ErrReturnNotFound
+		}
+		return nil, false, err
+	}
+	returns, err := loadReturns(ctx, tx, "id = $1", returnID)
+	if err != nil {
+		return nil, false, err
+	}
+	ret := returns[0]
+	if current == to || returnStep[to] > 0 && returnStep[current] >= returnStep[to] {
+		return ret, false, nil
+	}
+	if !slices.Contains(from, current) {
+		return nil, false, &ReturnStateError{Status: current}
+	}
+
+	if update != nil {
+		if err := update(tx, ret); err != nil {
+			return nil, false, fmt.Errorf("failed to update return: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE returns SET status = $2 WHERE id = $1`, returnID, to); err != nil {
+		return nil, false, fmt.Errorf("failed to update return status: %w", err)
+	}
+	event := ReturnEvent{Status: to, Note: note, OccurredAt: time.Now()}
+	if err := addReturnEvent(ctx, tx, returnID, event); err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit return: %w", err)
+	}
+
+	ret.Status = to
+	ret.History = append(ret.History, event)
+	return ret, true, nil
+}
@@ -1,21 +1,46 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	commonpb "github.com/my-store/pkg/api/common"
 	pb "github.com/my-store/pkg/api/order"
+	shippingpb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/events/projection"
+	"github.com/my-store/pkg/outbox"
+)
+
+// ErrOrderNotFound is returned when no order has the requested ID.
+var ErrOrderNotFound = errors.New("order not found")
+
+// Order statuses. After PENDING they are derived from the order's shipments (see
+// fulfill), so a lost parcel can take an order back from SHIPPED to
+// PARTIALLY_SHIPPED until it is replaced.
+const (
+	StatusPending          = "PENDING"
+	StatusPartiallyShipped = "PARTIALLY_SHIPPED"
+	StatusShipped          = "SHIPPED"
+	StatusDelivered        = "DELIVERED"
 )
 
 // Order represents an order in our system.
 type Order struct {
-	ID     int64
-	UserID int64
-	Items  []*pb.OrderItem
-	Status string
+	ID              int64
+	UserID          int64
+	Items           []*pb.OrderItem
+	Status          string
+	ShippingAddress *commonpb.Address
+	PaymentID       string // the payment provider's charge
+	PaidCents       int64  // captured; refunds never exceed it
+	Fulfillment     []*pb.ItemFulfillment // one per product
+	Shipments       []OrderShipment
+	Returns         []*Return // oldest first
 }
 
 // OrderStore handles database interactions for orders.
@@ -28,7 +53,7 @@ func NewOrderStore(db *sql.DB) *OrderStore {
 	return &OrderStore{db: db}
 }
 
-// InitSchema creates the orders table if it doesn't exist.
+// InitSchema creates the order tables if they don't exist.
 func (s *OrderStore) InitSchema() error {
 	query := `
 	CREATE TABLE IF NOT EXISTS orders (
@@ -36,49 +61,128 @@ func (s *OrderStore) InitSchema() error {
 		user_id BIGINT NOT NULL,
 		status TEXT NOT NULL,
 		items JSONB NOT NULL
-	);`
-	_, err := s.db.Exec(query)
-	return err
+	);
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_address JSONB;
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS paid_cents BIGINT NOT NULL DEFAULT 0;
+
+	-- The order's shipments, as last reported by the shipping service.
+	CREATE TABLE IF NOT EXISTS order_shipments (
+		tracking_id TEXT PRIMARY KEY,
+		order_id BIGINT NOT NULL,
+		status TEXT NOT NULL,
+		items JSONB NOT NULL,
+		occurred_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS order_shipments_order_id ON order_shipments (order_id);
+
+	CREATE TABLE IF NOT EXISTS returns (
+		id SERIAL PRIMARY KEY,
+		order_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		status TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		items JSONB NOT NULL,
+		tracking_id TEXT NOT NULL DEFAULT '',
+		refund_cents BIGINT NOT NULL,
+		currency TEXT NOT NULL,
+		refund_id TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS returns_order_id ON returns (order_id);
+	CREATE TABLE IF NOT EXISTS return_events (
+		id SERIAL PRIMARY KEY,
+		return_id BIGINT NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+		status TEXT NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS return_events_return_id ON return_events (return_id, occurred_at);`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+	if err := outbox.InitSchema(s.db); err != nil {
+		return err
+	}
+	return projection.InitSchema(s.db)
 }
 
 // Create adds a new order to the database.
-func (s *OrderStore) Create(userID int64, items []*pb.OrderItem) (*Order, error) {
+func (s *OrderStore) Create(ctx context.Context, userID int64, items []*pb.OrderItem, address *commonpb.Address) (*Order, error) {
 	// Convert items to JSON for storage
 	itemsJSON, err := json.Marshal(items)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal items: %w", err)
 	}
 
+	var addressJSON []byte
+	if address != nil {
+		addressJSON, err = json.Marshal(address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal address: %w", err)
+		}
+	}
+
 	query := `
-		INSERT INTO orders (user_id, status, items) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO orders (user_id, status, items, shipping_address) 
+		VALUES ($1, $2, $3, $4) 
 		RETURNING id`
 
 	var id int64
-	err = s.db.QueryRow(query, userID, "PENDING", itemsJSON).Scan(&id)
+	err = s.db.QueryRowContext(ctx, query, userID, StatusPending, itemsJSON, addressJSON).Scan(&id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert order: %w", err)
 	}
 
 	return &Order{
-		ID:     id,
-		UserID: userID,
-		Items:  items,
-		Status: "PENDING",
+		ID:              id,
+		UserID:          userID,
+		Items:           items,
+		Status:          StatusPending,
+		ShippingAddress: address,
 	}, nil
 }
 
-// Get retrieves an order by ID.
-func (s *OrderStore) Get(orderID int64) (*Order, error) {
-	query := `SELECT id, user_id, status, items FROM orders WHERE id = $1`
+// RecordPayment records the provider's charge of an order.
+func (s *OrderStore) RecordPayment(ctx context.Context, orderID int64, charge *Charge) error {
+	query := `UPDATE orders SET payment_id = $2, paid_cents = $3 WHERE id = $1`
+	if _, err := s.db.ExecContext(ctx, query, orderID, charge.ID, charge.AmountCents); err != nil {
+		return fmt.Errorf("failed to record payment of order %d: %w", orderID, err)
+	}
+	return nil
+}
+
+// DeleteUnpaid deletes an order whose payment failed, so it can't be shipped.
+func (s *OrderStore) DeleteUnpaid(ctx context.Context, orderID int64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM orders WHERE id = $1 AND payment_id = ''`, orderID); err != nil {
+		return fmt.Errorf("failed to delete unpaid order %d: %w", orderID, err)
+	}
+	return nil
+}
+
+// Get retrieves an order by ID. Its status and shipments come from the shipments
+// projection, so Get fails with projection.ErrOffline while that is rebuilt.
+func (s *OrderStore) Get(ctx context.Context, orderID int64) (*Order, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := projection.ReadLock(ctx, tx, projectionOrderShipments); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, user_id, status, items, shipping_address, payment_id, paid_cents FROM orders WHERE id = $1`
 
 	var order Order
-	var itemsJSON []byte
+	var itemsJSON, addressJSON []byte
 
-	err := s.db.QueryRow(query, orderID).Scan(&order.ID, &order.UserID, &order.Status, &itemsJSON)
+	err = tx.QueryRowContext(ctx, query, orderID).Scan(&order.ID, &order.UserID, &order.Status, &itemsJSON, &addressJSON,
+		&order.PaymentID, &order.PaidCents)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("order not found")
+			return nil, ErrOrderNotFound
 		}
 		return nil, err
 	}
@@ -87,5 +191,101 @@ func (s *OrderStore) Get(orderID int64) (*Order, error) {
 		return nil, fmt.Errorf("failed to unmarshal items: %w", err)
 	}
 
+	if addressJSON != nil {
+		if err := json.Unmarshal(addressJSON, &order.ShippingAddress); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal address: %w", err)
+		}
+	}
+
+	if order.Shipments, err = orderShipments(ctx, tx, orderID); err != nil {
+		return nil, err
+	}
+	order.Fulfillment, _ = fulfill(order.Items, order.Shipments)
+	if order.Returns, err = loadReturns(ctx, tx, "order_id = $1", orderID); err != nil {
+		return nil, err
+	}
+	countReturned(order.Fulfillment, order.Returns)
+
 	return &order, nil
 }
+
+// queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func orderShipments(ctx context.Context, q queryer, orderID int64) ([]OrderShipment, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT tracking_id, status, items FROM order_shipments
+		WHERE order_id = $1 ORDER BY occurred_at, tracking_id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order shipments: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []OrderShipment
+	for rows.Next() {
+		var shipment OrderShipment
+		var itemsJSON []byte
+		if err := rows.Scan(&shipment.TrackingID, &shipment.Status, &itemsJSON); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(itemsJSON, &shipment.Items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal shipment items: %w", err)
+		}
+		shipments = append(shipments, shipment)
+	}
+	return shipments, rows.Err()
+}
+
+// ApplyShipment records in tx the state of one of the order's shipments as of
+// occurredAt and derives the order status again. Updates older than the one
+// recorded are ignored, so replayed events can't roll a shipment back. A
+// shipment without items contains the whole order. It returns the order's
+// status and whether it changed; the caller commits tx.
+func (s *OrderStore) ApplyShipment(ctx context.Context, tx *sql.Tx, orderID int64, shipment OrderShipment, occurredAt time.Time) (string, bool, error) {
+	var current string
+	var itemsJSON []byte
+	err := tx.QueryRowContext(ctx, `SELECT status, items FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&current, &itemsJSON)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, ErrOrderNotFound
+		}
+		return "", false, err
+	}
+	var items []*pb.OrderItem
+	if err := json.Unmarshal(itemsJSON, &items); err != nil {
+		return "", false, fmt.Errorf("failed to unmarshal items: %w", err)
+	}
+
+	if len(shipment.Items) == 0 {
+		for _, item := range items {
+			shipment.Items = append(shipment.Items, &shippingpb.ShipmentItem{ProductId: item.ProductId, Quantity: item.Quantity})
+		}
+	}
+	shipmentItemsJSON, err := json.Marshal(shipment.Items)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to marshal shipment items: %w", err)
+	}
+	query := `
+		INSERT INTO order_shipments (tracking_id, order_id, status, items, occurred_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tracking_id) DO UPDATE
+		SET status = EXCLUDED.status, items = EXCLUDED.items, occurred_at = EXCLUDED.occurred_at
+		WHERE order_shipments.occurred_at <= EXCLUDED.occurred_at`
+	if _, err := tx.ExecContext(ctx, query, shipment.TrackingID, orderID, shipment.Status, shipmentItemsJSON, occurredAt); err != nil {
+		return "", false, fmt.Errorf("failed to record order shipment: %w", err)
+	}
+
+	shipments, err := orderShipments(ctx, tx, orderID)
+	if err != nil {
+		return "", false, err
+	}
+	_, status := fulfill(items, shipments)
+	if status != current {
+		if _, err := tx.ExecContext(ctx, `UPDATE orders SET status = $2 WHERE id = $1`, orderID, status); err != nil {
+			return "", false, fmt.Errorf("failed to update order status: %w", err)
+		}
+	}
+	return status, status != current, nil
+}
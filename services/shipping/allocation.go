@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	pb "github.com/my-store/pkg/api/shipping"
+)
+
+var (
+	// ErrNothingToShip is returned when every item of the order is already in a
+	// shipment.
+	ErrNothingToShip = errors.New("nothing left to ship")
+	// ErrExceedsOrder is matched by the error returned when a shipment would
+	// contain more of a product than the order has left to ship.
+	ErrExceedsOrder = errors.New("shipment exceeds the order")
+)
+
+// ExceedsOrderError reports the product a shipment has too much of.
+type ExceedsOrderError struct {
+	ProductID int64
+	Remaining int32 // how many are left to ship
+}
+
+func (e *ExceedsOrderError) Error() string {
+	return fmt.Sprintf("shipment exceeds the order: %d of product %d left to ship", e.Remaining, e.ProductID)
+}
+
+// Is makes errors.Is(err, ErrExceedsOrder) match.
+func (e *ExceedsOrderError) Is(target error) bool { return target == ErrExceedsOrder }
+
+// allocate returns the items of a new shipment: requested, merged by product, or
+// everything of ordered that isn't in shipped yet.
+func allocate(ordered []*pb.ShipmentItem, shipped map[int64]int32, requested []*pb.ShipmentItem) ([]*pb.ShipmentItem, error) {
+	remaining := sumByProduct(ordered)
+	for productID, quantity := range shipped {
+		remaining[productID] -= quantity
+	}
+
+	want := sumByProduct(requested)
+	if len(want) == 0 {
+		for productID, quantity := range remaining {
+			if quantity > 0 {
+				want[productID] = quantity
+			}
+		}
+		if len(want) == 0 {
+			return nil, ErrNothingToShip
+		}
+	}
+
+	items := make([]*pb.ShipmentItem, 0, len(want))
+	for _, productID := range slices.Sorted(maps.Keys(want)) {
+		if want[productID] > remaining[productID] {
+			return nil, &ExceedsOrderError{ProductID: productID, Remaining: max(remaining[productID], 0)}
+		}
+		items = append(items, &pb.ShipmentItem{ProductId: productID, Quantity: want[productID]})
+	}
+	return items, nil
+}
+
+// mergeItems returns items with one entry per product, ordered by product.
+func mergeItems(items []*pb.ShipmentItem) []*pb.ShipmentItem {
+	sums := sumByProduct(items)
+	merged := make([]*pb.ShipmentItem, 0, len(sums))
+	for _, productID := range slices.Sorted(maps.Keys(sums)) {
+		merged = append(merged, &pb.ShipmentItem{ProductId: productID, Quantity: sums[productID]})
+	}
+	return merged
+}
+
+// sumByProduct adds up the quantities of items listing the same product.
+func sumByProduct(items []*pb.ShipmentItem) map[int64]int32 {
+	sums := make(map[int64]int32, len(items))
+	for _, item := range items {
+		sums[item.ProductId] += item.Quantity
+	}
+	return sums
+}
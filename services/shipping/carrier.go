@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	commonpb "github.com/my-store/pkg/api/common"
+	pb "github.com/my-store/pkg/api/shipping"
+)
+
+// defaultWeightGrams is assumed for parcels whose weight the caller doesn't know.
+const defaultWeightGrams = 1000
+
+// ErrUnknownTrackingNumber is returned by a carrier that has no label with the
+// tracking number.
+var ErrUnknownTrackingNumber = errors.New("unknown tracking number")
+
+// Carrier books parcels with a shipping company. Implementations wrap the
+// company's API; simulatedCarrier stands in for one in local runs.
+type Carrier interface {
+	// Name identifies the carrier in quotes, webhooks and stored shipments.
+	Name() string
+	// Quote prices a parcel with each service that can deliver it. A carrier that
+	// can't reach the destination returns no quotes.
+	Quote(ctx context.Context, req QuoteRequest) ([]Quote, error)
+	// CreateLabel books a quoted service and returns the label to print.
+	CreateLabel(ctx context.Context, req LabelRequest) (*Label, error)
+	// Cancel voids a label that hasn't been used yet.
+	Cancel(ctx context.Context, trackingNumber string) error
+	// Track returns the carrier's scans of a parcel so far, oldest first, with
+	// Carrier and EventID set so repeated polls can be deduplicated.
+	Track(ctx context.Context, trackingNumber string) ([]ShipmentEvent, error)
+}
+
+// QuoteRequest describes a parcel to price.
+type QuoteRequest struct {
+	Destination *commonpb.Address
+	WeightGrams int32
+}
+
+// Quote is a carrier service's price for a parcel.
+type Quote struct {
+	Carrier       string
+	Service       string // the carrier's code, e.g. "ground"
+	ServiceName   string
+	PriceCents    int64
+	Currency      string
+	EstimatedDays int32
+}
+
+func (q Quote) proto() *pb.ShippingQuote {
+	return &pb.ShippingQuote{
+		Carrier:       q.Carrier,
+		Service:       q.Service,
+		ServiceName:   q.ServiceName,
+		PriceCents:    q.PriceCents,
+		Currency:      q.Currency,
+		EstimatedDays: q.EstimatedDays,
+	}
+}
+
+// LabelRequest books a parcel with a quoted service.
+type LabelRequest struct {
+	Reference   string // our tracking ID, printed on the label
+	Service     string
+	Recipient   *commonpb.Address
+	Sender      *commonpb.Address // nil for parcels we send
+	WeightGrams int32
+	Format      LabelFormat
+}
+
+// Label is a booked parcel's shipping label.
+type Label struct {
+	TrackingNumber string // the carrier's
+	Format         LabelFormat
+	Data           []byte
+}
+
+// Carriers are the carriers shipments can be booked with.
+type Carriers []Carrier
+
+// Get returns the carrier with the name.
+func (cs Carriers) Get(name string) (Carrier, bool) {
+	for _, c := range cs {
+		if c.Name() == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Quote asks every carrier for quotes. A carrier that fails is left out, so one
+// outage doesn't stop shipping; an error is returned only if all of them fail.
+func (cs Carriers) Quote(ctx context.Context, req QuoteRequest) ([]Quote, error) {
+	var quotes []Quote
+	var errs []error
+	for _, c := range cs {
+		q, err := c.Quote(ctx, req)
+		carrierRequests.WithLabelValues(c.Name(), "quote", outcome(err)).Inc()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
+			continue
+		}
+		quotes = append(quotes, q...)
+	}
+	if len(errs) == len(cs) && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return quotes, nil
+}
+
+// sortQuotes orders quotes best first under the policy.
+func sortQuotes(quotes []Quote, policy pb.QuotePolicy) {
+	slices.SortStableFunc(quotes, func(a, b Quote) int {
+		if policy == pb.QuotePolicy_QUOTE_POLICY_FASTEST {
+			return cmp.Or(cmp.Compare(a.EstimatedDays, b.EstimatedDays), cmp.Compare(a.PriceCents, b.PriceCents))
+		}
+		return cmp.Or(cmp.Compare(a.PriceCents, b.PriceCents), cmp.Compare(a.EstimatedDays, b.EstimatedDays))
+	})
+}
+
+// parsePolicy parses a SHIPPING_QUOTE_POLICY value.
+func parsePolicy(s string) (pb.QuotePolicy, error) {
+	switch s {
+	case "", "cheapest":
+		return pb.QuotePolicy_QUOTE_POLICY_CHEAPEST, nil
+	case "fastest":
+		return pb.QuotePolicy_QUOTE_POLICY_FASTEST, nil
+	}
+	return 0, fmt.Errorf("unknown quote policy %q (want cheapest or fastest)", s)
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// loadCarriers returns the carriers named in CARRIERS, by default just the
+// simulated one.
+func loadCarriers(sim simulation) (Carriers, error) {
+	names := os.Getenv("CARRIERS")
+	if names == "" {
+		names = simulatedCarrierName
+	}
+
+	var carriers Carriers
+	for _, name := range strings.Split(names, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case simulatedCarrierName:
+			carriers = append(carriers, newSimulatedCarrier(sim))
+		default:
+			return nil, fmt.Errorf("unknown carrier %q", name)
+		}
+	}
+	if len(carriers) == 0 {
+		return nil, errors.New("no carriers configured")
+	}
+	return carriers, nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import "github.com/my-store/pkg/apierr"
+
+// errDomain is the ErrorInfo domain of every error returned by this service.
+const errDomain apierr.Domain = "shipping.my-store"
+
+// Error reasons returned in ErrorInfo. Clients branch on them, so existing values must not change.
+const (
+	ReasonValidationFailed = "VALIDATION_FAILED"
+	ReasonShipmentExists   = "SHIPMENT_EXISTS" // no longer returned: orders may have several shipments
+	ReasonShipmentNotFound = "SHIPMENT_NOT_FOUND"
+	ReasonNoQuotes         = "NO_QUOTES"
+	ReasonServiceNotQuoted = "SERVICE_NOT_QUOTED"
+	ReasonCarrierFailed    = "CARRIER_FAILED"
+	ReasonLabelNotFound    = "LABEL_NOT_FOUND"
+	ReasonNothingToShip    = "NOTHING_TO_SHIP"
+	ReasonExceedsOrder     = "EXCEEDS_ORDER"
+	ReasonReturnExists     = "RETURN_SHIPMENT_EXISTS"
+)
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	pb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/events"
+	"github.com/my-store/pkg/outbox"
+)
+
+// topicShipmentStatusChanged carries pb.ShipmentStatusChanged events, keyed by
+// order ID so each order's changes are consumed in order.
+const topicShipmentStatusChanged = "shipment-status-changed"
+
+// eventRegistry types and versions the events the service publishes.
+var eventRegistry = events.Catalog()
+
+// publishStatusChange queues change for publishing once tx commits.
+func publishStatusChange(ctx context.Context, tx *sql.Tx, change *pb.ShipmentStatusChanged) error {
+	msg, err := eventRegistry.Message(ctx, "shipping", topicShipmentStatusChanged,
+		[]byte(strconv.FormatInt(change.OrderId, 10)), change)
+	if err != nil {
+		return err
+	}
+	return outbox.Add(ctx, tx, msg)
+}
@@ -0,0 +1,120 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/events"
+	"github.com/my-store/pkg/events/memory"
+	"github.com/my-store/pkg/kafka"
+	"github.com/my-store/pkg/outbox"
+)
+
+// TestCarrierEventsReachBroker records a parcel's scans as the webhook and the
+// simulator do, relays the outbox onto a broker and checks the
+// ShipmentStatusChanged events the order and notification services consume.
+func TestCarrierEventsReachBroker(t *testing.T) {
+	db := testDB(t)
+	store := NewShipmentStore(db)
+	if err := store.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+	shipment := createTestShipment(t, store, "ups", "1Z111")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	type published struct {
+		key   string
+		event *pb.ShipmentStatusChanged
+	}
+	var mu sync.Mutex
+	var got []published
+	broker := memory.NewBroker(3)
+	handle := func(ctx context.Context, rec *kafka.Record) error {
+		return events.Handle(eventRegistry, func(_ context.Context, _ events.Metadata, event *pb.ShipmentStatusChanged) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, published{string(rec.Key), event})
+			return nil
+		})(ctx, rec)
+	}
+	wg.Go(func() { outbox.NewRelay(db, broker).Run(ctx) })
+	wg.Go(func() { broker.Consumer("order-service", topicShipmentStatusChanged).Run(ctx, handle) })
+
+	pickedUp := time.Now().Add(-time.Hour).Truncate(time.Second)
+	scans := []struct {
+		eventID string
+		status  pb.ShipmentStatus
+		at      time.Time
+	}{
+		{"evt-1", pb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, pickedUp},
+		{"evt-2", pb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY, pickedUp.Add(2 * time.Minute)},
+		{"evt-2", pb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY, pickedUp.Add(2 * time.Minute)}, // redelivered
+		{"evt-3", pb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, pickedUp.Add(time.Minute)},           // late
+		{"evt-4", pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED, pickedUp.Add(3 * time.Minute)},
+	}
+	for _, scan := range scans {
+		_, err := recordCarrierEvent(ctx, store, "1Z111", ShipmentEvent{
+			Status: scan.status, OccurredAt: scan.at, Carrier: "ups", EventID: scan.eventID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []pb.ShipmentStatus{
+		pb.ShipmentStatus_SHIPMENT_STATUS_CREATED,
+		pb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT,
+		pb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY,
+		pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED,
+	}
+	for {
+		var committed int64
+		for p := range broker.Records(topicShipmentStatusChanged) {
+			committed += broker.Committed("order-service", topicShipmentStatusChanged, int32(p))
+		}
+		if committed >= int64(len(want)) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("%d of %d events consumed: %v", committed, len(want), ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if err := broker.WaitIdle(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	previous := pb.ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED
+	for i, p := range got {
+		e := p.event
+		if e.Status != want[i] || e.PreviousStatus != previous {
+			t.Errorf("event %d: %s after %s, want %s after %s", i, e.Status, e.PreviousStatus, want[i], previous)
+		}
+		if p.key != strconv.FormatInt(shipment.OrderID, 10) || e.OrderId != shipment.OrderID || e.TrackingId != shipment.TrackingID {
+			t.Errorf("event %d is keyed %q for order %d parcel %s, want order %d parcel %s",
+				i, p.key, e.OrderId, e.TrackingId, shipment.OrderID, shipment.TrackingID)
+		}
+		if len(e.Items) != 1 || e.Items[0].ProductId != 1 || e.Items[0].Quantity != 2 {
+			t.Errorf("event %d has items %v, want the shipment's", i, e.Items)
+		}
+		previous = want[i]
+	}
+}
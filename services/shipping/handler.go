@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	commonpb "github.com/my-store/pkg/api/common"
+	pb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/apierr"
+	"github.com/my-store/pkg/postal"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// returnsAddress is where customers send returns.
+var returnsAddress = &commonpb.Address{
+	RecipientName: "My Store Returns",
+	Line1:         "2500 Distribution Pkwy",
+	City:          "Memphis",
+	Region:        "TN",
+	PostalCode:    "38118",
+	CountryCode:   simulatedOrigin,
+}
+
+// ShippingServer implements the generated ShippingServiceServer interface.
+type ShippingServer struct {
+	pb.UnimplementedShippingServiceServer
+	store       *ShipmentStore
+	carriers    Carriers
+	policy      pb.QuotePolicy // used when a request doesn't name one
+	labelFormat LabelFormat
+}
+
+// NewShippingServer creates a new instance of our gRPC server.
+func NewShippingServer(store *ShipmentStore, carriers Carriers, policy pb.QuotePolicy, labelFormat LabelFormat) *ShippingServer {
+	return &ShippingServer{
+		store:       store,
+		carriers:    carriers,
+		policy:      policy,
+		labelFormat: labelFormat,
+	}
+}
+
+// CreateShipment books a parcel with some or all of an order's items, with the
+// requested carrier service or, if none is named, the best quote under the
+// policy. An order may be split across several shipments.
+func (s *ShippingServer) CreateShipment(ctx context.Context, req *pb.CreateShipmentRequest) (*pb.CreateShipmentResponse, error) {
+	if violations := validateShipment(req); len(violations) > 0 {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Shipment is invalid", violations)
+	}
+
+	// Checked up front so a parcel that can't be shipped doesn't buy a label;
+	// Create checks again in case another shipment claimed the items meanwhile.
+	items, err := s.store.Allocate(ctx, req.OrderId, req.Ordered, req.Items)
+	if err != nil {
+		return nil, s.allocationError(ctx, req.OrderId, err)
+	}
+
+	weight := parcelWeight(req.WeightGrams)
+	quote, err := s.selectQuote(ctx, req, weight)
+	if err != nil {
+		return nil, err
+	}
+	carrier, _ := s.carriers.Get(quote.Carrier)
+
+	trackingID, err := newTrackingID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to create shipment")
+	}
+	label, err := carrier.CreateLabel(ctx, LabelRequest{
+		Reference:   trackingID,
+		Service:     quote.Service,
+		Recipient:   req.Address,
+		WeightGrams: weight,
+		Format:      s.labelFormat,
+	})
+	carrierRequests.WithLabelValues(carrier.Name(), "label", outcome(err)).Inc()
+	if err != nil {
+		slog.ErrorContext(ctx, "Carrier failed to create label", "carrier", carrier.Name(), "order_id", req.OrderId, "error", err)
+		return nil, errDomain.Error(codes.Unavailable, ReasonCarrierFailed, "The carrier could not book the shipment")
+	}
+
+	shipment := &Shipment{
+		TrackingID:            trackingID,
+		OrderID:               req.OrderId,
+		Address:               req.Address,
+		Carrier:               quote.Carrier,
+		Service:               quote.Service,
+		CarrierTrackingNumber: label.TrackingNumber,
+		PriceCents:            quote.PriceCents,
+		Currency:              quote.Currency,
+		Items:                 items,
+	}
+	if err := s.store.Create(ctx, shipment, label, req.Ordered); err != nil {
+		// Void the label so the carrier doesn't bill for a parcel we never recorded.
+		s.cancelLabel(context.WithoutCancel(ctx), carrier, label.TrackingNumber)
+		return nil, s.allocationError(ctx, req.OrderId, err)
+	}
+	shipmentsByStatus.WithLabelValues(statusName(shipment.Status)).Inc()
+
+	return &pb.CreateShipmentResponse{
+		TrackingId: shipment.TrackingID,
+		Quote:      quote.proto(),
+		Items:      shipment.Items,
+	}, nil
+}
+
+// CreateReturnShipment books the cheapest carrier service from the customer to
+// the returns center for a return's items. A return has one shipment: booking it
+// again returns the one booked before, so callers can retry.
+func (s *ShippingServer) CreateReturnShipment(ctx context.Context, req *pb.CreateReturnShipmentRequest) (*pb.CreateShipmentResponse, error) {
+	if violations := validateReturnShipment(req); len(violations) > 0 {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Return shipment is invalid", violations)
+	}
+
+	existing, err := s.store.GetByReturnID(ctx, req.ReturnId)
+	if err == nil {
+		return returnShipmentResponse(existing), nil
+	}
+	if !errors.Is(err, ErrShipmentNotFound) {
+		slog.ErrorContext(ctx, "Failed to look up return shipment", "return_id", req.ReturnId, "error", err)
+		return nil, status.Errorf(codes.Internal, "Failed to create return shipment")
+	}
+
+	weight := parcelWeight(req.WeightGrams)
+	quotes, err := s.carriers.Quote(ctx, QuoteRequest{Destination: returnsAddress, WeightGrams: weight})
+	if err != nil {
+		slog.ErrorContext(ctx, "No carrier could quote", "return_id", req.ReturnId, "error", err)
+		return nil, errDomain.Error(codes.Unavailable, ReasonCarrierFailed, "Carriers are unavailable, try again later")
+	}
+	if len(quotes) == 0 {
+		return nil, errDomain.Error(codes.FailedPrecondition, ReasonNoQuotes, "No carrier ships to the returns center")
+	}
+	sortQuotes(quotes, pb.QuotePolicy_QUOTE_POLICY_CHEAPEST)
+	quote := quotes[0]
+	carrier, _ := s.carriers.Get(quote.Carrier)
+
+	trackingID, err := newTrackingID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to create return shipment")
+	}
+	label, err := carrier.CreateLabel(ctx, LabelRequest{
+		Reference:   trackingID,
+		Service:     quote.Service,
+		Recipient:   returnsAddress,
+		Sender:      req.Address,
+		WeightGrams: weight,
+		Format:      s.labelFormat,
+	})
+	carrierRequests.WithLabelValues(carrier.Name(), "label", outcome(err)).Inc()
+	if err != nil {
+		slog.ErrorContext(ctx, "Carrier failed to create return label", "carrier", carrier.Name(), "return_id", req.ReturnId, "error", err)
+		return nil, errDomain.Error(codes.Unavailable, ReasonCarrierFailed, "The carrier could not book the return")
+	}
+
+	shipment := &Shipment{
+		TrackingID:            trackingID,
+		OrderID:               req.OrderId,
+		Address:               returnsAddress,
+		Carrier:               quote.Carrier,
+		Service:               quote.Service,
+		CarrierTrackingNumber: label.TrackingNumber,
+		PriceCents:            quote.PriceCents,
+		Currency:              quote.Currency,
+		Items:                 mergeItems(req.Items),
+		ReturnID:              req.ReturnId,
+		Sender:                req.Address,
+	}
+	err = s.store.Create(ctx, shipment, label, nil)
+	if err != nil {
+		s.cancelLabel(context.WithoutCancel(ctx), carrier, label.TrackingNumber)
+	}
+	if errors.Is(err, ErrReturnShipmentExists) {
+		// A concurrent retry booked it first.
+		if existing, err = s.store.GetByReturnID(ctx, req.ReturnId); err == nil {
+			return returnShipmentResponse(existing), nil
+		}
+		return nil, errDomain.Error(codes.Aborted, ReasonReturnExists, "The return is being booked, try again")
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create return shipment", "return_id", req.ReturnId, "error", err)
+		return nil, status.Errorf(codes.Internal, "Failed to create return shipment")
+	}
+	shipmentsByStatus.WithLabelValues(statusName(shipment.Status)).Inc()
+
+	return &pb.CreateShipmentResponse{
+		TrackingId: shipment.TrackingID,
+		Quote:      quote.proto(),
+		Items:      shipment.Items,
+	}, nil
+}
+
+// returnShipmentResponse describes a return shipment booked earlier. Only the
+// quote's carrier, service and price are stored.
+func returnShipmentResponse(shipment *Shipment) *pb.CreateShipmentResponse {
+	return &pb.CreateShipmentResponse{
+		TrackingId: shipment.TrackingID,
+		Quote: &pb.ShippingQuote{
+			Carrier:    shipment.Carrier,
+			Service:    shipment.Service,
+			PriceCents: shipment.PriceCents,
+			Currency:   shipment.Currency,
+		},
+		Items: shipment.Items,
+	}
+}
+
+// allocationError converts an error from allocating or creating a shipment.
+func (s *ShippingServer) allocationError(ctx context.Context, orderID int64, err error) error {
+	var exceeds *ExceedsOrderError
+	switch {
+	case errors.Is(err, ErrNothingToShip):
+		return errDomain.Error(codes.FailedPrecondition, ReasonNothingToShip, "Every item of the order has already shipped")
+	case errors.As(err, &exceeds):
+		return errDomain.Error(codes.FailedPrecondition, ReasonExceedsOrder,
+			fmt.Sprintf("Only %d of product %d are left to ship", exceeds.Remaining, exceeds.ProductID))
+	}
+	slog.ErrorContext(ctx, "Failed to create shipment", "order_id", orderID, "error", err)
+	return status.Errorf(codes.Internal, "Failed to create shipment")
+}
+
+// selectQuote returns the quote for the carrier service the request names or,
+// failing that, the best under the request's policy.
+func (s *ShippingServer) selectQuote(ctx context.Context, req *pb.CreateShipmentRequest, weight int32) (Quote, error) {
+	quotes, err := s.carriers.Quote(ctx, QuoteRequest{Destination: req.Address, WeightGrams: weight})
+	if err != nil {
+		slog.ErrorContext(ctx, "No carrier could quote", "order_id", req.OrderId, "error", err)
+		return Quote{}, errDomain.Error(codes.Unavailable, ReasonCarrierFailed, "Carriers are unavailable, try again later")
+	}
+
+	if req.Carrier != "" {
+		for _, q := range quotes {
+			if q.Carrier == req.Carrier && q.Service == req.Service {
+				return q, nil
+			}
+		}
+		return Quote{}, errDomain.Error(codes.FailedPrecondition, ReasonServiceNotQuoted,
+			"The requested carrier service can't ship this parcel, get new quotes")
+	}
+
+	if len(quotes) == 0 {
+		return Quote{}, errDomain.Error(codes.FailedPrecondition, ReasonNoQuotes, "No carrier ships to this address")
+	}
+	sortQuotes(quotes, s.effectivePolicy(req.Policy))
+	return quotes[0], nil
+}
+
+func (s *ShippingServer) cancelLabel(ctx context.Context, carrier Carrier, trackingNumber string) {
+	err := carrier.Cancel(ctx, trackingNumber)
+	carrierRequests.WithLabelValues(carrier.Name(), "cancel", outcome(err)).Inc()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to cancel unused label", "carrier", carrier.Name(), "tracking_number", trackingNumber, "error", err)
+	}
+}
+
+func (s *ShippingServer) effectivePolicy(policy pb.QuotePolicy) pb.QuotePolicy {
+	if policy == pb.QuotePolicy_QUOTE_POLICY_UNSPECIFIED {
+		return s.policy
+	}
+	return policy
+}
+
+// GetShippingQuotes prices a parcel with every carrier, best first under the
+// request's policy.
+func (s *ShippingServer) GetShippingQuotes(ctx context.Context, req *pb.GetShippingQuotesRequest) (*pb.GetShippingQuotesResponse, error) {
+	violations := postal.Validate("address", req.Address)
+	if req.WeightGrams < 0 {
+		violations = append(violations, apierr.FieldViolation("weight_grams", "invalid", "Weight can't be negative"))
+	}
+	if len(violations) > 0 {
+		return nil, errDomain.InvalidArgument(ReasonValidationFailed, "Quote request is invalid", violations)
+	}
+
+	quotes, err := s.carriers.Quote(ctx, QuoteRequest{Destination: req.Address, WeightGrams: parcelWeight(req.WeightGrams)})
+	if err != nil {
+		slog.ErrorContext(ctx, "No carrier could quote", "error", err)
+		return nil, errDomain.Error(codes.Unavailable, ReasonCarrierFailed, "Carriers are unavailable, try again later")
+	}
+	sortQuotes(quotes, s.effectivePolicy(req.Policy))
+
+	resp := &pb.GetShippingQuotesResponse{Quotes: make([]*pb.ShippingQuote, len(quotes))}
+	for i, q := range quotes {
+		resp.Quotes[i] = q.proto()
+	}
+	return resp, nil
+}
+
+// GetShipmentLabel returns a shipment's label.
+func (s *ShippingServer) GetShipmentLabel(ctx context.Context, req *pb.GetShipmentLabelRequest) (*pb.GetShipmentLabelResponse, error) {
+	label, err := s.store.GetLabel(ctx, req.TrackingId)
+	if errors.Is(err, ErrShipmentNotFound) {
+		return nil, errDomain.Error(codes.NotFound, ReasonShipmentNotFound, "Shipment not found")
+	}
+	if errors.Is(err, ErrLabelNotFound) {
+		return nil, errDomain.Error(codes.NotFound, ReasonLabelNotFound, "Shipment has no label")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to load label")
+	}
+
+	return &pb.GetShipmentLabelResponse{
+		ContentType: label.Format.ContentType(),
+		Content:     label.Data,
+	}, nil
+}
+
+// parcelWeight returns the weight to quote for, defaulting an unknown one.
+func parcelWeight(grams int32) int32 {
+	if grams == 0 {
+		return defaultWeightGrams
+	}
+	return grams
+}
+
+// GetShipmentStatus returns a shipment's current status and tracking history.
+func (s *ShippingServer) GetShipmentStatus(ctx context.Context, req *pb.GetShipmentStatusRequest) (*pb.GetShipmentStatusResponse, error) {
+	shipment, err := s.store.GetByTrackingID(ctx, req.TrackingId)
+	if errors.Is(err, ErrShipmentNotFound) {
+		return nil, errDomain.Error(codes.NotFound, ReasonShipmentNotFound, "Shipment not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to load shipment")
+	}
+
+	return &pb.GetShipmentStatusResponse{
+		StatusText: statusDescription(shipment.Status),
+		Status:     shipment.Status,
+		OrderId:    shipment.OrderID,
+		History:    eventsProto(shipment.History),
+	}, nil
+}
+
+// GetShipmentTracking returns a shipment's timeline of tracking events.
+func (s *ShippingServer) GetShipmentTracking(ctx context.Context, req *pb.GetShipmentTrackingRequest) (*pb.GetShipmentTrackingResponse, error) {
+	shipment, err := s.store.GetByTrackingID(ctx, req.TrackingId)
+	if errors.Is(err, ErrShipmentNotFound) {
+		return nil, errDomain.Error(codes.NotFound, ReasonShipmentNotFound, "Shipment not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to load shipment")
+	}
+
+	return &pb.GetShipmentTrackingResponse{
+		TrackingId: shipment.TrackingID,
+		OrderId:    shipment.OrderID,
+		Status:     shipment.Status,
+		StatusText: statusDescription(shipment.Status),
+		Events:     eventsProto(shipment.History),
+
+		Carrier:               shipment.Carrier,
+		Service:               shipment.Service,
+		CarrierTrackingNumber: shipment.CarrierTrackingNumber,
+		Items:                 shipment.Items,
+		ReturnId:              shipment.ReturnID,
+	}, nil
+}
+
+func eventsProto(events []ShipmentEvent) []*pb.ShipmentEvent {
+	out := make([]*pb.ShipmentEvent, len(events))
+	for i, e := range events {
+		out[i] = &pb.ShipmentEvent{
+			Status:      e.Status,
+			Description: e.Description,
+			Location:    e.Location,
+			OccurredAt:  timestamppb.New(e.OccurredAt),
+		}
+	}
+	return out
+}
+
+// statusDescription is the text shown to customers for a status.
+func statusDescription(status pb.ShipmentStatus) string {
+	switch status {
+	case pb.ShipmentStatus_SHIPMENT_STATUS_CREATED:
+		return "Shipment created, awaiting pickup by the carrier"
+	case pb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT:
+		return "In transit"
+	case pb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY:
+		return "Out for delivery"
+	case pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED:
+		return "Delivered"
+	case pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERY_FAILED:
+		return "Delivery failed"
+	case pb.ShipmentStatus_SHIPMENT_STATUS_LOST:
+		return "Lost in transit"
+	default:
+		return "Unknown"
+	}
+}
+
+// validateShipment checks the order ID, the address and the parcel.
+func validateShipment(req *pb.CreateShipmentRequest) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	if req.OrderId <= 0 {
+		violations = append(violations, apierr.FieldViolation("order_id", "required", "Order ID is required"))
+	}
+	violations = append(violations, postal.Validate("address", req.Address)...)
+	if req.WeightGrams < 0 {
+		violations = append(violations, apierr.FieldViolation("weight_grams", "invalid", "Weight can't be negative"))
+	}
+	if (req.Carrier == "") != (req.Service == "") {
+		violations = append(violations, apierr.FieldViolation("service", "invalid", "Carrier and service must be given together"))
+	}
+	if len(req.Ordered) == 0 {
+		violations = append(violations, apierr.FieldViolation("ordered", "required", "The order's items are required"))
+	}
+	violations = append(violations, validateItems("ordered", req.Ordered)...)
+	violations = append(violations, validateItems("items", req.Items)...)
+	return violations
+}
+
+// validateReturnShipment checks the order, the return, the pickup address and
+// the parcel.
+func validateReturnShipment(req *pb.CreateReturnShipmentRequest) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	if req.OrderId <= 0 {
+		violations = append(violations, apierr.FieldViolation("order_id", "required", "Order ID is required"))
+	}
+	if req.ReturnId <= 0 {
+		violations = append(violations, apierr.FieldViolation("return_id", "required", "Return ID is required"))
+	}
+	violations = append(violations, postal.Validate("address", req.Address)...)
+	if req.WeightGrams < 0 {
+		violations = append(violations, apierr.FieldViolation("weight_grams", "invalid", "Weight can't be negative"))
+	}
+	if len(req.Items) == 0 {
+		violations = append(violations, apierr.FieldViolation("items", "required", "The returned items are required"))
+	}
+	violations = append(violations, validateItems("items", req.Items)...)
+	return violations
+}
+
+// validateItems checks the product and quantity of each item.
+func validateItems(field string, items []*pb.ShipmentItem) []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	for i, item := range items {
+		if item.ProductId <= 0 {
+			violations = append(violations, apierr.FieldViolation(
+				fmt.Sprintf("%s[%d].product_id", field, i), "required", "Product ID is required"))
+		}
+		if item.Quantity <= 0 {
+			violations = append(violations, apierr.FieldViolation(
+				fmt.Sprintf("%s[%d].quantity", field, i), "invalid", "Quantity must be at least 1"))
+		}
+	}
+	return violations
+}
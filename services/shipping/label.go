@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	commonpb "github.com/my-store/pkg/api/common"
+)
+
+// LabelFormat is the file format of a shipping label.
+type LabelFormat string
+
+const (
+	// LabelPDF prints on any printer.
+	LabelPDF LabelFormat = "pdf"
+	// LabelZPL is sent as-is to Zebra thermal label printers.
+	LabelZPL LabelFormat = "zpl"
+)
+
+// ContentType returns the MIME type of labels in the format.
+func (f LabelFormat) ContentType() string {
+	if f == LabelZPL {
+		return "application/zpl"
+	}
+	return "application/pdf"
+}
+
+// parseLabelFormat parses a LABEL_FORMAT value.
+func parseLabelFormat(s string) (LabelFormat, error) {
+	switch f := LabelFormat(strings.ToLower(s)); f {
+	case "":
+		return LabelPDF, nil
+	case LabelPDF, LabelZPL:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown label format %q (want pdf or zpl)", s)
+}
+
+// labelContent is what a label shows.
+type labelContent struct {
+	Carrier        string
+	Service        string
+	TrackingNumber string
+	Reference      string
+	WeightGrams    int32
+	Recipient      *commonpb.Address
+	Sender         *commonpb.Address // nil for parcels we send
+}
+
+// lines returns the label's text, top to bottom.
+func (c labelContent) lines() []string {
+	a := c.Recipient
+	from := "FROM: My Store Fulfillment"
+	if c.Sender != nil {
+		from = "FROM: " + c.Sender.GetRecipientName() + ", " + c.Sender.GetCity() + " " + c.Sender.GetCountryCode()
+	}
+	lines := []string{
+		from,
+		strings.ToUpper(c.Carrier + " " + c.Service),
+		fmt.Sprintf("WEIGHT: %.2f KG", float64(c.WeightGrams)/1000),
+		"",
+		"SHIP TO:",
+		a.GetRecipientName(),
+		a.GetLine1(),
+	}
+	if a.GetLine2() != "" {
+		lines = append(lines, a.GetLine2())
+	}
+	lines = append(lines,
+		strings.Join(strings.Fields(a.GetCity()+" "+a.GetRegion()+" "+a.GetPostalCode()), " "),
+		a.GetCountryCode(),
+		"",
+		"TRACKING: "+c.TrackingNumber,
+		"REF: "+c.Reference,
+	)
+	return lines
+}
+
+// renderLabel draws a 4x6 inch label in the format.
+func renderLabel(format LabelFormat, c labelContent) []byte {
+	if format == LabelZPL {
+		return renderZPL(c)
+	}
+	return renderPDF(c)
+}
+
+// renderZPL draws the label at 203 dpi with the tracking number as a Code 128
+// barcode.
+func renderZPL(c labelContent) []byte {
+	// ^ and ~ start ZPL commands, so they can't appear in field data.
+	clean := strings.NewReplacer("^", " ", "~", " ")
+
+	var b bytes.Buffer
+	b.WriteString("^XA\n^CI28\n^CF0,30\n")
+	y := 40
+	for _, line := range c.lines() {
+		if line != "" {
+			fmt.Fprintf(&b, "^FO40,%d^FD%s^FS\n", y, clean.Replace(line))
+		}
+		y += 40
+	}
+	fmt.Fprintf(&b, "^FO40,%d^BY3^BCN,150,Y,N,N^FD%s^FS\n", y+20, clean.Replace(c.TrackingNumber))
+	b.WriteString("^XZ\n")
+	return b.Bytes()
+}
+
+// renderPDF draws the label as a one-page PDF using the built-in Helvetica font.
+func renderPDF(c labelContent) []byte {
+	var content bytes.Buffer
+	content.WriteString("BT\n/F1 14 Tf\n18 TL\n24 396 Td\n")
+	for _, line := range c.lines() {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", pdfString(line))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 288 432] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// pdfString escapes s for a PDF literal string. Characters outside printable
+// ASCII, which the font's encoding would misprint, become "?".
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
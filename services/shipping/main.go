@@ -1,26 +1,97 @@
 package main
 
 import (
-	"fmt"
-	"log"
-	"net"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
+	"strconv"
+
+	pb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/kafka"
+	"github.com/my-store/pkg/logging"
+	"github.com/my-store/pkg/metrics"
+	"github.com/my-store/pkg/mtls"
+	"github.com/my-store/pkg/outbox"
+	"github.com/my-store/pkg/server"
 )
 
 func main() {
-	port := 50053
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	logging.Setup("shipping")
+
+	cfg, err := server.LoadConfig("shipping", 50053)
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	webhookPort := 8082
+	if v := os.Getenv("WEBHOOK_PORT"); v != "" {
+		if webhookPort, err = strconv.Atoi(v); err != nil {
+			logging.Fatal("Invalid WEBHOOK_PORT", "error", err)
+		}
+	}
+	webhookSecrets, err := loadWebhookSecrets()
+	if err != nil {
+		logging.Fatal("Invalid carrier webhook configuration", "error", err)
+	}
+	sim, err := loadSimulation()
+	if err != nil {
+		logging.Fatal("Invalid simulator configuration", "error", err)
+	}
+	carriers, err := loadCarriers(sim)
+	if err != nil {
+		logging.Fatal("Invalid carrier configuration", "error", err)
+	}
+	policy, err := parsePolicy(os.Getenv("SHIPPING_QUOTE_POLICY"))
+	if err != nil {
+		logging.Fatal("Invalid SHIPPING_QUOTE_POLICY", "error", err)
+	}
+	labelFormat, err := parseLabelFormat(os.Getenv("LABEL_FORMAT"))
+	if err != nil {
+		logging.Fatal("Invalid LABEL_FORMAT", "error", err)
+	}
+	// Shipments are created for orders whose ownership the BFF has checked, and
+	// their labels carry the customer's address.
+	srv := server.New(cfg, server.WithAuthorization(mtls.Rules{
+		pb.ShippingService_CreateShipment_FullMethodName:       {"bff"},
+		pb.ShippingService_CreateReturnShipment_FullMethodName: {"bff"},
+		pb.ShippingService_GetShipmentLabel_FullMethodName:     {"bff"},
+	}))
+
+	// 1. Connect to Database
+	db, err := srv.OpenDB()
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logging.Fatal("Failed to connect to database", "error", err)
+	}
+
+	// 2. Initialize Store & Schema
+	store := NewShipmentStore(db)
+	if err := store.InitSchema(); err != nil {
+		logging.Fatal("Failed to run migrations", "error", err)
 	}
-	defer lis.Close()
-	log.Printf("Shipping Service listening on port %d", port)
 
-	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+	// 3. Register Handlers
+	pb.RegisterShippingServiceServer(srv, NewShippingServer(store, carriers, policy, labelFormat))
+	webhooks := &webhookHandler{store: store, secrets: webhookSecrets}
+	srv.AddHTTPHandler(webhookPort, metrics.InstrumentHandler(webhooks.routes()))
+
+	// Locally there is no carrier to move parcels along; the simulator plays it.
+	if sim.Enabled {
+		srv.AddWorker(newSimulator(store, sim).Run)
+	}
+
+	// Status changes are written to the outbox with the shipment and published
+	// from there, so an event is never lost or sent for a rolled-back change.
+	if len(cfg.KafkaBrokers) > 0 {
+		producer, err := kafka.NewProducer(cfg.KafkaBrokers)
+		if err != nil {
+			logging.Fatal("Failed to create Kafka producer", "error", err)
+		}
+		srv.AddCloser(producer)
+		srv.AddWorker(outbox.NewRelay(db, producer).Run)
+	} else {
+		slog.Warn("KAFKA_BROKERS is not set; shipment events stay in the outbox")
+	}
+
+	// 4. Serve until SIGINT/SIGTERM, then drain in-flight calls
+	if err := srv.Run(); err != nil {
+		logging.Fatal("Shipping service failed", "error", err)
+	}
 }
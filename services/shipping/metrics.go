@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// shipmentsByStatus counts shipments entering each status (CREATED, SHIPPED,
+// DELIVERED, ...). It is exported as soon as the first shipment is recorded.
+var shipmentsByStatus = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "shipments_total",
+	Help: "Shipments that entered each status.",
+}, []string{"status"})
+
+// carrierRequests counts calls to carrier APIs by operation (quote, label, cancel,
+// track) and outcome (ok, error).
+var carrierRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "shipping_carrier_requests_total",
+	Help: "Requests to carrier APIs.",
+}, []string{"carrier", "operation", "outcome"})
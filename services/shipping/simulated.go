@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	commonpb "github.com/my-store/pkg/api/common"
+)
+
+// simulatedCarrierName is the name webhooks and stored shipments use for the
+// simulated carrier.
+const simulatedCarrierName = "simulated"
+
+// simulatedOrigin is the country the simulated carrier ships from; elsewhere is
+// international.
+const simulatedOrigin = "US"
+
+// ErrLabelUsed is returned when cancelling a label the carrier has already
+// picked up.
+var ErrLabelUsed = errors.New("label already used")
+
+// simulatedService is a service the simulated carrier offers.
+type simulatedService struct {
+	code, name    string
+	baseCents     int64 // price of the first 500 g in the nearest zone
+	perStepCents  int64 // each further 500 g
+	perZoneCents  int64
+	days          int32
+	extraIntlDays int32
+	domesticOnly  bool
+}
+
+var simulatedServices = []simulatedService{
+	{code: "ground", name: "Ground", baseCents: 599, perStepCents: 60, perZoneCents: 50, days: 5, extraIntlDays: 5},
+	{code: "express", name: "Express", baseCents: 1499, perStepCents: 120, perZoneCents: 100, days: 2, extraIntlDays: 2},
+	{code: "overnight", name: "Overnight", baseCents: 2999, perStepCents: 200, perZoneCents: 150, days: 1, domesticOnly: true},
+}
+
+// simulatedLabel is a label the simulated carrier has issued.
+type simulatedLabel struct {
+	service   simulatedService
+	recipient *commonpb.Address
+	sender    *commonpb.Address
+	createdAt time.Time
+	cancelled bool
+}
+
+// simulatedCarrier is a stand-in carrier for local runs and tests. Its quotes and
+// tracking numbers depend only on the request, so runs are reproducible. Labels
+// are kept in memory, so Cancel and Track only know the labels issued since the
+// process started; the simulator drives shipments from the database instead.
+type simulatedCarrier struct {
+	sim simulation
+	now func() time.Time
+
+	mu     sync.Mutex
+	labels map[string]*simulatedLabel
+}
+
+func newSimulatedCarrier(sim simulation) *simulatedCarrier {
+	return &simulatedCarrier{sim: sim, now: time.Now, labels: make(map[string]*simulatedLabel)}
+}
+
+func (c *simulatedCarrier) Name() string { return simulatedCarrierName }
+
+// Quote prices the services by weight, in 500 g steps, and by a zone derived from
+// the destination postal code.
+func (c *simulatedCarrier) Quote(_ context.Context, req QuoteRequest) ([]Quote, error) {
+	country := strings.ToUpper(req.Destination.GetCountryCode())
+	intl := country != simulatedOrigin
+	zone := simulatedZone(country, req.Destination.GetPostalCode())
+	steps := (int64(req.WeightGrams) - 1) / 500
+
+	var quotes []Quote
+	for _, s := range simulatedServices {
+		if s.domesticOnly && intl {
+			continue
+		}
+		q := Quote{
+			Carrier:       simulatedCarrierName,
+			Service:       s.code,
+			ServiceName:   s.name,
+			PriceCents:    s.baseCents + steps*s.perStepCents + zone*s.perZoneCents,
+			Currency:      "USD",
+			EstimatedDays: s.days,
+		}
+		if intl {
+			q.PriceCents *= 3
+			q.EstimatedDays += s.extraIntlDays
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, nil
+}
+
+// simulatedZone spreads destinations over zones 0 to 4.
+func simulatedZone(country, postalCode string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(country + "/" + strings.ToUpper(strings.ReplaceAll(postalCode, " ", ""))))
+	return int64(h.Sum32() % 5)
+}
+
+// CreateLabel issues a label. The tracking number is derived from the reference,
+// so booking the same shipment again returns the same number.
+func (c *simulatedCarrier) CreateLabel(_ context.Context, req LabelRequest) (*Label, error) {
+	var service *simulatedService
+	for i := range simulatedServices {
+		if simulatedServices[i].code == req.Service {
+			service = &simulatedServices[i]
+		}
+	}
+	if service == nil {
+		return nil, fmt.Errorf("unknown service %q", req.Service)
+	}
+
+	sum := sha256.Sum256([]byte(req.Reference))
+	trackingNumber := "SIM" + strings.ToUpper(hex.EncodeToString(sum[:8]))
+
+	c.mu.Lock()
+	c.labels[trackingNumber] = &simulatedLabel{
+		service:   *service,
+		recipient: req.Recipient,
+		sender:    req.Sender,
+		createdAt: c.now(),
+	}
+	c.mu.Unlock()
+
+	return &Label{
+		TrackingNumber: trackingNumber,
+		Format:         req.Format,
+		Data: renderLabel(req.Format, labelContent{
+			Carrier:        simulatedCarrierName,
+			Service:        service.name,
+			TrackingNumber: trackingNumber,
+			Reference:      req.Reference,
+			WeightGrams:    req.WeightGrams,
+			Recipient:      req.Recipient,
+			Sender:         req.Sender,
+		}),
+	}, nil
+}
+
+// Cancel voids a label until the parcel is picked up, one simulation step after
+// booking.
+func (c *simulatedCarrier) Cancel(_ context.Context, trackingNumber string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	label, ok := c.labels[trackingNumber]
+	if !ok {
+		return ErrUnknownTrackingNumber
+	}
+	if label.cancelled {
+		return nil
+	}
+	if c.now().Sub(label.createdAt) >= c.sim.Step {
+		return ErrLabelUsed
+	}
+	label.cancelled = true
+	return nil
+}
+
+// Track returns the scans of the parcel's itinerary (see simulation.itinerary)
+// that are due.
+func (c *simulatedCarrier) Track(_ context.Context, trackingNumber string) ([]ShipmentEvent, error) {
+	c.mu.Lock()
+	label, ok := c.labels[trackingNumber]
+	var copied simulatedLabel
+	if ok {
+		copied = *label
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, ErrUnknownTrackingNumber
+	}
+	if copied.cancelled {
+		return nil, nil
+	}
+
+	now := c.now()
+	var events []ShipmentEvent
+	for _, e := range c.sim.itinerary(trackingNumber, copied.createdAt, copied.sender, copied.recipient) {
+		if e.OccurredAt.After(now) {
+			break
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
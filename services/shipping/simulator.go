@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	commonpb "github.com/my-store/pkg/api/common"
+	pb "github.com/my-store/pkg/api/shipping"
+)
+
+// simulatorPollInterval is how often the simulator looks for scans that are due.
+const simulatorPollInterval = 5 * time.Second
+
+// simulation configures how parcels booked with the simulated carrier progress.
+type simulation struct {
+	Enabled bool          // run the simulator worker
+	Step    time.Duration // time between scans
+	// Share of parcels, in percent, that are lost at the hub or whose delivery
+	// fails. Which parcels is decided by their tracking number, so reruns match.
+	LostPercent   int
+	FailedPercent int
+}
+
+// loadSimulation reads the simulator settings from the environment.
+func loadSimulation() (simulation, error) {
+	sim := simulation{Step: time.Minute}
+	var err error
+	if v := os.Getenv("SHIPMENT_SIMULATOR"); v != "" {
+		if sim.Enabled, err = strconv.ParseBool(v); err != nil {
+			return sim, fmt.Errorf("invalid SHIPMENT_SIMULATOR: %w", err)
+		}
+	}
+	if v := os.Getenv("SIMULATOR_STEP_INTERVAL"); v != "" {
+		if sim.Step, err = time.ParseDuration(v); err != nil || sim.Step <= 0 {
+			return sim, fmt.Errorf("invalid SIMULATOR_STEP_INTERVAL %q", v)
+		}
+	}
+	for key, dst := range map[string]*int{
+		"SIMULATOR_LOST_PERCENT":            &sim.LostPercent,
+		"SIMULATOR_FAILED_DELIVERY_PERCENT": &sim.FailedPercent,
+	} {
+		if v := os.Getenv(key); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil || *dst < 0 || *dst > 100 {
+				return sim, fmt.Errorf("invalid %s %q (want 0 to 100)", key, v)
+			}
+		}
+	}
+	if sim.LostPercent+sim.FailedPercent > 100 {
+		return sim, errors.New("SIMULATOR_LOST_PERCENT and SIMULATOR_FAILED_DELIVERY_PERCENT add up to more than 100")
+	}
+	return sim, nil
+}
+
+// itinerary returns every scan the simulated carrier makes of a parcel booked at
+// bookedAt, one step apart: pickup, hub, then out for delivery and delivered.
+// A lost parcel's scans end at the hub, and a failed delivery ends the trip
+// instead of the delivery. Parcels without an origin are picked up at our
+// warehouse.
+func (sim simulation) itinerary(trackingNumber string, bookedAt time.Time, origin, destination *commonpb.Address) []ShipmentEvent {
+	h := fnv.New32a()
+	h.Write([]byte(trackingNumber))
+	roll := int(h.Sum32() % 100)
+	lost := roll < sim.LostPercent
+	failed := !lost && roll < sim.LostPercent+sim.FailedPercent
+
+	dest := destination.GetCity() + ", " + destination.GetCountryCode()
+	pickup := "Memphis, " + simulatedOrigin
+	if origin != nil {
+		pickup = origin.GetCity() + ", " + origin.GetCountryCode()
+	}
+	events := []ShipmentEvent{
+		{Status: pb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, Description: "Picked up by carrier", Location: pickup},
+		{Status: pb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT, Description: "Arrived at sorting hub", Location: "Louisville, " + simulatedOrigin},
+	}
+	switch {
+	case lost:
+		events = append(events,
+			ShipmentEvent{Status: pb.ShipmentStatus_SHIPMENT_STATUS_LOST, Description: "Parcel could not be located", Location: "Louisville, " + simulatedOrigin})
+	case failed:
+		events = append(events,
+			ShipmentEvent{Status: pb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY, Description: "Out for delivery", Location: dest},
+			ShipmentEvent{Status: pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERY_FAILED, Description: "Delivery attempted, recipient not available", Location: dest})
+	default:
+		events = append(events,
+			ShipmentEvent{Status: pb.ShipmentStatus_SHIPMENT_STATUS_OUT_FOR_DELIVERY, Description: "Out for delivery", Location: dest},
+			ShipmentEvent{Status: pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED, Description: "Delivered", Location: dest})
+	}
+
+	for i := range events {
+		events[i].OccurredAt = bookedAt.Add(time.Duration(i+1) * sim.Step)
+		events[i].Carrier = simulatedCarrierName
+		events[i].EventID = fmt.Sprintf("%s-%d", trackingNumber, i+1)
+	}
+	return events
+}
+
+// simulator plays the simulated carrier's part for its parcels: it records each
+// scan as it falls due, exactly as the carrier's webhook would report it, so the
+// status changes reach the outbox and the order service like real ones.
+type simulator struct {
+	store *ShipmentStore
+	sim   simulation
+	now   func() time.Time
+}
+
+func newSimulator(store *ShipmentStore, sim simulation) *simulator {
+	return &simulator{store: store, sim: sim, now: time.Now}
+}
+
+// Run records due scans until ctx is cancelled. Replicas may each run one; a scan
+// recorded twice is dropped as a duplicate.
+func (s *simulator) Run(ctx context.Context) {
+	slog.Info("Shipment simulator started", "step", s.sim.Step,
+		"lost_percent", s.sim.LostPercent, "failed_delivery_percent", s.sim.FailedPercent)
+
+	ticker := time.NewTicker(simulatorPollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.advance(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Shipment simulator failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// advance records the scans that have fallen due since the last pass.
+func (s *simulator) advance(ctx context.Context) error {
+	shipments, err := s.store.ListInFlight(ctx, simulatedCarrierName)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	for _, shipment := range shipments {
+		events := s.sim.itinerary(shipment.CarrierTrackingNumber, shipment.CreatedAt, shipment.Sender, shipment.Address)
+		for _, event := range events[min(shipment.CarrierEvents, len(events)):] {
+			if event.OccurredAt.After(now) {
+				break
+			}
+			if _, err := recordCarrierEvent(ctx, s.store, shipment.CarrierTrackingNumber, event); err != nil {
+				return fmt.Errorf("failed to record scan of %s: %w", shipment.TrackingID, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	commonpb "github.com/my-store/pkg/api/common"
+	pb "github.com/my-store/pkg/api/shipping"
+	"github.com/my-store/pkg/outbox"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// uniqueViolation is the Postgres error code for a unique constraint violation.
+const uniqueViolation = "23505"
+
+var (
+	// ErrShipmentNotFound is returned when no shipment has the requested tracking ID.
+	ErrShipmentNotFound = errors.New("shipment not found")
+	// ErrLabelNotFound is returned for shipments booked before labels were stored.
+	ErrLabelNotFound = errors.New("shipment has no label")
+	// ErrReturnShipmentExists is returned when the return already has a shipment.
+	ErrReturnShipmentExists = errors.New("return already has a shipment")
+)
+
+// Shipment is a parcel sent for an order or, if ReturnID is set, sent back by
+// the customer for a return.
+type Shipment struct {
+	ID         int64
+	TrackingID string
+	OrderID    int64
+	Address    *commonpb.Address
+	Status     pb.ShipmentStatus
+	CreatedAt  time.Time
+	History    []ShipmentEvent // oldest first
+	Items      []*pb.ShipmentItem
+
+	// The carrier service the shipment is booked with.
+	Carrier               string
+	Service               string
+	CarrierTrackingNumber string
+	PriceCents            int64
+	Currency              string
+
+	ReturnID int64
+	Sender   *commonpb.Address // where a return is picked up; nil otherwise
+}
+
+// ShipmentEvent is an entry in a shipment's tracking history: its creation, or a
+// scan reported by the carrier.
+type ShipmentEvent struct {
+	Status      pb.ShipmentStatus
+	Description string
+	Location    string
+	OccurredAt  time.Time
+	Carrier     string // set for carrier events
+	EventID     string // the carrier's ID for the event, used to drop duplicates
+}
+
+// EventResult describes what recording a carrier event changed.
+type EventResult struct {
+	Duplicate bool // the carrier had already reported the event; nothing changed
+	Previous  pb.ShipmentStatus
+	Status    pb.ShipmentStatus // equal to Previous unless the event changed the status
+}
+
+// InFlightShipment is a shipment its carrier hasn't finished with.
+type InFlightShipment struct {
+	TrackingID            string
+	CarrierTrackingNumber string
+	Address               *commonpb.Address
+	Sender                *commonpb.Address
+	CreatedAt             time.Time
+	CarrierEvents         int // scans the carrier has reported so far
+}
+
+// ShipmentStore handles database interactions for shipments.
+type ShipmentStore struct {
+	db *sql.DB
+}
+
+// NewShipmentStore initializes the store with a database connection.
+func NewShipmentStore(db *sql.DB) *ShipmentStore {
+	return &ShipmentStore{db: db}
+}
+
+// InitSchema creates the shipment tables if they don't exist.
+func (s *ShipmentStore) InitSchema() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS shipments (
+		id SERIAL PRIMARY KEY,
+		tracking_id TEXT UNIQUE NOT NULL,
+		order_id BIGINT NOT NULL,
+		address JSONB NOT NULL,
+		status TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS shipment_events (
+		id SERIAL PRIMARY KEY,
+		shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+		status TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS shipment_events_shipment_id ON shipment_events (shipment_id, occurred_at);
+	ALTER TABLE shipment_events ADD COLUMN IF NOT EXISTS location TEXT NOT NULL DEFAULT '';
+	ALTER TABLE shipment_events ADD COLUMN IF NOT EXISTS carrier TEXT;
+	ALTER TABLE shipment_events ADD COLUMN IF NOT EXISTS carrier_event_id TEXT;
+	CREATE UNIQUE INDEX IF NOT EXISTS shipment_events_carrier_event ON shipment_events (carrier, carrier_event_id);
+
+	ALTER TABLE shipments ADD COLUMN IF NOT EXISTS carrier TEXT NOT NULL DEFAULT '';
+	ALTER TABLE shipments ADD COLUMN IF NOT EXISTS service TEXT NOT NULL DEFAULT '';
+	ALTER TABLE shipments ADD COLUMN IF NOT EXISTS carrier_tracking_number TEXT NOT NULL DEFAULT '';
+	ALTER TABLE shipments ADD COLUMN IF NOT EXISTS price_cents BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE shipments ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT '';
+	ALTER TABLE shipments ADD COLUMN IF NOT EXISTS label_format TEXT NOT NULL DEFAULT '';
+	ALTER TABLE shipments ADD COLUMN IF NOT EXISTS label BYTEA;
+	CREATE INDEX IF NOT EXISTS shipments_carrier_tracking_number ON shipments (carrier, carrier_tracking_number);
+
+	-- An order may be split across several shipments; databases created before
+	-- that have a unique order_id.
+	ALTER TABLE shipments DROP CONSTRAINT IF EXISTS shipments_order_id_key;
+	CREATE INDEX IF NOT EXISTS shipments_order_id ON shipments (order_id);
+	CREATE TABLE IF NOT EXISTS shipment_items (
+		shipment_id BIGINT NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+		product_id BIGINT NOT NULL,
+		quantity INT NOT NULL CHECK (quantity > 0),
+		PRIMARY KEY (shipment_id, product_id)
+	);
+
+	ALTER TABLE shipments ADD COLUMN IF NOT EXISTS return_id BIGINT;
+	ALTER TABLE shipments ADD COLUMN IF NOT EXISTS sender JSONB;
+	CREATE UNIQUE INDEX IF NOT EXISTS shipments_return_id ON shipments (return_id);`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+	return outbox.InitSchema(s.db)
+}
+
+// Allocate returns what a new shipment of the order would contain: the requested
+// items or, if none are requested, everything not yet in a shipment. It fails
+// with ErrNothingToShip or ErrExceedsOrder. Create checks again under a lock.
+func (s *ShipmentStore) Allocate(ctx context.Context, orderID int64, ordered, requested []*pb.ShipmentItem) ([]*pb.ShipmentItem, error) {
+	shipped, err := shippedQuantities(ctx, s.db, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return allocate(ordered, shipped, requested)
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// shippedQuantities sums each product of the order over its shipments. Parcels
+// that were lost or couldn't be delivered don't count, so their items can be
+// shipped again, and neither do returns.
+func shippedQuantities(ctx context.Context, q queryer, orderID int64) (map[int64]int32, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT i.product_id, SUM(i.quantity)
+		FROM shipment_items i JOIN shipments s ON s.id = i.shipment_id
+		WHERE s.order_id = $1 AND s.return_id IS NULL AND s.status <> ALL($2)
+		GROUP BY i.product_id`, orderID, []string{
+		statusName(pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERY_FAILED),
+		statusName(pb.ShipmentStatus_SHIPMENT_STATUS_LOST),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipped quantities: %w", err)
+	}
+	defer rows.Close()
+
+	shipped := make(map[int64]int32)
+	for rows.Next() {
+		var productID int64
+		var quantity int32
+		if err := rows.Scan(&productID, &quantity); err != nil {
+			return nil, err
+		}
+		shipped[productID] = quantity
+	}
+	return shipped, rows.Err()
+}
+
+// Create records a booked shipment of shipment.Items, which must fit in what is
+// left of ordered, with its label and first tracking event, and publishes its
+// creation. It sets the shipment's ID, CreatedAt, Status and History. Return
+// shipments aren't checked against the order; a second one for the same return
+// fails with ErrReturnShipmentExists.
+func (s *ShipmentStore) Create(ctx context.Context, shipment *Shipment, label *Label, ordered []*pb.ShipmentItem) error {
+	addressJSON, err := json.Marshal(shipment.Address)
+	if err != nil {
+		return fmt.Errorf("failed to marshal address: %w", err)
+	}
+	var senderJSON []byte
+	if shipment.Sender != nil {
+		if senderJSON, err = json.Marshal(shipment.Sender); err != nil {
+			return fmt.Errorf("failed to marshal sender: %w", err)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if shipment.ReturnID == 0 {
+		// Serializes shipments of the order so two can't claim the same items.
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, shipment.OrderID); err != nil {
+			return fmt.Errorf("failed to lock order: %w", err)
+		}
+		shipped, err := shippedQuantities(ctx, tx, shipment.OrderID)
+		if err != nil {
+			return err
+		}
+		if _, err := allocate(ordered, shipped, shipment.Items); err != nil {
+			return err
+		}
+	}
+
+	shipment.Status = pb.ShipmentStatus_SHIPMENT_STATUS_CREATED
+	query := `
+		INSERT INTO shipments (tracking_id, order_id, address, status, carrier, service,
+			carrier_tracking_number, price_cents, currency, label_format, label, return_id, sender)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, 0), $13)
+		RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query, shipment.TrackingID, shipment.OrderID, addressJSON, statusName(shipment.Status),
+		shipment.Carrier, shipment.Service, shipment.CarrierTrackingNumber, shipment.PriceCents, shipment.Currency,
+		string(label.Format), label.Data, shipment.ReturnID, senderJSON).Scan(&shipment.ID, &shipment.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "shipments_return_id" {
+			return ErrReturnShipmentExists
+		}
+		return fmt.Errorf("failed to insert shipment: %w", err)
+	}
+	for _, item := range shipment.Items {
+		query = `INSERT INTO shipment_items (shipment_id, product_id, quantity) VALUES ($1, $2, $3)`
+		if _, err := tx.ExecContext(ctx, query, shipment.ID, item.ProductId, item.Quantity); err != nil {
+			return fmt.Errorf("failed to insert shipment item: %w", err)
+		}
+	}
+
+	event := ShipmentEvent{Status: shipment.Status, Description: statusDescription(shipment.Status), OccurredAt: shipment.CreatedAt}
+	query = `INSERT INTO shipment_events (shipment_id, status, description, occurred_at) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, query, shipment.ID, statusName(event.Status), event.Description, event.OccurredAt); err != nil {
+		return fmt.Errorf("failed to insert shipment event: %w", err)
+	}
+	err = publishStatusChange(ctx, tx, &pb.ShipmentStatusChanged{
+		TrackingId: shipment.TrackingID,
+		OrderId:    shipment.OrderID,
+		Status:     shipment.Status,
+		OccurredAt: timestamppb.New(shipment.CreatedAt),
+		Items:      shipment.Items,
+		ReturnId:   shipment.ReturnID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit shipment: %w", err)
+	}
+
+	shipment.History = []ShipmentEvent{event}
+	return nil
+}
+
+// GetByTrackingID retrieves a shipment and its tracking history.
+func (s *ShipmentStore) GetByTrackingID(ctx context.Context, trackingID string) (*Shipment, error) {
+	return s.get(ctx, "tracking_id = $1", trackingID)
+}
+
+// GetByReturnID retrieves a return's shipment and its tracking history.
+func (s *ShipmentStore) GetByReturnID(ctx context.Context, returnID int64) (*Shipment, error) {
+	return s.get(ctx, "return_id = $1", returnID)
+}
+
+// get retrieves the shipment matching where, which compares a unique column to
+// arg, with its items and tracking history.
+func (s *ShipmentStore) get(ctx context.Context, where string, arg any) (*Shipment, error) {
+	query := `
+		SELECT id, tracking_id, order_id, address, status, created_at,
+			carrier, service, carrier_tracking_number, price_cents, currency,
+			COALESCE(return_id, 0), sender
+		FROM shipments WHERE ` + where
+
+	var shipment Shipment
+	var addressJSON, senderJSON []byte
+	var status string
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(&shipment.ID, &shipment.TrackingID, &shipment.OrderID, &addressJSON, &status, &shipment.CreatedAt,
+		&shipment.Carrier, &shipment.Service, &shipment.CarrierTrackingNumber, &shipment.PriceCents, &shipment.Currency,
+		&shipment.ReturnID, &senderJSON)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrShipmentNotFound
+		}
+		return nil, err
+	}
+	shipment.Status = parseStatus(status)
+	if err := json.Unmarshal(addressJSON, &shipment.Address); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal address: %w", err)
+	}
+	if senderJSON != nil {
+		if err := json.Unmarshal(senderJSON, &shipment.Sender); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal sender: %w", err)
+		}
+	}
+
+	if shipment.Items, err = shipmentItems(ctx, s.db, shipment.ID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT status, description, location, occurred_at FROM shipment_events
+		WHERE shipment_id = $1 ORDER BY occurred_at, id`, shipment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipment events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event ShipmentEvent
+		if err := rows.Scan(&status, &event.Description, &event.Location, &event.OccurredAt); err != nil {
+			return nil, err
+		}
+		event.Status = parseStatus(status)
+		shipment.History = append(shipment.History, event)
+	}
+	return &shipment, rows.Err()
+}
+
+// shipmentItems returns what a shipment contains, by product.
+func shipmentItems(ctx context.Context, q queryer, shipmentID int64) ([]*pb.ShipmentItem, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT product_id, quantity FROM shipment_items
+		WHERE shipment_id = $1 ORDER BY product_id`, shipmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipment items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*pb.ShipmentItem
+	for rows.Next() {
+		var item pb.ShipmentItem
+		if err := rows.Scan(&item.ProductId, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+// ListInFlight returns the carrier's shipments that haven't reached a final
+// status, oldest first.
+func (s *ShipmentStore) ListInFlight(ctx context.Context, carrier string) ([]InFlightShipment, error) {
+	final := []string{
+		statusName(pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED),
+		statusName(pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERY_FAILED),
+		statusName(pb.ShipmentStatus_SHIPMENT_STATUS_LOST),
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.tracking_id, s.carrier_tracking_number, s.address, s.sender, s.created_at,
+			(SELECT COUNT(*) FROM shipment_events e WHERE e.shipment_id = s.id AND e.carrier = s.carrier)
+		FROM shipments s
+		WHERE s.carrier = $1 AND s.status <> ALL($2)
+		ORDER BY s.id`, carrier, final)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipments in flight: %w", err)
+	}
+	defer rows.Close()
+
+	var shipments []InFlightShipment
+	for rows.Next() {
+		var shipment InFlightShipment
+		var addressJSON, senderJSON []byte
+		if err := rows.Scan(&shipment.TrackingID, &shipment.CarrierTrackingNumber, &addressJSON, &senderJSON, &shipment.CreatedAt, &shipment.CarrierEvents); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(addressJSON, &shipment.Address); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal address: %w", err)
+		}
+		if senderJSON != nil {
+			if err := json.Unmarshal(senderJSON, &shipment.Sender); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal sender: %w", err)
+			}
+		}
+		shipments = append(shipments, shipment)
+	}
+	return shipments, rows.Err()
+}
+
+// GetLabel retrieves a shipment's label.
+func (s *ShipmentStore) GetLabel(ctx context.Context, trackingID string) (*Label, error) {
+	query := `SELECT carrier_tracking_number, label_format, label FROM shipments WHERE tracking_id = $1`
+
+	var label Label
+	var format string
+	err := s.db.QueryRowContext(ctx, query, trackingID).Scan(&label.TrackingNumber, &format, &label.Data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrShipmentNotFound
+		}
+		return nil, err
+	}
+	if label.Data == nil {
+		return nil, ErrLabelNotFound
+	}
+	label.Format = LabelFormat(format)
+	return &label, nil
+}
+
+// RecordEvent adds a carrier event to the shipment's history. trackingNumber is
+// the carrier's, and only shipments booked with event.Carrier match it, so a
+// carrier can't report on another carrier's parcels. The shipment takes
+// the event's status if the event is the latest one so far and the shipment
+// hasn't reached a final status; late or out-of-order scans only fill in the
+// history. A status change is published (see publishStatusChange) in the same
+// transaction.
+func (s *ShipmentStore) RecordEvent(ctx context.Context, trackingNumber string, event ShipmentEvent) (*EventResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var shipmentID, orderID, returnID int64
+	var current string
+	var ourTrackingID string
+	query := `
+		SELECT id, tracking_id, order_id, status, COALESCE(return_id, 0) FROM shipments
+		WHERE carrier = $2 AND carrier_tracking_number = $1
+		FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, trackingNumber, event.Carrier).Scan(&shipmentID, &ourTrackingID, &orderID, &current, &returnID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrShipmentNotFound
+		}
+		return nil, err
+	}
+	result := &EventResult{Previous: parseStatus(current), Status: parseStatus(current)}
+
+	// Carriers retry webhooks they think failed; a redelivered event is a no-op.
+	query = `
+		INSERT INTO shipment_events (shipment_id, status, description, location, occurred_at, carrier, carrier_event_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (carrier, carrier_event_id) DO NOTHING`
+	res, err := tx.ExecContext(ctx, query, shipmentID, statusName(event.Status), event.Description, event.Location, event.OccurredAt, event.Carrier, event.EventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert shipment event: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		result.Duplicate = true
+		return result, nil
+	}
+
+	var latest bool
+	query = `SELECT NOT EXISTS (SELECT 1 FROM shipment_events WHERE shipment_id = $1 AND occurred_at > $2)`
+	if err := tx.QueryRowContext(ctx, query, shipmentID, event.OccurredAt).Scan(&latest); err != nil {
+		return nil, fmt.Errorf("failed to order shipment event: %w", err)
+	}
+
+	if latest && !isFinal(result.Previous) && event.Status != result.Previous {
+		query = `UPDATE shipments SET status = $2, updated_at = NOW() WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, shipmentID, statusName(event.Status)); err != nil {
+			return nil, fmt.Errorf("failed to update shipment status: %w", err)
+		}
+		result.Status = event.Status
+		items, err := shipmentItems(ctx, tx, shipmentID)
+		if err != nil {
+			return nil, err
+		}
+		change := &pb.ShipmentStatusChanged{
+			TrackingId:     ourTrackingID,
+			OrderId:        orderID,
+			Status:         event.Status,
+			PreviousStatus: result.Previous,
+			Location:       event.Location,
+			OccurredAt:     timestamppb.New(event.OccurredAt),
+			Items:          items,
+			ReturnId:       returnID,
+		}
+		if err := publishStatusChange(ctx, tx, change); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit shipment event: %w", err)
+	}
+	return result, nil
+}
+
+// isFinal reports whether a shipment can no longer change status.
+func isFinal(status pb.ShipmentStatus) bool {
+	switch status {
+	case pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERED,
+		pb.ShipmentStatus_SHIPMENT_STATUS_DELIVERY_FAILED,
+		pb.ShipmentStatus_SHIPMENT_STATUS_LOST:
+		return true
+	}
+	return false
+}
+
+// newTrackingID returns a random tracking ID such as "MS7K2QX4ZP9DMA3VTB". It is
+// unguessable, so knowing one is what entitles a caller to see the shipment.
+func newTrackingID() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate tracking id: %w", err)
+	}
+	return "MS" + base32.StdEncoding.EncodeToString(b), nil
+}
+
+// statusName is the name a status is stored under, e.g. "IN_TRANSIT".
+func statusName(status pb.ShipmentStatus) string {
+	return strings.TrimPrefix(status.String(), "SHIPMENT_STATUS_")
+}
+
+func parseStatus(name string) pb.ShipmentStatus {
+	return pb.ShipmentStatus(pb.ShipmentStatus_value["SHIPMENT_STATUS_"+name])
+}
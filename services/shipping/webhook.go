@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/my-store/pkg/api/shipping"
+)
+
+// Carriers sign webhooks with a shared secret. The signature header has the form
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">". Covering the timestamp
+// lets the receiver reject replays of old requests.
+const (
+	signatureHeader    = "X-Carrier-Signature"
+	signatureTolerance = 5 * time.Minute
+	maxWebhookBody     = 64 << 10
+)
+
+// carrierEventJSON is the body of a carrier status webhook.
+type carrierEventJSON struct {
+	EventID     string    `json:"event_id"`
+	TrackingID  string    `json:"tracking_id"` // the carrier's tracking number
+	Status      string    `json:"status"` // e.g. "IN_TRANSIT"
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// webhookHandler receives carrier status updates.
+type webhookHandler struct {
+	store   *ShipmentStore
+	secrets map[string][]byte // webhook secret by carrier name
+}
+
+// loadWebhookSecrets reads the carriers named in CARRIER_WEBHOOKS and the secret
+// of each from CARRIER_<NAME>_WEBHOOK_SECRET.
+func loadWebhookSecrets() (map[string][]byte, error) {
+	secrets := make(map[string][]byte)
+	for _, name := range strings.Split(os.Getenv("CARRIER_WEBHOOKS"), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		key := "CARRIER_" + strings.ToUpper(name) + "_WEBHOOK_SECRET"
+		secret := os.Getenv(key)
+		if secret == "" {
+			return nil, fmt.Errorf("%s is not set", key)
+		}
+		secrets[name] = []byte(secret)
+	}
+	return secrets, nil
+}
+
+func (h *webhookHandler) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhooks/carriers/{carrier}", h.handleCarrierEvent)
+	return mux
+}
+
+func (h *webhookHandler) handleCarrierEvent(w http.ResponseWriter, r *http.Request) {
+	carrier := r.PathValue("carrier")
+	secret, ok := h.secrets[carrier]
+	if !ok {
+		http.Error(w, "Unknown carrier", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := verifySignature(secret, r.Header.Get(signatureHeader), body, time.Now()); err != nil {
+		slog.WarnContext(r.Context(), "Rejected carrier webhook", "carrier", carrier, "error", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var req carrierEventJSON
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	status := parseStatus(req.Status)
+	if req.EventID == "" || req.TrackingID == "" || req.OccurredAt.IsZero() ||
+		status == pb.ShipmentStatus_SHIPMENT_STATUS_UNSPECIFIED || status == pb.ShipmentStatus_SHIPMENT_STATUS_CREATED {
+		http.Error(w, "event_id, tracking_id, a carrier status and occurred_at are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	result, err := recordCarrierEvent(ctx, h.store, req.TrackingID, ShipmentEvent{
+		Status:      status,
+		Description: req.Description,
+		Location:    req.Location,
+		OccurredAt:  req.OccurredAt,
+		Carrier:     carrier,
+		EventID:     req.EventID,
+	})
+	if errors.Is(err, ErrShipmentNotFound) {
+		http.Error(w, "Unknown tracking number", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record carrier event", "carrier", carrier, "tracking_number", req.TrackingID, "error", err)
+		http.Error(w, "Failed to record event", http.StatusInternalServerError)
+		return
+	}
+
+	// Duplicates are acknowledged too, so the carrier stops retrying them.
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{"duplicate": result.Duplicate})
+}
+
+// recordCarrierEvent records a carrier's scan of the parcel with its tracking
+// number, whether reported by the carrier's webhook or by the simulator.
+func recordCarrierEvent(ctx context.Context, store *ShipmentStore, trackingNumber string, event ShipmentEvent) (*EventResult, error) {
+	result, err := store.RecordEvent(ctx, trackingNumber, event)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status != result.Previous {
+		shipmentsByStatus.WithLabelValues(statusName(result.Status)).Inc()
+		slog.InfoContext(ctx, "Shipment status changed", "tracking_number", trackingNumber, "carrier", event.Carrier,
+			"from", statusName(result.Previous), "to", statusName(result.Status))
+	}
+	return result, nil
+}
+
+// signPayload returns the signature header value for body sent at t.
+func signPayload(secret []byte, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(payloadMAC(secret, ts, body))
+}
+
+func payloadMAC(secret []byte, ts string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// verifySignature checks a signature header produced by signPayload.
+func verifySignature(secret []byte, header string, body []byte, now time.Time) error {
+	var ts string
+	var sig []byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			var err error
+			if sig, err = hex.DecodeString(value); err != nil {
+				sig = nil
+			}
+		}
+	}
+	if ts == "" || len(sig) == 0 {
+		return errors.New("malformed signature header")
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+	if !hmac.Equal(sig, payloadMAC(secret, ts, body)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	commonpb "github.com/my-store/pkg/api/common"
+	pb "github.com/my-store/pkg/api/shipping"
+)
+
+// testDB returns a connection to a schema of its own in the database at
+// TEST_DATABASE_URL, dropped when the test ends.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Fatal("TEST_DATABASE_URL is not set; integration tests need Postgres")
+	}
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	cfg.RuntimeParams["search_path"] = schema
+	db := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// createTestShipment books a shipment of order 1 with the carrier.
+func createTestShipment(t *testing.T, store *ShipmentStore, carrier, trackingNumber string) *Shipment {
+	t.Helper()
+	items := []*pb.ShipmentItem{{ProductId: 1, Quantity: 2}}
+	shipment := &Shipment{
+		TrackingID: "SIM" + trackingNumber,
+		OrderID:    1,
+		Address: &commonpb.Address{
+			RecipientName: "Ada Lovelace", Line1: "12 St James's Square", City: "London",
+			PostalCode: "SW1Y 4JH", CountryCode: "GB",
+		},
+		Items:                 items,
+		Carrier:               carrier,
+		CarrierTrackingNumber: trackingNumber,
+	}
+	label := &Label{TrackingNumber: trackingNumber, Format: LabelPDF, Data: []byte("%PDF")}
+	if err := store.Create(context.Background(), shipment, label, items); err != nil {
+		t.Fatal(err)
+	}
+	return shipment
+}
+
+func TestCarrierWebhookDuplicateDelivery(t *testing.T) {
+	db := testDB(t)
+	store := NewShipmentStore(db)
+	if err := store.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+	shipment := createTestShipment(t, store, "ups", "1Z999")
+
+	secret := []byte("carrier-secret")
+	h := &webhookHandler{store: store, secrets: map[string][]byte{"ups": secret}}
+	body := `{"event_id":"evt-1","tracking_id":"1Z999","status":"IN_TRANSIT","location":"London","occurred_at":"` +
+		time.Now().UTC().Format(time.RFC3339) + `"}`
+	deliver := func() (duplicate bool) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/carriers/ups", strings.NewReader(body))
+		req.Header.Set(signatureHeader, signPayload(secret, time.Now(), []byte(body)))
+		rec := httptest.NewRecorder()
+		h.routes().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d, want 200: %s", rec.Code, rec.Body)
+		}
+		var resp struct{ Duplicate bool }
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Duplicate
+	}
+
+	if deliver() {
+		t.Error("first delivery reported as a duplicate")
+	}
+	if !deliver() {
+		t.Error("redelivery not reported as a duplicate")
+	}
+
+	got, err := store.GetByTrackingID(context.Background(), shipment.TrackingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != pb.ShipmentStatus_SHIPMENT_STATUS_IN_TRANSIT {
+		t.Errorf("status %s, want IN_TRANSIT", statusName(got.Status))
+	}
+	if len(got.History) != 2 {
+		t.Errorf("history has %d events, want creation and one scan", len(got.History))
+	}
+	var published int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM outbox`).Scan(&published); err != nil {
+		t.Fatal(err)
+	}
+	if published != 2 {
+		t.Errorf("%d status changes in the outbox, want creation and one scan", published)
+	}
+}
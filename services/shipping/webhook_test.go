@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("carrier-secret")
+	body := []byte(`{"event_id":"evt-1"}`)
+	now := time.Unix(1_700_000_000, 0)
+	ts, mac, _ := strings.Cut(signPayload(secret, now, body), ",")
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		err    string // "" if the signature is valid
+	}{
+		{"valid", signPayload(secret, now, body), body, ""},
+		{"valid within tolerance", signPayload(secret, now.Add(-signatureTolerance+time.Second), body), body, ""},
+		{"fields in any order", mac + "," + ts, body, ""},
+		{"wrong secret", signPayload([]byte("other-secret"), now, body), body, "signature mismatch"},
+		{"tampered body", signPayload(secret, now, body), []byte(`{"event_id":"evt-2"}`), "signature mismatch"},
+		{"empty header", "", body, "malformed signature header"},
+		{"missing signature", "t=1700000000", body, "malformed signature header"},
+		{"missing timestamp", "v1=abcd", body, "malformed signature header"},
+		{"signature not hex", "t=1700000000,v1=not-hex", body, "malformed signature header"},
+		{"timestamp not a number", "t=yesterday,v1=abcd", body, "malformed signature timestamp"},
+		{"too old", signPayload(secret, now.Add(-signatureTolerance-time.Second), body), body, "signature timestamp outside tolerance"},
+		{"too far ahead", signPayload(secret, now.Add(signatureTolerance+time.Second), body), body, "signature timestamp outside tolerance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(secret, tt.header, tt.body, now)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("verifySignature() = %v, want nil", err)
+			case tt.err != "" && (err == nil || err.Error() != tt.err):
+				t.Errorf("verifySignature() = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestCarrierWebhookRejects covers the requests turned away before the store is
+// touched; recording events needs Postgres (see webhook_integration_test.go).
+func TestCarrierWebhookRejects(t *testing.T) {
+	secret := []byte("carrier-secret")
+	h := &webhookHandler{secrets: map[string][]byte{"ups": secret}}
+	event := `{"event_id":"evt-1","tracking_id":"1Z999","status":"IN_TRANSIT","occurred_at":"2024-05-01T10:00:00Z"}`
+	signed := func(body string) string { return signPayload(secret, time.Now(), []byte(body)) }
+
+	tests := []struct {
+		name      string
+		carrier   string
+		body      string
+		signature string
+		want      int
+	}{
+		{"unknown carrier", "dhl", event, signPayload([]byte("dhl-secret"), time.Now(), []byte(event)), http.StatusNotFound},
+		{"unsigned", "ups", event, "", http.StatusUnauthorized},
+		{"wrong secret", "ups", event, signPayload([]byte("other-secret"), time.Now(), []byte(event)), http.StatusUnauthorized},
+		{"replayed", "ups", event, signPayload(secret, time.Now().Add(-time.Hour), []byte(event)), http.StatusUnauthorized},
+		{"too large", "ups", strings.Repeat(" ", maxWebhookBody+1), "", http.StatusRequestEntityTooLarge},
+		{"not JSON", "ups", "event", signed("event"), http.StatusBadRequest},
+		{"no event ID", "ups", `{"tracking_id":"1Z999","status":"IN_TRANSIT","occurred_at":"2024-05-01T10:00:00Z"}`,
+			signed(`{"tracking_id":"1Z999","status":"IN_TRANSIT","occurred_at":"2024-05-01T10:00:00Z"}`), http.StatusBadRequest},
+		{"creation isn't a carrier status", "ups", strings.Replace(event, "IN_TRANSIT", "CREATED", 1),
+			signed(strings.Replace(event, "IN_TRANSIT", "CREATED", 1)), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/carriers/"+tt.carrier, bytes.NewBufferString(tt.body))
+			if tt.signature != "" {
+				req.Header.Set(signatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			h.routes().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}